@@ -5,13 +5,52 @@ import (
 	"fmt"
 	"log/slog"
 	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
 
+	"github.com/atomicmeganerd/starfeed/common"
 	"github.com/atomicmeganerd/starfeed/config"
+	"github.com/atomicmeganerd/starfeed/feedproxy"
 	"github.com/atomicmeganerd/starfeed/gitforge"
 	"github.com/atomicmeganerd/starfeed/rss"
 	"github.com/atomicmeganerd/starfeed/runners"
 )
 
+// This builds the runners and executes them once. We rebuild the runners for every run so that
+// tokens loaded from files, environment variables or commands are re-read each time.
+func buildAndExecuteRunners(
+	ctx context.Context,
+	cfg config.Config,
+	logger *slog.Logger,
+	client *http.Client,
+	feedProxy *feedproxy.Server,
+) error {
+	runnerSlice, err := buildRunners(ctx, cfg, logger, client, feedProxy)
+	if err != nil {
+		return fmt.Errorf("error building runners: %w", err)
+	}
+	return runners.ExecuteRunners(ctx, runnerSlice)
+}
+
+// Builds the runners again on a later tick of the daemon loop. Tokens are resolved and we log in
+// to the RSS server every time, so a failure here is usually a passing one like a secret store
+// that is briefly unreachable. We log it and try again on the next tick instead of exiting.
+func rebuildAndExecuteRunners(
+	ctx context.Context,
+	cfg config.Config,
+	logger *slog.Logger,
+	client *http.Client,
+	feedProxy *feedproxy.Server,
+) error {
+	runnerSlice, err := buildRunners(ctx, cfg, logger, client, feedProxy)
+	if err != nil {
+		logger.Error("Error building runners, trying again on the next run", "error", err)
+		return nil
+	}
+	return runners.ExecuteRunners(ctx, runnerSlice)
+}
+
 // This function builds our runner objects. We have one shared rssServer but we can have
 // multiple git forges so we return one runner per git forge.
 func buildRunners(
@@ -19,16 +58,15 @@ func buildRunners(
 	cfg config.Config,
 	logger *slog.Logger,
 	client *http.Client,
+	feedProxy *feedproxy.Server,
 ) ([]runners.StarfeedRunner, error) {
 	// We build a shared RSS server that we publish too. All runners share it.
 	rssServerName := cfg.RSSServer.Name
 	rssServerLogger := logger.With("rssServer", rssServerName)
-	rssServer := rss.NewFreshRSSClient(
-		cfg.RSSServer.User, cfg.RSSServer.URL, rssServerLogger, client,
-	)
-	// Try to authenticate to the shared RSS server
-	if err := rssServer.Authenticate(ctx, cfg.RSSServer.Token); err != nil {
-		return nil, fmt.Errorf("error authenticating to freshrss %s: %w", rssServerName, err)
+	retry := cfg.RetryPolicy()
+	rssServer := buildRSSServer(cfg.RSSServer, rssServerLogger, client, retry)
+	if err := authenticateRSSServer(ctx, cfg.RSSServer, rssServer); err != nil {
+		return nil, err
 	}
 	rssServerLogger.Info("Successfully authenticated to RSS Server")
 
@@ -37,21 +75,74 @@ func buildRunners(
 	runnerSlice := make([]runners.StarfeedRunner, len(cfg.GitForges))
 	for ix, forgeCfg := range cfg.GitForges {
 		forgeName := forgeCfg.Name
+		forgeFeeds, proxiedFeeds := buildFeedSet(feedProxy, forgeName)
+		cache, forgeClient, err := buildForgeHTTPClient(cfg.CacheDir, forgeName, client)
+		if err != nil {
+			return nil, err
+		}
+		rules, err := repoRules(forgeCfg.Rules)
+		if err != nil {
+			return nil, fmt.Errorf("error building rules for gitforge %s: %w", forgeName, err)
+		}
+		forgeOpts := gitforge.GitForgeOptions{
+			Type:              forgeCfg.Type,
+			Fqdn:              forgeCfg.Fqdn,
+			APIURL:            forgeCfg.APIURL,
+			WebURL:            forgeCfg.WebURL,
+			FeedKind:          gitforge.FeedKind(forgeCfg.FeedKind),
+			FeedKindOverrides: feedKindOverrides(forgeCfg.FeedKindOverrides),
+			Rules:             rules,
+			StarLists:         forgeCfg.StarLists,
+			Usernames:         forgeCfg.StarredUsernames(),
+			Sources:           repoSources(forgeCfg.Sources),
+			Private:           gitforge.PrivatePolicy(forgeCfg.Private),
+			FeedProxy:         forgeFeeds,
+			Forks:             gitforge.ForkPolicy(forgeCfg.Forks),
+			Mirrors:           gitforge.MirrorPolicy(forgeCfg.Mirrors),
+		}
+		token, appAuth, err := forgeCredentials(ctx, forgeCfg, forgeOpts, client, retry)
+		if err != nil {
+			return nil, fmt.Errorf("error loading token for gitforge %s: %w", forgeName, err)
+		}
+		forgeOpts.AppAuth = appAuth
 		forge := gitforge.NewGitForgeClient(
-			forgeCfg.Type,
-			forgeCfg.Fqdn,
-			forgeCfg.Token,
+			forgeOpts,
+			token,
 			logger.With("gitForge", forgeName),
-			client,
+			forgeClient,
+			retry,
 		)
 
-		// The category we publish in RSS  is always equal to the name of the GitForge
-		category := rss.FeedCategory(forgeName)
+		repoIndex, err := buildRepoIndexStore(cfg.StateDir, forgeName)
+		if err != nil {
+			return nil, err
+		}
+
+		// The template was already validated when we loaded the config
+		titleTemplate, err := forgeCfg.FeedTitleTemplate()
+		if err != nil {
+			return nil, fmt.Errorf(
+				"error parsing title template for gitforge %s: %w", forgeName, err,
+			)
+		}
+
+		// We publish in a category named after the GitForge unless a repo is in a star list
 		syncLogger := logger.With("gitForge", forgeName, "rssServer", rssServerName)
 		runner := runners.NewSyncFeedsRunner(
 			forge,
 			rssServer,
-			category,
+			runners.SyncFeedsOptions{
+				Category:         rss.FeedCategory(forgeName),
+				Archived:         runners.ArchivedPolicy(forgeCfg.Archived),
+				ArchivedCategory: rss.FeedCategory(forgeCfg.ArchivedCategoryName()),
+				TitleTemplate:    titleTemplate,
+				MaxReleaseAge:    forgeCfg.MaxReleaseAgeDuration(),
+				Dormant:          runners.DormantPolicy(forgeCfg.Dormant),
+				PrefixStarLists:  forgeCfg.StarListPrefix,
+				FeedProxy:        proxiedFeeds,
+			},
+			cache,
+			repoIndex,
 			syncLogger,
 		)
 
@@ -60,3 +151,220 @@ func buildRunners(
 	}
 	return runnerSlice, nil
 }
+
+// The name of the RSS server in the config decides which kind of server we talk to
+func buildRSSServer(
+	cfg config.RSSServerConfig,
+	logger *slog.Logger,
+	client *http.Client,
+	retry common.RetryPolicy,
+) rss.Server {
+	switch cfg.Name {
+	case "greader":
+		opts := rss.GReaderOptions{}
+		if cfg.GReader != nil {
+			opts = rss.GReaderOptions{
+				BasePath:    cfg.GReader.BasePath,
+				LoginPath:   cfg.GReader.LoginPath,
+				LabelPrefix: cfg.GReader.LabelPrefix,
+			}
+		}
+		return rss.NewGReaderClient(cfg.User, cfg.URL, opts, logger, client, retry)
+	case "miniflux":
+		return rss.NewMinifluxClient(cfg.URL, logger, client, retry)
+	case "nextcloud_news":
+		return rss.NewNextcloudNewsClient(cfg.User, cfg.URL, logger, client, retry)
+	case "ttrss":
+		return rss.NewTinyTinyRSSClient(cfg.User, cfg.URL, logger, client, retry)
+	case "opml":
+		return rss.NewOPMLClient(cfg.Path, logger)
+	default:
+		return rss.NewFreshRSSClient(cfg.User, cfg.URL, logger, client, retry)
+	}
+}
+
+// Tokens are resolved every time we build the runners so rotated secrets are picked up. An OPML
+// file has no token so it is only opened.
+func authenticateRSSServer(
+	ctx context.Context,
+	cfg config.RSSServerConfig,
+	rssServer rss.Server,
+) error {
+	rssToken := ""
+	if cfg.NeedsToken() {
+		token, err := cfg.ResolveToken(ctx)
+		if err != nil {
+			return fmt.Errorf("error loading token for rss server %s: %w", cfg.Name, err)
+		}
+		rssToken = token
+	}
+	if err := rssServer.Authenticate(ctx, rssToken); err != nil {
+		return fmt.Errorf("error authenticating to rss server %s: %w", cfg.Name, err)
+	}
+	return nil
+}
+
+// The feed proxy outlives the runners as the RSS server fetches private feeds from it between
+// runs. Its secret is only read at startup as changing it changes every proxied feed URL. We
+// return nil if no proxy is configured.
+func startFeedProxy(
+	ctx context.Context,
+	cfg config.Config,
+	logger *slog.Logger,
+) (*feedproxy.Server, error) {
+	if cfg.FeedProxy == nil {
+		return nil, nil
+	}
+	secret, err := cfg.FeedProxy.ResolveToken(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("error loading secret for feed proxy: %w", err)
+	}
+	proxyLogger := logger.With("feedProxy", cfg.FeedProxy.URL)
+	server := feedproxy.NewServer(cfg.FeedProxy.URL, secret, proxyLogger)
+	if err := server.Start(ctx, cfg.FeedProxy.Listen); err != nil {
+		return nil, err
+	}
+	return server, nil
+}
+
+// Each GitForge registers its private feeds in a set of its own that replaces the one of its last
+// run. We return nil interfaces if no proxy is configured.
+func buildFeedSet(
+	feedProxy *feedproxy.Server,
+	forgeName string,
+) (gitforge.FeedProxy, runners.FeedProxy) {
+	if feedProxy == nil {
+		return nil, nil
+	}
+	feedSet := feedProxy.NewFeedSet(forgeName)
+	return feedSet, feedSet
+}
+
+// Each GitForge gets its own HTTP cache directory so that the hit/miss counts we report are per
+// GitForge. If no cache dir is configured we just use the shared client and a nil cache.
+func buildForgeHTTPClient(
+	cacheDir, forgeName string,
+	client *http.Client,
+) (*common.HTTPCache, *http.Client, error) {
+	if cacheDir == "" {
+		return nil, client, nil
+	}
+	cache, err := common.NewHTTPCache(filepath.Join(cacheDir, forgeName), client.Transport)
+	if err != nil {
+		return nil, nil, err
+	}
+	return cache, &http.Client{Timeout: client.Timeout, Transport: cache}, nil
+}
+
+// A forge authenticates either as a GitHub App installation or with a token
+func forgeCredentials(
+	ctx context.Context,
+	forgeCfg config.GitForgeConfig,
+	opts gitforge.GitForgeOptions,
+	client *http.Client,
+	retry common.RetryPolicy,
+) (string, *gitforge.GitHubAppAuth, error) {
+	if forgeCfg.GitHubApp == nil {
+		token, err := forgeCfg.ResolveToken(ctx)
+		return token, nil, err
+	}
+	appAuth, err := buildGitHubAppAuth(ctx, opts, *forgeCfg.GitHubApp, client, retry)
+	return "", appAuth, err
+}
+
+// The key is read every time we build the runners like the tokens are. We get the first
+// installation token right away so that a misconfigured app fails here and not halfway through
+// loading the feeds.
+func buildGitHubAppAuth(
+	ctx context.Context,
+	opts gitforge.GitForgeOptions,
+	cfg config.GitHubAppConfig,
+	client *http.Client,
+	retry common.RetryPolicy,
+) (*gitforge.GitHubAppAuth, error) {
+	key, err := os.ReadFile(cfg.PrivateKeyFile)
+	if err != nil {
+		return nil, fmt.Errorf("could not read github app private key: %w", err)
+	}
+	auth, err := gitforge.NewGitHubAppAuth(
+		opts,
+		gitforge.GitHubApp{
+			AppID:          cfg.AppID,
+			InstallationID: cfg.InstallationID,
+			PrivateKey:     key,
+		},
+		client,
+		retry,
+	)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := auth.Token(ctx); err != nil {
+		return nil, err
+	}
+	return auth, nil
+}
+
+// Each GitForge gets its own repo index as repo ids are only unique within a forge. Without a
+// state dir we return a nil store which never remembers anything.
+func buildRepoIndexStore(stateDir, forgeName string) (*runners.FileRepoIndexStore, error) {
+	if stateDir == "" {
+		return nil, nil
+	}
+	store, err := runners.NewFileRepoIndexStore(filepath.Join(stateDir, forgeName+".json"))
+	if err != nil {
+		return nil, fmt.Errorf("error creating repo index for gitforge %s: %w", forgeName, err)
+	}
+	return store, nil
+}
+
+// The config only knows strings so we convert the overrides to the type the gitforge wants
+func feedKindOverrides(overrides map[string]string) map[string]gitforge.FeedKind {
+	kinds := make(map[string]gitforge.FeedKind, len(overrides))
+	for fullName, kind := range overrides {
+		kinds[fullName] = gitforge.FeedKind(kind)
+	}
+	return kinds
+}
+
+func repoSources(sources []string) []gitforge.RepoSource {
+	repoSources := make([]gitforge.RepoSource, len(sources))
+	for ix, source := range sources {
+		repoSources[ix] = gitforge.RepoSource(source)
+	}
+	return repoSources
+}
+
+// The regexes were already validated when we loaded the config but we still have to compile them
+func repoRules(cfgs []config.RepoRuleConfig) ([]gitforge.RepoRule, error) {
+	rules := make([]gitforge.RepoRule, len(cfgs))
+	for ix, cfg := range cfgs {
+		rule := gitforge.RepoRule{
+			Include:  cfg.Action == "include",
+			Owner:    cfg.Owner,
+			Name:     cfg.Name,
+			Language: cfg.Language,
+			Topics:   cfg.Topics,
+			Fork:     cfg.Fork,
+			Archived: cfg.Archived,
+			MinStars: cfg.MinStars,
+		}
+		var err error
+		if rule.OwnerRegex, err = compileRegexp(cfg.OwnerRegex); err != nil {
+			return nil, fmt.Errorf("rule %d has an invalid owner_regex: %w", ix+1, err)
+		}
+		if rule.NameRegex, err = compileRegexp(cfg.NameRegex); err != nil {
+			return nil, fmt.Errorf("rule %d has an invalid name_regex: %w", ix+1, err)
+		}
+		rules[ix] = rule
+	}
+	return rules, nil
+}
+
+// An empty expression is a condition that was not set
+func compileRegexp(expr string) (*regexp.Regexp, error) {
+	if expr == "" {
+		return nil, nil
+	}
+	return regexp.Compile(expr)
+}
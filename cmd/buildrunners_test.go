@@ -0,0 +1,65 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/atomicmeganerd/starfeed/config"
+	"github.com/atomicmeganerd/starfeed/feedproxy"
+)
+
+// A token command that fails, like a secret store that is briefly unreachable, stops the first run
+// but only skips a later tick of the daemon loop
+func TestBuildRunnersFailure(t *testing.T) {
+	type runFunc func(
+		context.Context, config.Config, *slog.Logger, *http.Client, *feedproxy.Server,
+	) error
+
+	testCases := []struct {
+		name      string
+		run       runFunc
+		expectErr bool
+		expectLog bool
+	}{
+		{name: "First run fails", run: buildAndExecuteRunners, expectErr: true},
+		{name: "Later tick logs and carries on", run: rebuildAndExecuteRunners, expectLog: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			// An OPML file needs no network so only the token command can fail
+			cfg := config.Config{
+				GitForges: []config.GitForgeConfig{{
+					Type:        "github",
+					Name:        "GitHub",
+					Fqdn:        "github.com",
+					TokenSource: config.TokenSource{TokenCommand: []string{"false"}},
+				}},
+				RSSServer: config.RSSServerConfig{
+					Name: "opml",
+					Path: filepath.Join(t.TempDir(), "feeds.opml"),
+				},
+			}
+			var logs bytes.Buffer
+			logger := slog.New(slog.NewTextHandler(&logs, nil))
+
+			err := tc.run(context.Background(), cfg, logger, &http.Client{}, nil)
+			if tc.expectErr && err == nil {
+				t.Fatalf("Expected error but got nil")
+			}
+			if !tc.expectErr && err != nil {
+				t.Fatalf("Expected no error but got %v", err)
+			}
+			logged := strings.Contains(logs.String(), "Error building runners")
+			if logged != tc.expectLog {
+				t.Errorf("Expected logged to be %t, got %q", tc.expectLog, logs.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,117 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"slices"
+	"syscall"
+	"time"
+
+	"github.com/atomicmeganerd/starfeed/common"
+	"github.com/atomicmeganerd/starfeed/config"
+	"github.com/atomicmeganerd/starfeed/gitforge"
+)
+
+// The login subcommand gets a token for one of the forges in the config with the OAuth device
+// flow and stores it in the token_file of that forge. This saves new users from creating a token
+// with the right scopes by hand.
+func runLogin(args []string) error {
+	cfg, err := config.NewConfig(config.ConfigLoader{})
+	if err != nil {
+		slog.Default().Error("Error loading configuration", "error", err)
+		return err
+	}
+	logger := buildLogger(cfg.Debug)
+	if len(args) != 1 {
+		err := errors.New("usage: starfeed login <forge-name>")
+		logger.Error("Error logging in", "error", err)
+		return err
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	client := &http.Client{Timeout: 60 * time.Second}
+	if err := login(ctx, cfg, args[0], logger.With("gitForge", args[0]), client); err != nil {
+		logger.Error("Error logging in", "gitForge", args[0], "error", err)
+		return err
+	}
+	return nil
+}
+
+func login(
+	ctx context.Context,
+	cfg config.Config,
+	forgeName string,
+	logger *slog.Logger,
+	client *http.Client,
+) error {
+	ix := slices.IndexFunc(cfg.GitForges, func(forgeCfg config.GitForgeConfig) bool {
+		return forgeCfg.Name == forgeName
+	})
+	if ix < 0 {
+		return fmt.Errorf("there is no gitforge named %s in the config", forgeName)
+	}
+	forgeCfg := cfg.GitForges[ix]
+	if forgeCfg.OAuthClientID == "" {
+		return errors.New("the gitforge has no oauth_client_id to log in with")
+	}
+	if forgeCfg.TokenFile == "" {
+		return errors.New("the gitforge has no token_file to store the token in")
+	}
+
+	opts := gitforge.GitForgeOptions{
+		Type:      forgeCfg.Type,
+		Fqdn:      forgeCfg.Fqdn,
+		APIURL:    forgeCfg.APIURL,
+		WebURL:    forgeCfg.WebURL,
+		Usernames: forgeCfg.StarredUsernames(),
+	}
+	retry := cfg.RetryPolicy()
+	flow, err := gitforge.NewDeviceFlow(opts, forgeCfg.OAuthClientID, client, retry)
+	if err != nil {
+		return err
+	}
+	code, err := flow.RequestCode(ctx)
+	if err != nil {
+		return err
+	}
+	logger.Info(
+		"Open the verification URL and enter the code to log in",
+		"url", code.VerificationURI,
+		"code", code.UserCode,
+	)
+	token, err := flow.PollToken(ctx, code)
+	if err != nil {
+		return err
+	}
+
+	// We check the token before we store it so that a bad one never replaces one that works
+	forge := gitforge.NewGitForgeClient(opts, token, logger, client, retry)
+	numRepos, err := forge.VerifyAccess(ctx)
+	if err != nil {
+		return fmt.Errorf("could not fetch starred repos with the new token: %w", err)
+	}
+	if err := saveToken(forgeCfg.TokenFile, token); err != nil {
+		return err
+	}
+	logger.Info("Successfully logged in", "tokenFile", forgeCfg.TokenFile, "numRepos", numRepos)
+	return nil
+}
+
+// The token file is a secret so only its owner can read it
+func saveToken(tokenFile, token string) error {
+	if err := os.MkdirAll(filepath.Dir(tokenFile), 0o700); err != nil {
+		return fmt.Errorf("could not create directory for token file: %w", err)
+	}
+	if err := common.WriteFileAtomic(tokenFile, []byte(token+"\n"), 0o600); err != nil {
+		return fmt.Errorf("could not write token file: %w", err)
+	}
+	return nil
+}
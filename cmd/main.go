@@ -10,7 +10,6 @@ import (
 	"time"
 
 	"github.com/atomicmeganerd/starfeed/config"
-	"github.com/atomicmeganerd/starfeed/runners"
 )
 
 // This is injected by the CI/CD to tag the binary
@@ -20,8 +19,15 @@ var (
 )
 
 func main() {
+	// The login subcommand gets a token for a forge. Without a subcommand we sync feeds.
+	var err error
+	if len(os.Args) > 1 && os.Args[1] == "login" {
+		err = runLogin(os.Args[2:])
+	} else {
+		err = run()
+	}
 	// Return an error to the operating system if run returns an error
-	if err := run(); err != nil {
+	if err != nil {
 		os.Exit(1)
 	}
 }
@@ -50,21 +56,22 @@ func run() error {
 	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
 	defer stop()
 
+	feedProxy, err := startFeedProxy(ctx, cfg, logger)
+	if err != nil {
+		logger.Error("Error starting feed proxy", "error", err)
+		return err
+	}
+
 	// Setup our ticker for our timed execution. This will send a time.Time value to the ticker.C
 	// is set by the interval setting in the Config.
 	// NOTE: This is a bounded (size 1) async channel
 	ticker := time.NewTicker(cfg.Interval())
 	defer ticker.Stop()
 
-	runnerSlice, err := buildRunners(ctx, cfg, logger, client)
-	if err != nil {
-		logger.Error("Error building runners", "error", err)
-		return err
-	}
-
 	// We always want to run on startup, and if we are in SingleRun mode we will terminate
 	// the app after running the workflow once. SingleRun is useful for development and testing.
-	if err := runners.ExecuteRunners(ctx, runnerSlice); err != nil {
+	// A config that can't build its runners fails here straight away.
+	if err := buildAndExecuteRunners(ctx, cfg, logger, client, feedProxy); err != nil {
 		logger.Error("Error executing runners", "error", err)
 		return err
 	}
@@ -89,7 +96,7 @@ func run() error {
 			// already capture the timestamp when we execute. But it is good to recognize that
 			// the ticker channel is sent this data.
 		case t := <-ticker.C:
-			if err := runners.ExecuteRunners(ctx, runnerSlice); err != nil {
+			if err := rebuildAndExecuteRunners(ctx, cfg, logger, client, feedProxy); err != nil {
 				logger.Error("Error executing runners", "error", err)
 				return err
 			}
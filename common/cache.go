@@ -0,0 +1,213 @@
+package common
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+)
+
+// HTTPCache is an http.RoundTripper that remembers the ETag and Last-Modified validators of GET
+// responses on disk. The next time we request the same URL we send them back as a conditional
+// request and if the server answers 304 Not Modified we serve the body we stored last time. The
+// caller of DoAPIRequest just sees a normal 200 OK.
+//
+// This saves a lot of bandwidth and GitHub does not count 304 responses against the rate limit.
+// Entries for URLs we stop requesting, like the feeds of unstarred repos, are removed by Prune.
+type HTTPCache struct {
+	dir    string
+	base   http.RoundTripper
+	hits   atomic.Int64
+	misses atomic.Int64
+	// The entries requested since the cache was created or last pruned
+	mu   sync.Mutex
+	used *Set[string]
+}
+
+// How many requests were answered from the cache and how many had to be downloaded in full
+type CacheStats struct {
+	Hits   int
+	Misses int
+}
+
+// This is what we persist to disk for each URL
+type cacheEntry struct {
+	ETag         string      `json:"etag"`
+	LastModified string      `json:"last_modified"`
+	Header       http.Header `json:"header"`
+	Body         []byte      `json:"body"`
+}
+
+// Creates the cache directory if required. If base is nil we use the http.DefaultTransport.
+func NewHTTPCache(dir string, base http.RoundTripper) (*HTTPCache, error) {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, fmt.Errorf("could not create http cache dir %s: %w", dir, err)
+	}
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return &HTTPCache{dir: dir, base: base, used: NewSet[string]()}, nil
+}
+
+func (c *HTTPCache) RoundTrip(req *http.Request) (*http.Response, error) {
+	// Only GET requests are safe to cache
+	if req.Method != http.MethodGet {
+		return c.base.RoundTrip(req)
+	}
+
+	entryPath := c.entryPath(req.URL.String())
+	c.markUsed(entryPath)
+	entry, cached := c.load(entryPath)
+	if cached {
+		// A RoundTripper must not modify the request it was given
+		req = req.Clone(req.Context())
+		if entry.ETag != "" {
+			req.Header.Set("If-None-Match", entry.ETag)
+		}
+		if entry.LastModified != "" {
+			req.Header.Set("If-Modified-Since", entry.LastModified)
+		}
+	}
+
+	res, err := c.base.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if cached && res.StatusCode == http.StatusNotModified {
+		_ = res.Body.Close()
+		c.hits.Add(1)
+		return entry.response(req, res.Header), nil
+	}
+	c.misses.Add(1)
+
+	etag := res.Header.Get("ETag")
+	lastModified := res.Header.Get("Last-Modified")
+	if res.StatusCode != http.StatusOK || (etag == "" && lastModified == "") {
+		return res, nil
+	}
+
+	// We have to read the whole body to store it so we hand the caller a copy of it
+	body, err := io.ReadAll(res.Body)
+	_ = res.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	res.Body = io.NopCloser(bytes.NewReader(body))
+
+	// The cache is best effort. If we can't write it we will just download it again next time.
+	_ = c.store(entryPath, cacheEntry{
+		ETag:         etag,
+		LastModified: lastModified,
+		Header:       res.Header.Clone(),
+		Body:         body,
+	})
+	return res, nil
+}
+
+// Returns the hit and miss counts since the last call and resets them. It is safe to call on a
+// nil cache which is what we have when caching is disabled.
+func (c *HTTPCache) TakeStats() CacheStats {
+	if c == nil {
+		return CacheStats{}
+	}
+	return CacheStats{
+		Hits:   int(c.hits.Swap(0)),
+		Misses: int(c.misses.Swap(0)),
+	}
+}
+
+// Removes the entries of every URL that was not requested since the cache was created or last
+// pruned. We call this at the end of a run when every URL we still need has been requested. It is
+// safe to call on a nil cache.
+func (c *HTTPCache) Prune() error {
+	if c == nil {
+		return nil
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entryPaths, err := filepath.Glob(filepath.Join(c.dir, "*.json"))
+	if err != nil {
+		return err
+	}
+	var errs []error
+	for _, entryPath := range entryPaths {
+		if c.used.Contains(entryPath) {
+			continue
+		}
+		if err := os.Remove(entryPath); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			errs = append(errs, err)
+		}
+	}
+	c.used = NewSet[string]()
+	if len(errs) > 0 {
+		return fmt.Errorf("could not prune http cache %s: %w", c.dir, errors.Join(errs...))
+	}
+	return nil
+}
+
+func (c *HTTPCache) markUsed(entryPath string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.used.Add(entryPath)
+}
+
+// URLs can contain all kinds of characters that are not valid in file names so we hash them
+func (c *HTTPCache) entryPath(reqURL string) string {
+	sum := sha256.Sum256([]byte(reqURL))
+	return filepath.Join(c.dir, hex.EncodeToString(sum[:])+".json")
+}
+
+func (c *HTTPCache) load(entryPath string) (cacheEntry, bool) {
+	data, err := os.ReadFile(entryPath)
+	if err != nil {
+		return cacheEntry{}, false
+	}
+	var entry cacheEntry
+	if err := json.Unmarshal(data, &entry); err != nil {
+		return cacheEntry{}, false
+	}
+	return entry, true
+}
+
+func (c *HTTPCache) store(entryPath string, entry cacheEntry) error {
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	return WriteFileAtomic(entryPath, data, 0o600)
+}
+
+// Builds a 200 OK response from the cache entry. Headers on the 304 response (like the rate
+// limit headers) are fresher than the ones we stored so they win.
+func (e cacheEntry) response(req *http.Request, notModifiedHeader http.Header) *http.Response {
+	header := e.Header.Clone()
+	if header == nil {
+		header = http.Header{}
+	}
+	for key, values := range notModifiedHeader {
+		header[key] = values
+	}
+	header.Del("Content-Length")
+	return &http.Response{
+		Status:        "200 OK",
+		StatusCode:    http.StatusOK,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          io.NopCloser(bytes.NewReader(e.Body)),
+		ContentLength: int64(len(e.Body)),
+		Request:       req,
+	}
+}
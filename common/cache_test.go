@@ -0,0 +1,169 @@
+package common
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"sync/atomic"
+	"testing"
+)
+
+const (
+	mockETag = `"abc123"`
+	mockBody = `<feed><entry><title>v1.0.0</title></entry></feed>`
+)
+
+// This server returns an ETag and honours If-None-Match. It counts how many full responses it
+// has sent so we can tell if the cache was used.
+func newETagServer(t *testing.T, etag string, fullResponses *atomic.Int32) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if etag != "" && r.Header.Get("If-None-Match") == etag {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		fullResponses.Add(1)
+		if etag != "" {
+			w.Header().Set("ETag", etag)
+		}
+		w.Header().Set("Link", `<https://example.com/?page=2>; rel="next"`)
+		_, _ = w.Write([]byte(mockBody))
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestHTTPCache(t *testing.T) {
+	testCases := []struct {
+		name                  string
+		etag                  string
+		method                string
+		expectedFullResponses int32
+		expectedStats         CacheStats
+	}{
+		{
+			name:                  "Second GET is served from the cache",
+			etag:                  mockETag,
+			method:                http.MethodGet,
+			expectedFullResponses: 1,
+			expectedStats:         CacheStats{Hits: 1, Misses: 1},
+		},
+		{
+			name:                  "Responses without validators are not cached",
+			etag:                  "",
+			method:                http.MethodGet,
+			expectedFullResponses: 2,
+			expectedStats:         CacheStats{Hits: 0, Misses: 2},
+		},
+		{
+			name:                  "POST requests bypass the cache",
+			etag:                  mockETag,
+			method:                http.MethodPost,
+			expectedFullResponses: 2,
+			expectedStats:         CacheStats{Hits: 0, Misses: 0},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			fullResponses := &atomic.Int32{}
+			server := newETagServer(t, tc.etag, fullResponses)
+
+			cache, err := NewHTTPCache(t.TempDir(), nil)
+			if err != nil {
+				t.Fatalf("Expected no error creating cache but got %v", err)
+			}
+			client := &http.Client{Transport: cache}
+
+			for range 2 {
+				body, headers, err := DoAPIRequest(
+					context.Background(),
+					tc.method,
+					server.URL,
+					nil,
+					http.Header{},
+					client,
+					RetryPolicy{},
+				)
+				if err != nil {
+					t.Fatalf("Expected no error but got %v", err)
+				}
+				if string(body) != mockBody {
+					t.Fatalf("Expected body %q but got %q", mockBody, string(body))
+				}
+				// We need the Link header to survive the cache for pagination
+				if headers.Get("Link") == "" {
+					t.Fatalf("Expected Link header to be returned")
+				}
+			}
+
+			if got := fullResponses.Load(); got != tc.expectedFullResponses {
+				t.Errorf("Expected %d full responses but got %d", tc.expectedFullResponses, got)
+			}
+			if got := cache.TakeStats(); got != tc.expectedStats {
+				t.Errorf("Expected stats %+v but got %+v", tc.expectedStats, got)
+			}
+			if got := cache.TakeStats(); got != (CacheStats{}) {
+				t.Errorf("Expected stats to be reset but got %+v", got)
+			}
+		})
+	}
+}
+
+func TestHTTPCacheNilStats(t *testing.T) {
+	var cache *HTTPCache
+	if got := cache.TakeStats(); got != (CacheStats{}) {
+		t.Errorf("Expected zero stats from nil cache but got %+v", got)
+	}
+	if err := cache.Prune(); err != nil {
+		t.Errorf("Expected no error pruning nil cache but got %v", err)
+	}
+}
+
+func TestHTTPCachePrune(t *testing.T) {
+	fullResponses := &atomic.Int32{}
+	server := newETagServer(t, mockETag, fullResponses)
+	dir := t.TempDir()
+	keptURL, prunedURL := server.URL+"/kept", server.URL+"/pruned"
+
+	// Each run gets a new cache like the runners do
+	get := func(cache *HTTPCache, reqURL string) {
+		t.Helper()
+		client := &http.Client{Transport: cache}
+		if _, _, err := DoAPIRequest(
+			context.Background(), http.MethodGet, reqURL, nil, http.Header{}, client, RetryPolicy{},
+		); err != nil {
+			t.Fatalf("Expected no error but got %v", err)
+		}
+	}
+	newCache := func() *HTTPCache {
+		t.Helper()
+		cache, err := NewHTTPCache(dir, nil)
+		if err != nil {
+			t.Fatalf("Expected no error creating cache but got %v", err)
+		}
+		return cache
+	}
+
+	first := newCache()
+	get(first, keptURL)
+	get(first, prunedURL)
+	if err := first.Prune(); err != nil {
+		t.Fatalf("Expected no error pruning but got %v", err)
+	}
+
+	// The next run no longer requests one of the URLs so its entry goes
+	second := newCache()
+	get(second, keptURL)
+	if err := second.Prune(); err != nil {
+		t.Fatalf("Expected no error pruning but got %v", err)
+	}
+	for reqURL, expected := range map[string]bool{keptURL: true, prunedURL: false} {
+		_, err := os.Stat(second.entryPath(reqURL))
+		if exists := err == nil; exists != expected {
+			t.Errorf("Expected the entry for %s to exist: %t, got %t", reqURL, expected, exists)
+		}
+	}
+}
@@ -3,12 +3,14 @@ package common
 import (
 	"bytes"
 	"context"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
 )
 
-// This is a common HTTP method that can be used by any of our client objects.
+// This is a common HTTP method that can be used by any of our client objects. Transient failures
+// are retried according to the RetryPolicy.
 func DoAPIRequest(
 	ctx context.Context,
 	method string,
@@ -16,7 +18,42 @@ func DoAPIRequest(
 	payload []byte,
 	headers http.Header,
 	client *http.Client,
+	retry RetryPolicy,
 ) ([]byte, http.Header, error) {
+	maxAttempts := retry.maxAttemptsFor(method)
+	for attempt := 1; ; attempt++ {
+		req, err := newRequest(ctx, method, reqURL, payload, headers)
+		if err != nil {
+			// There is no point retrying a request we can't even build
+			return nil, nil, err
+		}
+
+		data, respHeaders, err := doRequestOnce(req, client)
+		if httpErr, ok := errors.AsType[HTTPError](err); ok {
+			httpErr.Attempts = attempt
+			err = httpErr
+		}
+		if err == nil || attempt >= maxAttempts || !shouldRetry(ctx, err, respHeaders) {
+			return data, respHeaders, err
+		}
+
+		delay, ok := retry.delay(attempt, respHeaders)
+		if !ok {
+			return data, respHeaders, err
+		}
+		if sleepErr := sleepContext(ctx, delay); sleepErr != nil {
+			return data, respHeaders, err
+		}
+	}
+}
+
+func newRequest(
+	ctx context.Context,
+	method string,
+	reqURL string,
+	payload []byte,
+	headers http.Header,
+) (*http.Request, error) {
 	var req *http.Request
 	var err error
 	if payload != nil {
@@ -26,12 +63,15 @@ func DoAPIRequest(
 		req, err = http.NewRequestWithContext(ctx, method, reqURL, nil)
 	}
 	if err != nil {
-		return nil, nil, err
+		return nil, err
 	}
 
 	// Set headers
 	req.Header = headers.Clone()
+	return req, nil
+}
 
+func doRequestOnce(req *http.Request, client *http.Client) ([]byte, http.Header, error) {
 	res, err := client.Do(req)
 	if err != nil {
 		return nil, nil, err
@@ -50,22 +90,37 @@ func DoAPIRequest(
 
 	// If we do have an error we can still try to return the data
 	return data, res.Header, HTTPError{
-		URL:        reqURL,
+		URL:        req.URL.String(),
 		StatusCode: res.StatusCode,
 		Status:     res.Status,
 	}
 }
 
+// HTTP errors are only retried for transient status codes. Anything else that went wrong was a
+// network level failure (connection reset, timeout...) that is worth another try unless our own
+// context has been cancelled.
+func shouldRetry(ctx context.Context, err error, respHeaders http.Header) bool {
+	if ctx.Err() != nil {
+		return false
+	}
+	if httpErr, ok := errors.AsType[HTTPError](err); ok {
+		return isRetryableStatus(httpErr.StatusCode, respHeaders)
+	}
+	return true
+}
+
 // Sometimes we need to know if the error is 404 or something else...
 type HTTPError struct {
 	URL        string
 	StatusCode int
 	Status     string
+	// How many attempts we made before giving up
+	Attempts int
 }
 
 func (h HTTPError) Error() string {
 	return fmt.Sprintf(
-		"http error, url: %s, status code: %d, status: %s",
-		h.URL, h.StatusCode, h.Status,
+		"http error, url: %s, status code: %d, status: %s, attempts: %d",
+		h.URL, h.StatusCode, h.Status, h.Attempts,
 	)
 }
@@ -131,7 +131,13 @@ func TestDoAPIRequest(t *testing.T) {
 			client := &http.Client{Transport: &mockTransport}
 
 			body, respHeaders, err := DoAPIRequest(
-				context.Background(), tc.method, tc.reqURL, tc.payload, tc.headers, client,
+				context.Background(),
+				tc.method,
+				tc.reqURL,
+				tc.payload,
+				tc.headers,
+				client,
+				RetryPolicy{},
 			)
 
 			if tc.expectError {
@@ -0,0 +1,116 @@
+package common
+
+import (
+	"context"
+	"math/rand/v2"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+const (
+	defaultMaxAttempts = 3
+	defaultBaseDelay   = 1 * time.Second
+	defaultMaxDelay    = 30 * time.Second
+)
+
+// RetryPolicy controls how many times DoAPIRequest will try a request before giving up. Between
+// attempts we back off exponentially from BaseDelay up to MaxDelay with some jitter so that all
+// of our goroutines don't hammer the server at the same moment. The zero value makes a single
+// attempt which is what we want in most tests.
+type RetryPolicy struct {
+	MaxAttempts int
+	BaseDelay   time.Duration
+	MaxDelay    time.Duration
+}
+
+func DefaultRetryPolicy() RetryPolicy {
+	return RetryPolicy{
+		MaxAttempts: defaultMaxAttempts,
+		BaseDelay:   defaultBaseDelay,
+		MaxDelay:    defaultMaxDelay,
+	}
+}
+
+// We never retry methods that are not idempotent as the first attempt may have gone through
+func (p RetryPolicy) maxAttemptsFor(method string) int {
+	if p.MaxAttempts < 1 || !isIdempotent(method) {
+		return 1
+	}
+	return p.MaxAttempts
+}
+
+// Returns how long to wait before the next attempt and false if we should not retry at all. If
+// the server sent Retry-After we honour it, unless it asks us to wait longer than MaxDelay in
+// which case we give up rather than block the whole run.
+func (p RetryPolicy) delay(attempt int, respHeaders http.Header) (time.Duration, bool) {
+	if retryAfter, ok := ParseRetryAfter(respHeaders, time.Now()); ok {
+		if retryAfter > p.MaxDelay {
+			return 0, false
+		}
+		return retryAfter, true
+	}
+
+	backoff := p.BaseDelay << (attempt - 1)
+	if backoff > p.MaxDelay || backoff <= 0 {
+		backoff = p.MaxDelay
+	}
+	// Equal jitter: wait at least half of the backoff plus a random amount up to the other half
+	half := backoff / 2
+	if half <= 0 {
+		return backoff, true
+	}
+	return half + rand.N(half), true
+}
+
+func isIdempotent(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodPut, http.MethodDelete:
+		return true
+	}
+	return false
+}
+
+// Transient server errors and throttling are worth another try. GitHub also uses 403 with a
+// Retry-After header for its secondary rate limits.
+func isRetryableStatus(statusCode int, respHeaders http.Header) bool {
+	switch statusCode {
+	case http.StatusRequestTimeout,
+		http.StatusTooManyRequests,
+		http.StatusInternalServerError,
+		http.StatusBadGateway,
+		http.StatusServiceUnavailable,
+		http.StatusGatewayTimeout:
+		return true
+	case http.StatusForbidden:
+		return respHeaders.Get("Retry-After") != ""
+	}
+	return false
+}
+
+// Retry-After can either be a number of seconds or an HTTP date. Returns false if it is missing.
+func ParseRetryAfter(respHeaders http.Header, now time.Time) (time.Duration, bool) {
+	value := respHeaders.Get("Retry-After")
+	if value == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.Atoi(value); err == nil && seconds >= 0 {
+		return time.Duration(seconds) * time.Second, true
+	}
+	if when, err := http.ParseTime(value); err == nil {
+		return max(when.Sub(now), 0), true
+	}
+	return 0, false
+}
+
+// Sleep that wakes up early if the context is cancelled
+func sleepContext(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
@@ -0,0 +1,239 @@
+package common
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/atomicmeganerd/starfeed/testutils"
+)
+
+var fastRetryPolicy = RetryPolicy{
+	MaxAttempts: 3,
+	BaseDelay:   time.Millisecond,
+	MaxDelay:    10 * time.Millisecond,
+}
+
+func mockResponse(statusCode int, headers http.Header) http.Response {
+	return http.Response{
+		StatusCode: statusCode,
+		Status:     http.StatusText(statusCode),
+		Header:     headers,
+		Body:       io.NopCloser(strings.NewReader("body")),
+	}
+}
+
+func TestDoAPIRequestRetries(t *testing.T) {
+	testCases := []struct {
+		name             string
+		method           string
+		responses        []http.Response
+		expectedCalls    int
+		expectError      bool
+		expectedAttempts int
+	}{
+		{
+			name:   "502 then 200 succeeds on the second attempt",
+			method: http.MethodGet,
+			responses: []http.Response{
+				mockResponse(http.StatusBadGateway, http.Header{}),
+				mockResponse(http.StatusOK, http.Header{}),
+			},
+			expectedCalls: 2,
+		},
+		{
+			name:   "503 on every attempt gives up after max attempts",
+			method: http.MethodGet,
+			responses: []http.Response{
+				mockResponse(http.StatusServiceUnavailable, http.Header{}),
+				mockResponse(http.StatusServiceUnavailable, http.Header{}),
+				mockResponse(http.StatusServiceUnavailable, http.Header{}),
+			},
+			expectedCalls:    3,
+			expectError:      true,
+			expectedAttempts: 3,
+		},
+		{
+			name:   "404 is not retried",
+			method: http.MethodGet,
+			responses: []http.Response{
+				mockResponse(http.StatusNotFound, http.Header{}),
+			},
+			expectedCalls:    1,
+			expectError:      true,
+			expectedAttempts: 1,
+		},
+		{
+			name:   "POST is not retried",
+			method: http.MethodPost,
+			responses: []http.Response{
+				mockResponse(http.StatusBadGateway, http.Header{}),
+				mockResponse(http.StatusOK, http.Header{}),
+			},
+			expectedCalls:    1,
+			expectError:      true,
+			expectedAttempts: 1,
+		},
+		{
+			name:   "429 with short Retry-After is retried",
+			method: http.MethodGet,
+			responses: []http.Response{
+				mockResponse(http.StatusTooManyRequests, http.Header{"Retry-After": {"0"}}),
+				mockResponse(http.StatusOK, http.Header{}),
+			},
+			expectedCalls: 2,
+		},
+		{
+			name:   "Retry-After longer than max delay is not retried",
+			method: http.MethodGet,
+			responses: []http.Response{
+				mockResponse(http.StatusTooManyRequests, http.Header{"Retry-After": {"3600"}}),
+				mockResponse(http.StatusOK, http.Header{}),
+			},
+			expectedCalls:    1,
+			expectError:      true,
+			expectedAttempts: 1,
+		},
+		{
+			name:   "403 without Retry-After is not retried",
+			method: http.MethodGet,
+			responses: []http.Response{
+				mockResponse(http.StatusForbidden, http.Header{}),
+			},
+			expectedCalls:    1,
+			expectError:      true,
+			expectedAttempts: 1,
+		},
+		{
+			name:   "403 with Retry-After is a secondary rate limit and is retried",
+			method: http.MethodGet,
+			responses: []http.Response{
+				mockResponse(http.StatusForbidden, http.Header{"Retry-After": {"0"}}),
+				mockResponse(http.StatusOK, http.Header{}),
+			},
+			expectedCalls: 2,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			mockTransport := testutils.NewMockMultiResponseRoundTripper(tc.responses)
+			client := &http.Client{Transport: &mockTransport}
+
+			_, _, err := DoAPIRequest(
+				context.Background(),
+				tc.method,
+				MockURL1,
+				nil,
+				http.Header{},
+				client,
+				fastRetryPolicy,
+			)
+
+			if calls := mockTransport.GetNumCalls(); calls != tc.expectedCalls {
+				t.Errorf("Expected %d calls but got %d", tc.expectedCalls, calls)
+			}
+
+			if !tc.expectError {
+				if err != nil {
+					t.Fatalf("Expected no error but got %v", err)
+				}
+				return
+			}
+
+			httpErr, ok := errors.AsType[HTTPError](err)
+			if !ok {
+				t.Fatalf("Expected an HTTPError but got %v", err)
+			}
+			if httpErr.Attempts != tc.expectedAttempts {
+				t.Errorf("Expected %d attempts but got %d", tc.expectedAttempts, httpErr.Attempts)
+			}
+		})
+	}
+}
+
+func TestDoAPIRequestRetriesNetworkErrors(t *testing.T) {
+	// The mock transport returns an error once it runs out of responses so every attempt fails
+	mockTransport := testutils.NewMockMultiResponseRoundTripper([]http.Response{})
+	client := &http.Client{Transport: &mockTransport}
+
+	_, _, err := DoAPIRequest(
+		context.Background(), http.MethodGet, MockURL1, nil, http.Header{}, client, fastRetryPolicy,
+	)
+	if err == nil {
+		t.Fatalf("Expected an error but got none")
+	}
+	if calls := mockTransport.GetNumCalls(); calls != fastRetryPolicy.MaxAttempts {
+		t.Errorf("Expected %d calls but got %d", fastRetryPolicy.MaxAttempts, calls)
+	}
+}
+
+func TestDoAPIRequestStopsWhenContextCancelled(t *testing.T) {
+	responses := []http.Response{
+		mockResponse(http.StatusBadGateway, http.Header{}),
+		mockResponse(http.StatusOK, http.Header{}),
+	}
+	mockTransport := testutils.NewMockMultiResponseRoundTripper(responses)
+	client := &http.Client{Transport: &mockTransport}
+
+	// A long delay means we will be sleeping when the context is cancelled
+	policy := RetryPolicy{MaxAttempts: 3, BaseDelay: time.Hour, MaxDelay: time.Hour}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	_, _, err := DoAPIRequest(ctx, http.MethodGet, MockURL1, nil, http.Header{}, client, policy)
+	if err == nil {
+		t.Fatalf("Expected an error but got none")
+	}
+	if calls := mockTransport.GetNumCalls(); calls != 1 {
+		t.Errorf("Expected 1 call but got %d", calls)
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	testCases := []struct {
+		name          string
+		value         string
+		expectedDelay time.Duration
+		expectedOK    bool
+	}{
+		{name: "missing header", value: "", expectedOK: false},
+		{name: "seconds", value: "30", expectedDelay: 30 * time.Second, expectedOK: true},
+		{
+			name:          "http date",
+			value:         now.Add(time.Minute).Format(http.TimeFormat),
+			expectedDelay: time.Minute,
+			expectedOK:    true,
+		},
+		{
+			name:          "http date in the past",
+			value:         now.Add(-time.Minute).Format(http.TimeFormat),
+			expectedDelay: 0,
+			expectedOK:    true,
+		},
+		{name: "garbage", value: "soon", expectedOK: false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			headers := http.Header{}
+			if tc.value != "" {
+				headers.Set("Retry-After", tc.value)
+			}
+			delay, ok := ParseRetryAfter(headers, now)
+			if ok != tc.expectedOK || delay != tc.expectedDelay {
+				t.Errorf(
+					"ParseRetryAfter(%q) = (%s, %v), want (%s, %v)",
+					tc.value, delay, ok, tc.expectedDelay, tc.expectedOK,
+				)
+			}
+		})
+	}
+}
@@ -3,8 +3,14 @@ package config
 import (
 	"bytes"
 	"fmt"
+	"io"
+	"path"
+	"regexp"
+	"slices"
+	"text/template"
 	"time"
 
+	"github.com/atomicmeganerd/starfeed/common"
 	"github.com/go-playground/validator/v10"
 	"github.com/pelletier/go-toml/v2"
 )
@@ -17,30 +23,209 @@ type Config struct {
 	RunInterval duration         `validate:"required"            toml:"run_interval"`
 	Debug       bool             `                               toml:"debug"`
 	SingleRun   bool             `                               toml:"single_run"`
+	// Optional. If set we keep an HTTP cache of starred repo pages and release feeds here.
+	CacheDir string `toml:"cache_dir"`
+	// Optional. If set we remember which repo each feed belongs to here so renames can be followed.
+	StateDir string `toml:"state_dir"`
+	// Optional. The defaults from common.DefaultRetryPolicy are used for anything not set.
+	Retry RetryConfig `toml:"retry"`
+	// Optional. Serves the feeds of private repos to the RSS server for forges that proxy them.
+	FeedProxy *FeedProxyConfig `toml:"feed_proxy"`
 }
 
 func (c Config) Interval() time.Duration {
 	return time.Duration(c.RunInterval)
 }
 
+// Builds the retry policy shared by all of our HTTP clients
+func (c Config) RetryPolicy() common.RetryPolicy {
+	policy := common.DefaultRetryPolicy()
+	if c.Retry.MaxAttempts != 0 {
+		policy.MaxAttempts = c.Retry.MaxAttempts
+	}
+	if c.Retry.BaseDelay != 0 {
+		policy.BaseDelay = time.Duration(c.Retry.BaseDelay)
+	}
+	if c.Retry.MaxDelay != 0 {
+		policy.MaxDelay = time.Duration(c.Retry.MaxDelay)
+	}
+	return policy
+}
+
+// This type holds and validates how we retry transient HTTP failures
+type RetryConfig struct {
+	MaxAttempts int   `validate:"omitempty,min=1,max=10" toml:"max_attempts"`
+	BaseDelay   delay `                                  toml:"base_delay"`
+	MaxDelay    delay `                                  toml:"max_delay"`
+}
+
+// This type holds and validates the feed proxy. Its URL is the base URL the RSS server reaches
+// us at and the token is the secret the feed URLs are signed with.
+type FeedProxyConfig struct {
+	Listen string `validate:"required"                      toml:"listen"`
+	URL    string `validate:"required,url,startswith=http" toml:"url"`
+	TokenSource
+}
+
 // This type both holds and validates the config for a GitForge
 type GitForgeConfig struct {
-	Type  string `validate:"required,oneof=github forgejo" toml:"type"`
-	Name  string `validate:"required,min=3"                toml:"name"`
-	Fqdn  string `validate:"required,min=8"                toml:"fqdn"`
-	Token string `validate:"required,min=10"` // WARNING: This is a secret
+	Type string `validate:"required,oneof=github forgejo gitlab"    toml:"type"`
+	Name string `validate:"required,min=3"                          toml:"name"`
+	Fqdn string `validate:"required_without=APIURL,omitempty,min=8" toml:"fqdn"`
+	// Optional overrides for when the endpoints can't be derived from the fqdn
+	APIURL string `validate:"omitempty,url,startswith=http" toml:"api_url"`
+	WebURL string `validate:"omitempty,url,startswith=http" toml:"web_url"`
+	// Optional. What to do with archived repos: keep (the default), remove or move.
+	Archived         string `validate:"omitempty,oneof=keep remove move" toml:"archived"`
+	ArchivedCategory string `                                            toml:"archived_category"`
+	// Optional. A Go template for feed titles, e.g. "{{.Owner}}/{{.Name}} releases".
+	TitleTemplate string `validate:"omitempty,gotemplate" toml:"title_template"`
+	// Optional. Which feed to follow: releases (the default), tags or releases_or_tags. It can be
+	// overridden per repo by its full name (owner/name).
+	FeedKind          string            `validate:"omitempty,feedkind" toml:"feed_kind"`
+	FeedKindOverrides map[string]string `validate:"dive,feedkind"      toml:"feed_kind_overrides"`
+	// Optional. Feeds that have not released for longer than this are dormant and are either
+	// skipped (the default) or also removed.
+	MaxReleaseAge age    `                                    toml:"max_release_age"`
+	Dormant       string `validate:"omitempty,oneof=skip remove" toml:"dormant"`
+	// Optional. Rules that include or exclude starred repos. The first rule that matches a repo
+	// decides and repos no rule matches are included.
+	Rules []RepoRuleConfig `validate:"dive" toml:"rules"`
+	// Optional. Follow the stars of one or more other users or organisations instead of the
+	// token owner's.
+	Username  string   `                                             toml:"username"`
+	Usernames []string `validate:"excluded_with=Username,dive,min=1" toml:"usernames"`
+	// Optional. Follow starred repos (the default), watched repos or both. GitLab only has stars.
+	Sources []string `validate:"dive,oneof=starred watched" toml:"sources"`
+	// Optional and GitHub only. Put the feeds of repos in a star list in a category named after
+	// the list, optionally prefixed with the name of the forge.
+	StarLists      bool `validate:"excluded_unless=Type github" toml:"star_lists"`
+	StarListPrefix bool `                                       toml:"star_list_prefix"`
+	// Optional. What to do with private repos whose feeds the RSS server can't read: skip them
+	// (the default) or proxy them through the feed proxy.
+	Private string `validate:"omitempty,oneof=skip proxy" toml:"private"`
+	// Optional. Whether to follow forks (the default), the parents they were forked from or both.
+	Forks string `validate:"omitempty,oneof=self parent both" toml:"forks"`
+	// Optional and Forgejo only. Whether to follow pull mirrors (the default) or the project
+	// they mirror.
+	Mirrors string `validate:"omitempty,oneof=self origin" toml:"mirrors"`
+	// GitHub only. Authenticate as a GitHub App installation instead of with a token. An
+	// installation has no stars of its own so the users to follow have to be set.
+	GitHubApp *GitHubAppConfig `validate:"omitempty,excluded_unless=Type github" toml:"github_app"`
+	// Optional and GitHub only. The client id of an OAuth app on the forge that `starfeed login`
+	// uses to get a token with the device flow.
+	OAuthClientID string `validate:"excluded_unless=Type github" toml:"oauth_client_id"`
+	TokenSource
+}
+
+// This type holds and validates the GitHub App a forge authenticates as
+type GitHubAppConfig struct {
+	AppID          int64  `validate:"required,min=1" toml:"app_id"`
+	InstallationID int64  `validate:"required,min=1" toml:"installation_id"`
+	PrivateKeyFile string `validate:"required"       toml:"private_key_file"`
+}
+
+// This type holds and validates a rule for starred repos. Every condition that is set has to
+// match for the rule to match.
+type RepoRuleConfig struct {
+	Action     string   `validate:"required,oneof=include exclude" toml:"action"`
+	Owner      string   `validate:"omitempty,glob"                 toml:"owner"`
+	Name       string   `validate:"omitempty,glob"                 toml:"name"`
+	OwnerRegex string   `validate:"omitempty,regexp"               toml:"owner_regex"`
+	NameRegex  string   `validate:"omitempty,regexp"               toml:"name_regex"`
+	Language   string   `                                          toml:"language"`
+	Topics     []string `                                          toml:"topics"`
+	Fork       *bool    `                                          toml:"fork"`
+	Archived   *bool    `                                          toml:"archived"`
+	MinStars   int      `validate:"min=0"                          toml:"min_stars"`
+}
+
+// The category archived repos are moved to. Each forge needs its own as the runner removes
+// feeds from it that are not starred on its forge.
+func (g GitForgeConfig) ArchivedCategoryName() string {
+	if g.ArchivedCategory != "" {
+		return g.ArchivedCategory
+	}
+	return g.Name + " Archived"
+}
+
+// The categories the runner of the forge owns on top of those of its star lists
+func (g GitForgeConfig) categories() []string {
+	categories := []string{g.Name}
+	if g.Archived == "move" {
+		categories = append(categories, g.ArchivedCategoryName())
+	}
+	return categories
+}
+
+// The users whose stars we follow. It is empty if we follow the token owner.
+func (g GitForgeConfig) StarredUsernames() []string {
+	if g.Username != "" {
+		return []string{g.Username}
+	}
+	return g.Usernames
+}
+
+func (g GitForgeConfig) MaxReleaseAgeDuration() time.Duration {
+	return time.Duration(g.MaxReleaseAge)
+}
+
+// Parses the title template. Returns nil if none was configured.
+func (g GitForgeConfig) FeedTitleTemplate() (*template.Template, error) {
+	if g.TitleTemplate == "" {
+		return nil, nil
+	}
+	return template.New(g.Name).Parse(g.TitleTemplate)
 }
 
 // This type both holds and validates the config for the RSS Server
 type RSSServerConfig struct {
-	Name  string `validate:"required,oneof=freshrss" toml:"name"`
-	URL   string `validate:"required,url"            toml:"url"`
-	User  string `validate:"required,min=3"          toml:"user"`
-	Token string `validate:"required,min=10"` // WARNING: This is a secret
+	Name string `validate:"required,rssserver"                  toml:"name"`
+	URL  string `validate:"required_unless=Name opml,omitempty,url" toml:"url"`
+	// Miniflux only needs its API key and an OPML file needs neither
+	User string `validate:"omitempty,min=3" toml:"user"`
+	// OPML only. The file we keep our feeds in.
+	Path string `validate:"required_if=Name opml,excluded_unless=Name opml" toml:"path"`
+	// Optional and greader only. Where the Google Reader API of the server differs from the
+	// defaults.
+	GReader *GReaderConfig `validate:"omitempty,excluded_unless=Name greader" toml:"greader"`
+	TokenSource
+}
+
+// An OPML file is written locally so there is nothing to authenticate to
+func (r RSSServerConfig) NeedsToken() bool {
+	return r.Name != "opml"
+}
+
+func (r RSSServerConfig) needsUser() bool {
+	return r.Name != "miniflux" && r.Name != "opml"
+}
+
+// This type holds and validates the paths and label prefix of a Google Reader API server
+type GReaderConfig struct {
+	BasePath    string `validate:"omitempty,startswith=/" toml:"base_path"`
+	LoginPath   string `validate:"omitempty,startswith=/" toml:"login_path"`
+	LabelPrefix string `validate:"omitempty,endswith=/"   toml:"label_prefix"`
 }
 
 func NewConfig(cl configLoader) (Config, error) {
 	validate := validator.New()
+	validate.RegisterStructValidation(validateTokenSource, TokenSource{})
+	validate.RegisterStructValidation(validateGitForge, GitForgeConfig{})
+	validate.RegisterStructValidation(validateRSSServer, RSSServerConfig{})
+	validate.RegisterStructValidation(validateFeedProxy, FeedProxyConfig{})
+	validate.RegisterStructValidation(validateConfig, Config{})
+	validate.RegisterAlias("feedkind", "oneof=releases tags releases_or_tags")
+	validate.RegisterAlias("rssserver", "oneof=freshrss greader miniflux nextcloud_news ttrss opml")
+	if err := validate.RegisterValidation("gotemplate", validateTemplate); err != nil {
+		return Config{}, fmt.Errorf("could not register template validation: %w", err)
+	}
+	if err := validate.RegisterValidation("glob", validateGlob); err != nil {
+		return Config{}, fmt.Errorf("could not register glob validation: %w", err)
+	}
+	if err := validate.RegisterValidation("regexp", validateRegexp); err != nil {
+		return Config{}, fmt.Errorf("could not register regexp validation: %w", err)
+	}
 
 	cfgData, err := cl.LoadConfig()
 	if err != nil {
@@ -63,3 +248,133 @@ func NewConfig(cl configLoader) (Config, error) {
 
 	return cfg, nil
 }
+
+// The fields a title template can use. This has to match runners.FeedTitleData.
+type titleTemplateData struct {
+	Owner       string
+	Name        string
+	FullName    string
+	Description string
+}
+
+// Checks that a field holds a Go template that parses and renders a title
+func validateTemplate(fl validator.FieldLevel) bool {
+	return checkTitleTemplate(fl.FieldName(), fl.Field().String()) == nil
+}
+
+// A template that parses can still fail on every repo, e.g. when it uses a field we don't have,
+// so we render it for a sample repo as well
+func checkTitleTemplate(name, text string) error {
+	tmpl, err := template.New(name).Parse(text)
+	if err != nil {
+		return err
+	}
+	return tmpl.Execute(io.Discard, titleTemplateData{
+		Owner:       "owner",
+		Name:        "repo",
+		FullName:    "owner/repo",
+		Description: "A sample repo",
+	})
+}
+
+// GitLab has no equivalent of watched repos, only Forgejo reports pull mirrors and a forge needs
+// exactly one way to authenticate
+func validateGitForge(sl validator.StructLevel) {
+	forge := sl.Current().Interface().(GitForgeConfig)
+	if forge.Type == "gitlab" && slices.Contains(forge.Sources, "watched") {
+		sl.ReportError(forge.Sources, "Sources", "Sources", "gitlab_watched", "")
+	}
+	if forge.Type != "forgejo" && forge.Mirrors != "" {
+		sl.ReportError(forge.Mirrors, "Mirrors", "Mirrors", "forgejo_mirrors", "")
+	}
+	if forge.GitHubApp == nil {
+		if forge.numSources() == 0 {
+			sl.ReportError(forge.Token, "Token", "Token", "one_token_source", "")
+		}
+		return
+	}
+	if forge.numSources() > 0 {
+		sl.ReportError(forge.GitHubApp, "GitHubApp", "GitHubApp", "github_app_or_token", "")
+	}
+	if len(forge.StarredUsernames()) == 0 {
+		sl.ReportError(forge.GitHubApp, "GitHubApp", "GitHubApp", "github_app_usernames", "")
+	}
+}
+
+// Every RSS server but an OPML file authenticates with a token and most of them with a user too
+func validateRSSServer(sl validator.StructLevel) {
+	server := sl.Current().Interface().(RSSServerConfig)
+	if server.needsUser() && server.User == "" {
+		sl.ReportError(server.User, "User", "User", "required", "")
+	}
+	if server.NeedsToken() && server.numSources() == 0 {
+		sl.ReportError(server.Token, "Token", "Token", "one_token_source", "")
+	}
+}
+
+// The feed proxy always needs a secret to sign its URLs with
+func validateFeedProxy(sl validator.StructLevel) {
+	proxy := sl.Current().Interface().(FeedProxyConfig)
+	if proxy.numSources() == 0 {
+		sl.ReportError(proxy.Token, "Token", "Token", "one_token_source", "")
+	}
+}
+
+// Forges can only proxy private repos if the feed proxy is configured
+func validateConfig(sl validator.StructLevel) {
+	cfg := sl.Current().Interface().(Config)
+	proxied := slices.ContainsFunc(cfg.GitForges, func(forge GitForgeConfig) bool {
+		return forge.Private == "proxy"
+	})
+	if proxied && cfg.FeedProxy == nil {
+		sl.ReportError(cfg.FeedProxy, "FeedProxy", "FeedProxy", "required_for_private_proxy", "")
+	}
+	if !uniqueCategories(cfg.GitForges) {
+		sl.ReportError(cfg.GitForges, "GitForges", "GitForges", "unique_categories", "")
+	}
+	validateStarLists(sl, cfg)
+}
+
+// Unprefixed star list categories may hold feeds we did not add, so we need the repo index in
+// state_dir to know which ones are ours. Another forge's lists could also be named the same.
+func validateStarLists(sl validator.StructLevel, cfg Config) {
+	unprefixed := slices.ContainsFunc(cfg.GitForges, func(forge GitForgeConfig) bool {
+		return forge.StarLists && !forge.StarListPrefix
+	})
+	if !unprefixed {
+		return
+	}
+	if cfg.StateDir == "" {
+		sl.ReportError(cfg.StateDir, "StateDir", "StateDir", "required_for_star_lists", "")
+	}
+	if len(cfg.GitForges) > 1 {
+		sl.ReportError(cfg.GitForges, "GitForges", "GitForges", "star_list_prefix_required", "")
+	}
+}
+
+// The runners remove the feeds in their categories that are not from their forge so no two
+// forges may own the same category
+func uniqueCategories(forges []GitForgeConfig) bool {
+	seen := common.NewSet[string]()
+	for _, forge := range forges {
+		for _, category := range forge.categories() {
+			if seen.Contains(category) {
+				return false
+			}
+			seen.Add(category)
+		}
+	}
+	return true
+}
+
+// Checks that a field holds a glob that path.Match understands
+func validateGlob(fl validator.FieldLevel) bool {
+	_, err := path.Match(fl.Field().String(), "")
+	return err == nil
+}
+
+// Checks that a field holds a regular expression that compiles
+func validateRegexp(fl validator.FieldLevel) bool {
+	_, err := regexp.Compile(fl.Field().String())
+	return err == nil
+}
@@ -6,6 +6,7 @@ import (
 	"testing"
 	"time"
 
+	"github.com/atomicmeganerd/starfeed/common"
 	"github.com/atomicmeganerd/starfeed/testutils"
 )
 
@@ -45,17 +46,17 @@ token = "freshrss_token_12345"
 				RunInterval: duration(expectedRunInterval),
 				GitForges: []GitForgeConfig{
 					{
-						Type:  "github",
-						Name:  "GitHub",
-						Fqdn:  "github.com",
-						Token: "ghp_1234567890abcdef",
+						Type:        "github",
+						Name:        "GitHub",
+						Fqdn:        "github.com",
+						TokenSource: TokenSource{Token: "ghp_1234567890abcdef"},
 					},
 				},
 				RSSServer: RSSServerConfig{
-					Name:  "freshrss",
-					URL:   "http://freshrss:80",
-					User:  "testuser",
-					Token: "freshrss_token_12345",
+					Name:        "freshrss",
+					URL:         "http://freshrss:80",
+					User:        "testuser",
+					TokenSource: TokenSource{Token: "freshrss_token_12345"},
 				},
 			},
 			expectErr: false,
@@ -87,17 +88,17 @@ token = "freshrss_token_12345"
 				RunInterval: duration(expectedRunInterval),
 				GitForges: []GitForgeConfig{
 					{
-						Type:  "forgejo",
-						Name:  "Codeberg",
-						Fqdn:  "codeberg.org",
-						Token: "forgejo_token_123456",
+						Type:        "forgejo",
+						Name:        "Codeberg",
+						Fqdn:        "codeberg.org",
+						TokenSource: TokenSource{Token: "forgejo_token_123456"},
 					},
 				},
 				RSSServer: RSSServerConfig{
-					Name:  "freshrss",
-					URL:   "http://freshrss:80",
-					User:  "testuser",
-					Token: "freshrss_token_12345",
+					Name:        "freshrss",
+					URL:         "http://freshrss:80",
+					User:        "testuser",
+					TokenSource: TokenSource{Token: "freshrss_token_12345"},
 				},
 			},
 			expectErr: false,
@@ -135,27 +136,2003 @@ token = "freshrss_token_12345"
 				RunInterval: duration(expectedRunInterval),
 				GitForges: []GitForgeConfig{
 					{
-						Type:  "github",
-						Name:  "GitHub",
-						Fqdn:  "github.com",
-						Token: "ghp_1234567890abcdef",
+						Type:        "github",
+						Name:        "GitHub",
+						Fqdn:        "github.com",
+						TokenSource: TokenSource{Token: "ghp_1234567890abcdef"},
 					},
 					{
-						Type:  "forgejo",
-						Name:  "Codeberg",
-						Fqdn:  "codeberg.org",
-						Token: "forgejo_token_123456",
+						Type:        "forgejo",
+						Name:        "Codeberg",
+						Fqdn:        "codeberg.org",
+						TokenSource: TokenSource{Token: "forgejo_token_123456"},
 					},
 				},
 				RSSServer: RSSServerConfig{
-					Name:  "freshrss",
-					URL:   "http://freshrss:80",
-					User:  "testuser",
-					Token: "freshrss_token_12345",
+					Name:        "freshrss",
+					URL:         "http://freshrss:80",
+					User:        "testuser",
+					TokenSource: TokenSource{Token: "freshrss_token_12345"},
 				},
 			},
 			expectErr: false,
 		},
+		{
+			name: "valid config with gitlab forge",
+			mockCfgData: func() []byte {
+				return []byte(`
+debug = false
+single_run = false
+run_interval = "24h"
+
+[[git_forges]]
+type = "gitlab"
+name = "GitLab"
+fqdn = "gitlab.com"
+token = "glpat-1234567890abcdef"
+
+[rss_server]
+name = "freshrss"
+url = "http://freshrss:80"
+user = "testuser"
+token = "freshrss_token_12345"
+`)
+			},
+			expectedConfig: Config{
+				Debug:       false,
+				SingleRun:   false,
+				RunInterval: duration(expectedRunInterval),
+				GitForges: []GitForgeConfig{
+					{
+						Type:        "gitlab",
+						Name:        "GitLab",
+						Fqdn:        "gitlab.com",
+						TokenSource: TokenSource{Token: "glpat-1234567890abcdef"},
+					},
+				},
+				RSSServer: RSSServerConfig{
+					Name:        "freshrss",
+					URL:         "http://freshrss:80",
+					User:        "testuser",
+					TokenSource: TokenSource{Token: "freshrss_token_12345"},
+				},
+			},
+			expectErr: false,
+		},
+		{
+			name: "valid config with cache and state dirs",
+			mockCfgData: func() []byte {
+				return []byte(`
+run_interval = "24h"
+cache_dir = "/var/cache/starfeed"
+state_dir = "/var/lib/starfeed"
+
+[[git_forges]]
+type = "github"
+name = "GitHub"
+fqdn = "github.com"
+token = "ghp_1234567890abcdef"
+
+[rss_server]
+name = "freshrss"
+url = "http://freshrss:80"
+user = "testuser"
+token = "freshrss_token_12345"
+`)
+			},
+			expectedConfig: Config{
+				RunInterval: duration(expectedRunInterval),
+				CacheDir:    "/var/cache/starfeed",
+				StateDir:    "/var/lib/starfeed",
+				GitForges: []GitForgeConfig{
+					{
+						Type:        "github",
+						Name:        "GitHub",
+						Fqdn:        "github.com",
+						TokenSource: TokenSource{Token: "ghp_1234567890abcdef"},
+					},
+				},
+				RSSServer: RSSServerConfig{
+					Name:        "freshrss",
+					URL:         "http://freshrss:80",
+					User:        "testuser",
+					TokenSource: TokenSource{Token: "freshrss_token_12345"},
+				},
+			},
+			expectErr: false,
+		},
+		{
+			name: "valid config with retry policy",
+			mockCfgData: func() []byte {
+				return []byte(`
+run_interval = "24h"
+
+[retry]
+max_attempts = 5
+base_delay = "500ms"
+max_delay = "1m"
+
+[[git_forges]]
+type = "github"
+name = "GitHub"
+fqdn = "github.com"
+token = "ghp_1234567890abcdef"
+
+[rss_server]
+name = "freshrss"
+url = "http://freshrss:80"
+user = "testuser"
+token = "freshrss_token_12345"
+`)
+			},
+			expectedConfig: Config{
+				RunInterval: duration(expectedRunInterval),
+				Retry: RetryConfig{
+					MaxAttempts: 5,
+					BaseDelay:   delay(500 * time.Millisecond),
+					MaxDelay:    delay(time.Minute),
+				},
+				GitForges: []GitForgeConfig{
+					{
+						Type:        "github",
+						Name:        "GitHub",
+						Fqdn:        "github.com",
+						TokenSource: TokenSource{Token: "ghp_1234567890abcdef"},
+					},
+				},
+				RSSServer: RSSServerConfig{
+					Name:        "freshrss",
+					URL:         "http://freshrss:80",
+					User:        "testuser",
+					TokenSource: TokenSource{Token: "freshrss_token_12345"},
+				},
+			},
+			expectErr: false,
+		},
+		{
+			name: "invalid retry max_attempts",
+			mockCfgData: func() []byte {
+				return []byte(`
+run_interval = "24h"
+
+[retry]
+max_attempts = 50
+
+[[git_forges]]
+type = "github"
+name = "GitHub"
+fqdn = "github.com"
+token = "ghp_1234567890abcdef"
+
+[rss_server]
+name = "freshrss"
+url = "http://freshrss:80"
+user = "testuser"
+token = "freshrss_token_12345"
+`)
+			},
+			expectErr: true,
+		},
+		{
+			name: "invalid retry delay above maximum",
+			mockCfgData: func() []byte {
+				return []byte(`
+run_interval = "24h"
+
+[retry]
+max_delay = "1h"
+
+[[git_forges]]
+type = "github"
+name = "GitHub"
+fqdn = "github.com"
+token = "ghp_1234567890abcdef"
+
+[rss_server]
+name = "freshrss"
+url = "http://freshrss:80"
+user = "testuser"
+token = "freshrss_token_12345"
+`)
+			},
+			expectErr: true,
+		},
+		{
+			name: "valid config with token file and token env",
+			mockCfgData: func() []byte {
+				return []byte(`
+run_interval = "24h"
+
+[[git_forges]]
+type = "github"
+name = "GitHub"
+fqdn = "github.com"
+token_file = "/run/secrets/github_token"
+
+[rss_server]
+name = "freshrss"
+url = "http://freshrss:80"
+user = "testuser"
+token_env = "FRESHRSS_TOKEN"
+`)
+			},
+			expectedConfig: Config{
+				RunInterval: duration(expectedRunInterval),
+				GitForges: []GitForgeConfig{
+					{
+						Type:        "github",
+						Name:        "GitHub",
+						Fqdn:        "github.com",
+						TokenSource: TokenSource{TokenFile: "/run/secrets/github_token"},
+					},
+				},
+				RSSServer: RSSServerConfig{
+					Name:        "freshrss",
+					URL:         "http://freshrss:80",
+					User:        "testuser",
+					TokenSource: TokenSource{TokenEnv: "FRESHRSS_TOKEN"},
+				},
+			},
+			expectErr: false,
+		},
+		{
+			name: "valid config with token command",
+			mockCfgData: func() []byte {
+				return []byte(`
+run_interval = "24h"
+
+[[git_forges]]
+type = "github"
+name = "GitHub"
+fqdn = "github.com"
+token_command = ["pass", "show", "github"]
+
+[rss_server]
+name = "freshrss"
+url = "http://freshrss:80"
+user = "testuser"
+token = "freshrss_token_12345"
+`)
+			},
+			expectedConfig: Config{
+				RunInterval: duration(expectedRunInterval),
+				GitForges: []GitForgeConfig{
+					{
+						Type: "github",
+						Name: "GitHub",
+						Fqdn: "github.com",
+						TokenSource: TokenSource{
+							TokenCommand: []string{"pass", "show", "github"},
+						},
+					},
+				},
+				RSSServer: RSSServerConfig{
+					Name:        "freshrss",
+					URL:         "http://freshrss:80",
+					User:        "testuser",
+					TokenSource: TokenSource{Token: "freshrss_token_12345"},
+				},
+			},
+			expectErr: false,
+		},
+		{
+			name: "more than one token source",
+			mockCfgData: func() []byte {
+				return []byte(`
+run_interval = "24h"
+
+[[git_forges]]
+type = "github"
+name = "GitHub"
+fqdn = "github.com"
+token = "ghp_1234567890abcdef"
+token_env = "GITHUB_TOKEN"
+
+[rss_server]
+name = "freshrss"
+url = "http://freshrss:80"
+user = "testuser"
+token = "freshrss_token_12345"
+`)
+			},
+			expectErr: true,
+		},
+		{
+			name: "no token source",
+			mockCfgData: func() []byte {
+				return []byte(`
+run_interval = "24h"
+
+[[git_forges]]
+type = "github"
+name = "GitHub"
+fqdn = "github.com"
+
+[rss_server]
+name = "freshrss"
+url = "http://freshrss:80"
+user = "testuser"
+token = "freshrss_token_12345"
+`)
+			},
+			expectErr: true,
+		},
+		{
+			name: "valid config with api_url and web_url instead of fqdn",
+			mockCfgData: func() []byte {
+				return []byte(`
+run_interval = "24h"
+
+[[git_forges]]
+type = "github"
+name = "GitHub Enterprise"
+api_url = "https://ghe.corp/api/v3"
+web_url = "https://ghe.corp"
+token = "ghp_1234567890abcdef"
+
+[rss_server]
+name = "freshrss"
+url = "http://freshrss:80"
+user = "testuser"
+token = "freshrss_token_12345"
+`)
+			},
+			expectedConfig: Config{
+				RunInterval: duration(expectedRunInterval),
+				GitForges: []GitForgeConfig{
+					{
+						Type:        "github",
+						Name:        "GitHub Enterprise",
+						APIURL:      "https://ghe.corp/api/v3",
+						WebURL:      "https://ghe.corp",
+						TokenSource: TokenSource{Token: "ghp_1234567890abcdef"},
+					},
+				},
+				RSSServer: RSSServerConfig{
+					Name:        "freshrss",
+					URL:         "http://freshrss:80",
+					User:        "testuser",
+					TokenSource: TokenSource{Token: "freshrss_token_12345"},
+				},
+			},
+			expectErr: false,
+		},
+		{
+			name: "missing both fqdn and api_url",
+			mockCfgData: func() []byte {
+				return []byte(`
+run_interval = "24h"
+
+[[git_forges]]
+type = "github"
+name = "GitHub"
+token = "ghp_1234567890abcdef"
+
+[rss_server]
+name = "freshrss"
+url = "http://freshrss:80"
+user = "testuser"
+token = "freshrss_token_12345"
+`)
+			},
+			expectErr: true,
+		},
+		{
+			name: "invalid api_url",
+			mockCfgData: func() []byte {
+				return []byte(`
+run_interval = "24h"
+
+[[git_forges]]
+type = "forgejo"
+name = "Forgejo"
+api_url = "forgejo.lab:3000/api/v1"
+token = "forgejo_token_123456"
+
+[rss_server]
+name = "freshrss"
+url = "http://freshrss:80"
+user = "testuser"
+token = "freshrss_token_12345"
+`)
+			},
+			expectErr: true,
+		},
+		{
+			name: "valid config with archived policy",
+			mockCfgData: func() []byte {
+				return []byte(`
+run_interval = "24h"
+
+[[git_forges]]
+type = "github"
+name = "GitHub"
+fqdn = "github.com"
+archived = "move"
+archived_category = "Archived"
+token = "ghp_1234567890abcdef"
+
+[rss_server]
+name = "freshrss"
+url = "http://freshrss:80"
+user = "testuser"
+token = "freshrss_token_12345"
+`)
+			},
+			expectedConfig: Config{
+				RunInterval: duration(expectedRunInterval),
+				GitForges: []GitForgeConfig{
+					{
+						Type:             "github",
+						Name:             "GitHub",
+						Fqdn:             "github.com",
+						Archived:         "move",
+						ArchivedCategory: "Archived",
+						TokenSource:      TokenSource{Token: "ghp_1234567890abcdef"},
+					},
+				},
+				RSSServer: RSSServerConfig{
+					Name:        "freshrss",
+					URL:         "http://freshrss:80",
+					User:        "testuser",
+					TokenSource: TokenSource{Token: "freshrss_token_12345"},
+				},
+			},
+			expectErr: false,
+		},
+		{
+			name: "invalid archived policy",
+			mockCfgData: func() []byte {
+				return []byte(`
+run_interval = "24h"
+
+[[git_forges]]
+type = "github"
+name = "GitHub"
+fqdn = "github.com"
+archived = "hide"
+token = "ghp_1234567890abcdef"
+
+[rss_server]
+name = "freshrss"
+url = "http://freshrss:80"
+user = "testuser"
+token = "freshrss_token_12345"
+`)
+			},
+			expectErr: true,
+		},
+		{
+			name: "valid config with title template",
+			mockCfgData: func() []byte {
+				return []byte(`
+run_interval = "24h"
+
+[[git_forges]]
+type = "github"
+name = "GitHub"
+fqdn = "github.com"
+title_template = "{{.Owner}}/{{.Name}} releases"
+token = "ghp_1234567890abcdef"
+
+[rss_server]
+name = "freshrss"
+url = "http://freshrss:80"
+user = "testuser"
+token = "freshrss_token_12345"
+`)
+			},
+			expectedConfig: Config{
+				RunInterval: duration(expectedRunInterval),
+				GitForges: []GitForgeConfig{
+					{
+						Type:          "github",
+						Name:          "GitHub",
+						Fqdn:          "github.com",
+						TitleTemplate: "{{.Owner}}/{{.Name}} releases",
+						TokenSource:   TokenSource{Token: "ghp_1234567890abcdef"},
+					},
+				},
+				RSSServer: RSSServerConfig{
+					Name:        "freshrss",
+					URL:         "http://freshrss:80",
+					User:        "testuser",
+					TokenSource: TokenSource{Token: "freshrss_token_12345"},
+				},
+			},
+			expectErr: false,
+		},
+		{
+			name: "title template that does not parse",
+			mockCfgData: func() []byte {
+				return []byte(`
+run_interval = "24h"
+
+[[git_forges]]
+type = "github"
+name = "GitHub"
+fqdn = "github.com"
+title_template = "{{.Owner}/{{.Name}}"
+token = "ghp_1234567890abcdef"
+
+[rss_server]
+name = "freshrss"
+url = "http://freshrss:80"
+user = "testuser"
+token = "freshrss_token_12345"
+`)
+			},
+			expectErr: true,
+		},
+		{
+			name: "valid config with feed kind and overrides",
+			mockCfgData: func() []byte {
+				return []byte(`
+run_interval = "24h"
+
+[[git_forges]]
+type = "github"
+name = "GitHub"
+fqdn = "github.com"
+feed_kind = "releases_or_tags"
+feed_kind_overrides = { "golang/go" = "tags" }
+token = "ghp_1234567890abcdef"
+
+[rss_server]
+name = "freshrss"
+url = "http://freshrss:80"
+user = "testuser"
+token = "freshrss_token_12345"
+`)
+			},
+			expectedConfig: Config{
+				RunInterval: duration(expectedRunInterval),
+				GitForges: []GitForgeConfig{
+					{
+						Type:              "github",
+						Name:              "GitHub",
+						Fqdn:              "github.com",
+						FeedKind:          "releases_or_tags",
+						FeedKindOverrides: map[string]string{"golang/go": "tags"},
+						TokenSource:       TokenSource{Token: "ghp_1234567890abcdef"},
+					},
+				},
+				RSSServer: RSSServerConfig{
+					Name:        "freshrss",
+					URL:         "http://freshrss:80",
+					User:        "testuser",
+					TokenSource: TokenSource{Token: "freshrss_token_12345"},
+				},
+			},
+			expectErr: false,
+		},
+		{
+			name: "invalid feed kind override",
+			mockCfgData: func() []byte {
+				return []byte(`
+run_interval = "24h"
+
+[[git_forges]]
+type = "github"
+name = "GitHub"
+fqdn = "github.com"
+feed_kind_overrides = { "golang/go" = "commits" }
+token = "ghp_1234567890abcdef"
+
+[rss_server]
+name = "freshrss"
+url = "http://freshrss:80"
+user = "testuser"
+token = "freshrss_token_12345"
+`)
+			},
+			expectErr: true,
+		},
+		{
+			name: "valid config with max release age in days",
+			mockCfgData: func() []byte {
+				return []byte(`
+run_interval = "24h"
+
+[[git_forges]]
+type = "github"
+name = "GitHub"
+fqdn = "github.com"
+max_release_age = "365d"
+dormant = "remove"
+token = "ghp_1234567890abcdef"
+
+[rss_server]
+name = "freshrss"
+url = "http://freshrss:80"
+user = "testuser"
+token = "freshrss_token_12345"
+`)
+			},
+			expectedConfig: Config{
+				RunInterval: duration(expectedRunInterval),
+				GitForges: []GitForgeConfig{
+					{
+						Type:          "github",
+						Name:          "GitHub",
+						Fqdn:          "github.com",
+						MaxReleaseAge: age(365 * 24 * time.Hour),
+						Dormant:       "remove",
+						TokenSource:   TokenSource{Token: "ghp_1234567890abcdef"},
+					},
+				},
+				RSSServer: RSSServerConfig{
+					Name:        "freshrss",
+					URL:         "http://freshrss:80",
+					User:        "testuser",
+					TokenSource: TokenSource{Token: "freshrss_token_12345"},
+				},
+			},
+			expectErr: false,
+		},
+		{
+			name: "invalid max release age",
+			mockCfgData: func() []byte {
+				return []byte(`
+run_interval = "24h"
+
+[[git_forges]]
+type = "github"
+name = "GitHub"
+fqdn = "github.com"
+max_release_age = "0d"
+token = "ghp_1234567890abcdef"
+
+[rss_server]
+name = "freshrss"
+url = "http://freshrss:80"
+user = "testuser"
+token = "freshrss_token_12345"
+`)
+			},
+			expectErr: true,
+		},
+		{
+			name: "invalid dormant policy",
+			mockCfgData: func() []byte {
+				return []byte(`
+run_interval = "24h"
+
+[[git_forges]]
+type = "github"
+name = "GitHub"
+fqdn = "github.com"
+max_release_age = "8760h"
+dormant = "hide"
+token = "ghp_1234567890abcdef"
+
+[rss_server]
+name = "freshrss"
+url = "http://freshrss:80"
+user = "testuser"
+token = "freshrss_token_12345"
+`)
+			},
+			expectErr: true,
+		},
+		{
+			name: "valid config with repo rules",
+			mockCfgData: func() []byte {
+				return []byte(`
+run_interval = "24h"
+
+[[git_forges]]
+type = "github"
+name = "GitHub"
+fqdn = "github.com"
+token = "ghp_1234567890abcdef"
+
+[[git_forges.rules]]
+action = "exclude"
+owner = "my-org-*"
+name_regex = "^awesome-"
+fork = true
+
+[[git_forges.rules]]
+action = "include"
+language = "Go"
+topics = ["cli", "rss"]
+archived = false
+min_stars = 100
+
+[rss_server]
+name = "freshrss"
+url = "http://freshrss:80"
+user = "testuser"
+token = "freshrss_token_12345"
+`)
+			},
+			expectedConfig: Config{
+				RunInterval: duration(expectedRunInterval),
+				GitForges: []GitForgeConfig{
+					{
+						Type: "github",
+						Name: "GitHub",
+						Fqdn: "github.com",
+						Rules: []RepoRuleConfig{
+							{
+								Action:    "exclude",
+								Owner:     "my-org-*",
+								NameRegex: "^awesome-",
+								Fork:      new(true),
+							},
+							{
+								Action:   "include",
+								Language: "Go",
+								Topics:   []string{"cli", "rss"},
+								Archived: new(false),
+								MinStars: 100,
+							},
+						},
+						TokenSource: TokenSource{Token: "ghp_1234567890abcdef"},
+					},
+				},
+				RSSServer: RSSServerConfig{
+					Name:        "freshrss",
+					URL:         "http://freshrss:80",
+					User:        "testuser",
+					TokenSource: TokenSource{Token: "freshrss_token_12345"},
+				},
+			},
+			expectErr: false,
+		},
+		{
+			name: "invalid repo rule action",
+			mockCfgData: func() []byte {
+				return []byte(`
+run_interval = "24h"
+
+[[git_forges]]
+type = "github"
+name = "GitHub"
+fqdn = "github.com"
+token = "ghp_1234567890abcdef"
+
+[[git_forges.rules]]
+action = "ignore"
+
+[rss_server]
+name = "freshrss"
+url = "http://freshrss:80"
+user = "testuser"
+token = "freshrss_token_12345"
+`)
+			},
+			expectErr: true,
+		},
+		{
+			name: "invalid repo rule glob",
+			mockCfgData: func() []byte {
+				return []byte(`
+run_interval = "24h"
+
+[[git_forges]]
+type = "github"
+name = "GitHub"
+fqdn = "github.com"
+token = "ghp_1234567890abcdef"
+
+[[git_forges.rules]]
+action = "exclude"
+owner = "[my-org"
+
+[rss_server]
+name = "freshrss"
+url = "http://freshrss:80"
+user = "testuser"
+token = "freshrss_token_12345"
+`)
+			},
+			expectErr: true,
+		},
+		{
+			name: "invalid repo rule regex",
+			mockCfgData: func() []byte {
+				return []byte(`
+run_interval = "24h"
+
+[[git_forges]]
+type = "github"
+name = "GitHub"
+fqdn = "github.com"
+token = "ghp_1234567890abcdef"
+
+[[git_forges.rules]]
+action = "exclude"
+name_regex = "(awesome"
+
+[rss_server]
+name = "freshrss"
+url = "http://freshrss:80"
+user = "testuser"
+token = "freshrss_token_12345"
+`)
+			},
+			expectErr: true,
+		},
+		{
+			name: "negative repo rule min stars",
+			mockCfgData: func() []byte {
+				return []byte(`
+run_interval = "24h"
+
+[[git_forges]]
+type = "github"
+name = "GitHub"
+fqdn = "github.com"
+token = "ghp_1234567890abcdef"
+
+[[git_forges.rules]]
+action = "include"
+min_stars = -1
+
+[rss_server]
+name = "freshrss"
+url = "http://freshrss:80"
+user = "testuser"
+token = "freshrss_token_12345"
+`)
+			},
+			expectErr: true,
+		},
+		{
+			name: "valid config with star lists",
+			mockCfgData: func() []byte {
+				return []byte(`
+run_interval = "24h"
+
+[[git_forges]]
+type = "github"
+name = "GitHub"
+fqdn = "github.com"
+star_lists = true
+star_list_prefix = true
+token = "ghp_1234567890abcdef"
+
+[rss_server]
+name = "freshrss"
+url = "http://freshrss:80"
+user = "testuser"
+token = "freshrss_token_12345"
+`)
+			},
+			expectedConfig: Config{
+				RunInterval: duration(expectedRunInterval),
+				GitForges: []GitForgeConfig{
+					{
+						Type:           "github",
+						Name:           "GitHub",
+						Fqdn:           "github.com",
+						StarLists:      true,
+						StarListPrefix: true,
+						TokenSource:    TokenSource{Token: "ghp_1234567890abcdef"},
+					},
+				},
+				RSSServer: RSSServerConfig{
+					Name:        "freshrss",
+					URL:         "http://freshrss:80",
+					User:        "testuser",
+					TokenSource: TokenSource{Token: "freshrss_token_12345"},
+				},
+			},
+			expectErr: false,
+		},
+		{
+			name: "star lists on a forge other than github",
+			mockCfgData: func() []byte {
+				return []byte(`
+run_interval = "24h"
+
+[[git_forges]]
+type = "forgejo"
+name = "Codeberg"
+fqdn = "codeberg.org"
+star_lists = true
+token = "codeberg_token"
+
+[rss_server]
+name = "freshrss"
+url = "http://freshrss:80"
+user = "testuser"
+token = "freshrss_token_12345"
+`)
+			},
+			expectErr: true,
+		},
+		{
+			name: "unprefixed star lists without a state_dir",
+			mockCfgData: func() []byte {
+				return []byte(`
+run_interval = "24h"
+
+[[git_forges]]
+type = "github"
+name = "GitHub"
+fqdn = "github.com"
+star_lists = true
+token = "ghp_1234567890abcdef"
+
+[rss_server]
+name = "freshrss"
+url = "http://freshrss:80"
+user = "testuser"
+token = "freshrss_token_12345"
+`)
+			},
+			expectErr: true,
+		},
+		{
+			name: "unprefixed star lists with another forge",
+			mockCfgData: func() []byte {
+				return []byte(`
+run_interval = "24h"
+state_dir = "/var/lib/starfeed"
+
+[[git_forges]]
+type = "github"
+name = "GitHub"
+fqdn = "github.com"
+star_lists = true
+token = "ghp_1234567890abcdef"
+
+[[git_forges]]
+type = "forgejo"
+name = "Codeberg"
+fqdn = "codeberg.org"
+token = "codeberg_token"
+
+[rss_server]
+name = "freshrss"
+url = "http://freshrss:80"
+user = "testuser"
+token = "freshrss_token_12345"
+`)
+			},
+			expectErr: true,
+		},
+		{
+			name: "valid config with usernames",
+			mockCfgData: func() []byte {
+				return []byte(`
+run_interval = "24h"
+
+[[git_forges]]
+type = "github"
+name = "GitHub"
+fqdn = "github.com"
+usernames = ["team-picks", "alice"]
+token = "ghp_1234567890abcdef"
+
+[rss_server]
+name = "freshrss"
+url = "http://freshrss:80"
+user = "testuser"
+token = "freshrss_token_12345"
+`)
+			},
+			expectedConfig: Config{
+				RunInterval: duration(expectedRunInterval),
+				GitForges: []GitForgeConfig{
+					{
+						Type:        "github",
+						Name:        "GitHub",
+						Fqdn:        "github.com",
+						Usernames:   []string{"team-picks", "alice"},
+						TokenSource: TokenSource{Token: "ghp_1234567890abcdef"},
+					},
+				},
+				RSSServer: RSSServerConfig{
+					Name:        "freshrss",
+					URL:         "http://freshrss:80",
+					User:        "testuser",
+					TokenSource: TokenSource{Token: "freshrss_token_12345"},
+				},
+			},
+			expectErr: false,
+		},
+		{
+			name: "both username and usernames",
+			mockCfgData: func() []byte {
+				return []byte(`
+run_interval = "24h"
+
+[[git_forges]]
+type = "github"
+name = "GitHub"
+fqdn = "github.com"
+username = "alice"
+usernames = ["bob"]
+token = "ghp_1234567890abcdef"
+
+[rss_server]
+name = "freshrss"
+url = "http://freshrss:80"
+user = "testuser"
+token = "freshrss_token_12345"
+`)
+			},
+			expectErr: true,
+		},
+		{
+			name: "empty username in usernames",
+			mockCfgData: func() []byte {
+				return []byte(`
+run_interval = "24h"
+
+[[git_forges]]
+type = "github"
+name = "GitHub"
+fqdn = "github.com"
+usernames = ["alice", ""]
+token = "ghp_1234567890abcdef"
+
+[rss_server]
+name = "freshrss"
+url = "http://freshrss:80"
+user = "testuser"
+token = "freshrss_token_12345"
+`)
+			},
+			expectErr: true,
+		},
+		{
+			name: "valid config with watched repos",
+			mockCfgData: func() []byte {
+				return []byte(`
+run_interval = "24h"
+
+[[git_forges]]
+type = "forgejo"
+name = "Codeberg"
+fqdn = "codeberg.org"
+sources = ["starred", "watched"]
+token = "ghp_1234567890abcdef"
+
+[rss_server]
+name = "freshrss"
+url = "http://freshrss:80"
+user = "testuser"
+token = "freshrss_token_12345"
+`)
+			},
+			expectedConfig: Config{
+				RunInterval: duration(expectedRunInterval),
+				GitForges: []GitForgeConfig{
+					{
+						Type:        "forgejo",
+						Name:        "Codeberg",
+						Fqdn:        "codeberg.org",
+						Sources:     []string{"starred", "watched"},
+						TokenSource: TokenSource{Token: "ghp_1234567890abcdef"},
+					},
+				},
+				RSSServer: RSSServerConfig{
+					Name:        "freshrss",
+					URL:         "http://freshrss:80",
+					User:        "testuser",
+					TokenSource: TokenSource{Token: "freshrss_token_12345"},
+				},
+			},
+			expectErr: false,
+		},
+		{
+			name: "invalid source",
+			mockCfgData: func() []byte {
+				return []byte(`
+run_interval = "24h"
+
+[[git_forges]]
+type = "github"
+name = "GitHub"
+fqdn = "github.com"
+sources = ["forked"]
+token = "ghp_1234567890abcdef"
+
+[rss_server]
+name = "freshrss"
+url = "http://freshrss:80"
+user = "testuser"
+token = "freshrss_token_12345"
+`)
+			},
+			expectErr: true,
+		},
+		{
+			name: "watched repos on gitlab",
+			mockCfgData: func() []byte {
+				return []byte(`
+run_interval = "24h"
+
+[[git_forges]]
+type = "gitlab"
+name = "GitLab"
+fqdn = "gitlab.com"
+sources = ["watched"]
+token = "ghp_1234567890abcdef"
+
+[rss_server]
+name = "freshrss"
+url = "http://freshrss:80"
+user = "testuser"
+token = "freshrss_token_12345"
+`)
+			},
+			expectErr: true,
+		},
+		{
+			name: "valid config with github app",
+			mockCfgData: func() []byte {
+				return []byte(`
+run_interval = "24h"
+
+[[git_forges]]
+type = "github"
+name = "GitHub"
+fqdn = "github.com"
+usernames = ["team-picks"]
+
+[git_forges.github_app]
+app_id = 12
+installation_id = 99
+private_key_file = "/run/secrets/starfeed.pem"
+
+[rss_server]
+name = "freshrss"
+url = "http://freshrss:80"
+user = "testuser"
+token = "freshrss_token_12345"
+`)
+			},
+			expectedConfig: Config{
+				RunInterval: duration(expectedRunInterval),
+				GitForges: []GitForgeConfig{
+					{
+						Type:      "github",
+						Name:      "GitHub",
+						Fqdn:      "github.com",
+						Usernames: []string{"team-picks"},
+						GitHubApp: &GitHubAppConfig{
+							AppID:          12,
+							InstallationID: 99,
+							PrivateKeyFile: "/run/secrets/starfeed.pem",
+						},
+					},
+				},
+				RSSServer: RSSServerConfig{
+					Name:        "freshrss",
+					URL:         "http://freshrss:80",
+					User:        "testuser",
+					TokenSource: TokenSource{Token: "freshrss_token_12345"},
+				},
+			},
+			expectErr: false,
+		},
+		{
+			name: "github app and a token",
+			mockCfgData: func() []byte {
+				return []byte(`
+run_interval = "24h"
+
+[[git_forges]]
+type = "github"
+name = "GitHub"
+fqdn = "github.com"
+username = "team-picks"
+token = "ghp_1234567890abcdef"
+
+[git_forges.github_app]
+app_id = 12
+installation_id = 99
+private_key_file = "/run/secrets/starfeed.pem"
+
+[rss_server]
+name = "freshrss"
+url = "http://freshrss:80"
+user = "testuser"
+token = "freshrss_token_12345"
+`)
+			},
+			expectErr: true,
+		},
+		{
+			name: "github app without usernames",
+			mockCfgData: func() []byte {
+				return []byte(`
+run_interval = "24h"
+
+[[git_forges]]
+type = "github"
+name = "GitHub"
+fqdn = "github.com"
+
+[git_forges.github_app]
+app_id = 12
+installation_id = 99
+private_key_file = "/run/secrets/starfeed.pem"
+
+[rss_server]
+name = "freshrss"
+url = "http://freshrss:80"
+user = "testuser"
+token = "freshrss_token_12345"
+`)
+			},
+			expectErr: true,
+		},
+		{
+			name: "github app on forgejo",
+			mockCfgData: func() []byte {
+				return []byte(`
+run_interval = "24h"
+
+[[git_forges]]
+type = "forgejo"
+name = "Codeberg"
+fqdn = "codeberg.org"
+username = "team-picks"
+
+[git_forges.github_app]
+app_id = 12
+installation_id = 99
+private_key_file = "/run/secrets/starfeed.pem"
+
+[rss_server]
+name = "freshrss"
+url = "http://freshrss:80"
+user = "testuser"
+token = "freshrss_token_12345"
+`)
+			},
+			expectErr: true,
+		},
+		{
+			name: "github app without private key file",
+			mockCfgData: func() []byte {
+				return []byte(`
+run_interval = "24h"
+
+[[git_forges]]
+type = "github"
+name = "GitHub"
+fqdn = "github.com"
+username = "team-picks"
+
+[git_forges.github_app]
+app_id = 12
+installation_id = 99
+
+[rss_server]
+name = "freshrss"
+url = "http://freshrss:80"
+user = "testuser"
+token = "freshrss_token_12345"
+`)
+			},
+			expectErr: true,
+		},
+		{
+			name: "valid config with oauth client id",
+			mockCfgData: func() []byte {
+				return []byte(`
+run_interval = "24h"
+
+[[git_forges]]
+type = "github"
+name = "GitHub"
+fqdn = "github.com"
+oauth_client_id = "0123456789abcdef"
+token_file = "/var/lib/starfeed/github.token"
+
+[rss_server]
+name = "freshrss"
+url = "http://freshrss:80"
+user = "testuser"
+token = "freshrss_token_12345"
+`)
+			},
+			expectedConfig: Config{
+				RunInterval: duration(expectedRunInterval),
+				GitForges: []GitForgeConfig{
+					{
+						Type:          "github",
+						Name:          "GitHub",
+						Fqdn:          "github.com",
+						OAuthClientID: "0123456789abcdef",
+						TokenSource: TokenSource{
+							TokenFile: "/var/lib/starfeed/github.token",
+						},
+					},
+				},
+				RSSServer: RSSServerConfig{
+					Name:        "freshrss",
+					URL:         "http://freshrss:80",
+					User:        "testuser",
+					TokenSource: TokenSource{Token: "freshrss_token_12345"},
+				},
+			},
+			expectErr: false,
+		},
+		{
+			name: "oauth client id on gitlab",
+			mockCfgData: func() []byte {
+				return []byte(`
+run_interval = "24h"
+
+[[git_forges]]
+type = "gitlab"
+name = "GitLab"
+fqdn = "gitlab.com"
+oauth_client_id = "0123456789abcdef"
+token_file = "/var/lib/starfeed/gitlab.token"
+
+[rss_server]
+name = "freshrss"
+url = "http://freshrss:80"
+user = "testuser"
+token = "freshrss_token_12345"
+`)
+			},
+			expectErr: true,
+		},
+		{
+			name: "oauth client id on forgejo",
+			mockCfgData: func() []byte {
+				return []byte(`
+run_interval = "24h"
+
+[[git_forges]]
+type = "forgejo"
+name = "Codeberg"
+fqdn = "codeberg.org"
+oauth_client_id = "0123456789abcdef"
+token_file = "/var/lib/starfeed/codeberg.token"
+
+[rss_server]
+name = "freshrss"
+url = "http://freshrss:80"
+user = "testuser"
+token = "freshrss_token_12345"
+`)
+			},
+			expectErr: true,
+		},
+		{
+			name: "valid config with private repos proxied",
+			mockCfgData: func() []byte {
+				return []byte(`
+run_interval = "24h"
+
+[feed_proxy]
+listen = ":8080"
+url = "http://starfeed:8080"
+token_env = "STARFEED_PROXY_SECRET"
+
+[[git_forges]]
+type = "github"
+name = "GitHub"
+fqdn = "github.com"
+private = "proxy"
+token = "ghp_1234567890abcdef"
+
+[rss_server]
+name = "freshrss"
+url = "http://freshrss:80"
+user = "testuser"
+token = "freshrss_token_12345"
+`)
+			},
+			expectedConfig: Config{
+				RunInterval: duration(expectedRunInterval),
+				FeedProxy: &FeedProxyConfig{
+					Listen:      ":8080",
+					URL:         "http://starfeed:8080",
+					TokenSource: TokenSource{TokenEnv: "STARFEED_PROXY_SECRET"},
+				},
+				GitForges: []GitForgeConfig{
+					{
+						Type:        "github",
+						Name:        "GitHub",
+						Fqdn:        "github.com",
+						Private:     "proxy",
+						TokenSource: TokenSource{Token: "ghp_1234567890abcdef"},
+					},
+				},
+				RSSServer: RSSServerConfig{
+					Name:        "freshrss",
+					URL:         "http://freshrss:80",
+					User:        "testuser",
+					TokenSource: TokenSource{Token: "freshrss_token_12345"},
+				},
+			},
+			expectErr: false,
+		},
+		{
+			name: "private repos proxied without a feed proxy",
+			mockCfgData: func() []byte {
+				return []byte(`
+run_interval = "24h"
+
+[[git_forges]]
+type = "github"
+name = "GitHub"
+fqdn = "github.com"
+private = "proxy"
+token = "ghp_1234567890abcdef"
+
+[rss_server]
+name = "freshrss"
+url = "http://freshrss:80"
+user = "testuser"
+token = "freshrss_token_12345"
+`)
+			},
+			expectErr: true,
+		},
+		{
+			name: "invalid private policy",
+			mockCfgData: func() []byte {
+				return []byte(`
+run_interval = "24h"
+
+[[git_forges]]
+type = "github"
+name = "GitHub"
+fqdn = "github.com"
+private = "subscribe"
+token = "ghp_1234567890abcdef"
+
+[rss_server]
+name = "freshrss"
+url = "http://freshrss:80"
+user = "testuser"
+token = "freshrss_token_12345"
+`)
+			},
+			expectErr: true,
+		},
+		{
+			name: "feed proxy without a secret",
+			mockCfgData: func() []byte {
+				return []byte(`
+run_interval = "24h"
+
+[feed_proxy]
+listen = ":8080"
+url = "http://starfeed:8080"
+
+[[git_forges]]
+type = "github"
+name = "GitHub"
+fqdn = "github.com"
+private = "proxy"
+token = "ghp_1234567890abcdef"
+
+[rss_server]
+name = "freshrss"
+url = "http://freshrss:80"
+user = "testuser"
+token = "freshrss_token_12345"
+`)
+			},
+			expectErr: true,
+		},
+		{
+			name: "feed proxy without a url",
+			mockCfgData: func() []byte {
+				return []byte(`
+run_interval = "24h"
+
+[feed_proxy]
+listen = ":8080"
+token_env = "STARFEED_PROXY_SECRET"
+
+[[git_forges]]
+type = "github"
+name = "GitHub"
+fqdn = "github.com"
+private = "proxy"
+token = "ghp_1234567890abcdef"
+
+[rss_server]
+name = "freshrss"
+url = "http://freshrss:80"
+user = "testuser"
+token = "freshrss_token_12345"
+`)
+			},
+			expectErr: true,
+		},
+		{
+			name: "valid config with fork parents",
+			mockCfgData: func() []byte {
+				return []byte(`
+run_interval = "24h"
+
+[[git_forges]]
+type = "github"
+name = "GitHub"
+fqdn = "github.com"
+forks = "parent"
+token = "ghp_1234567890abcdef"
+
+[rss_server]
+name = "freshrss"
+url = "http://freshrss:80"
+user = "testuser"
+token = "freshrss_token_12345"
+`)
+			},
+			expectedConfig: Config{
+				RunInterval: duration(expectedRunInterval),
+				GitForges: []GitForgeConfig{
+					{
+						Type:        "github",
+						Name:        "GitHub",
+						Fqdn:        "github.com",
+						Forks:       "parent",
+						TokenSource: TokenSource{Token: "ghp_1234567890abcdef"},
+					},
+				},
+				RSSServer: RSSServerConfig{
+					Name:        "freshrss",
+					URL:         "http://freshrss:80",
+					User:        "testuser",
+					TokenSource: TokenSource{Token: "freshrss_token_12345"},
+				},
+			},
+			expectErr: false,
+		},
+		{
+			name: "invalid fork policy",
+			mockCfgData: func() []byte {
+				return []byte(`
+run_interval = "24h"
+
+[[git_forges]]
+type = "github"
+name = "GitHub"
+fqdn = "github.com"
+forks = "upstream"
+token = "ghp_1234567890abcdef"
+
+[rss_server]
+name = "freshrss"
+url = "http://freshrss:80"
+user = "testuser"
+token = "freshrss_token_12345"
+`)
+			},
+			expectErr: true,
+		},
+		{
+			name: "valid config with mirror origins",
+			mockCfgData: func() []byte {
+				return []byte(`
+run_interval = "24h"
+
+[[git_forges]]
+type = "forgejo"
+name = "Forge"
+fqdn = "codeberg.org"
+mirrors = "origin"
+token = "forgejo_token_123456"
+
+[rss_server]
+name = "freshrss"
+url = "http://freshrss:80"
+user = "testuser"
+token = "freshrss_token_12345"
+`)
+			},
+			expectedConfig: Config{
+				RunInterval: duration(expectedRunInterval),
+				GitForges: []GitForgeConfig{
+					{
+						Type:        "forgejo",
+						Name:        "Forge",
+						Fqdn:        "codeberg.org",
+						Mirrors:     "origin",
+						TokenSource: TokenSource{Token: "forgejo_token_123456"},
+					},
+				},
+				RSSServer: RSSServerConfig{
+					Name:        "freshrss",
+					URL:         "http://freshrss:80",
+					User:        "testuser",
+					TokenSource: TokenSource{Token: "freshrss_token_12345"},
+				},
+			},
+			expectErr: false,
+		},
+		{
+			name: "invalid mirror policy",
+			mockCfgData: func() []byte {
+				return []byte(`
+run_interval = "24h"
+
+[[git_forges]]
+type = "forgejo"
+name = "Forge"
+fqdn = "codeberg.org"
+mirrors = "upstream"
+token = "forgejo_token_123456"
+
+[rss_server]
+name = "freshrss"
+url = "http://freshrss:80"
+user = "testuser"
+token = "freshrss_token_12345"
+`)
+			},
+			expectErr: true,
+		},
+		{
+			name: "mirrors on a forge other than forgejo",
+			mockCfgData: func() []byte {
+				return []byte(`
+run_interval = "24h"
+
+[[git_forges]]
+type = "github"
+name = "Forge"
+fqdn = "github.com"
+mirrors = "origin"
+token = "ghp_1234567890abcdef"
+
+[rss_server]
+name = "freshrss"
+url = "http://freshrss:80"
+user = "testuser"
+token = "freshrss_token_12345"
+`)
+			},
+			expectErr: true,
+		},
+		{
+			name: "valid config with miniflux",
+			mockCfgData: func() []byte {
+				return []byte(`
+run_interval = "24h"
+
+[[git_forges]]
+type = "github"
+name = "GitHub"
+fqdn = "github.com"
+token = "ghp_1234567890abcdef"
+
+[rss_server]
+name = "miniflux"
+url = "http://miniflux:8080"
+token = "miniflux_api_key_12345"
+`)
+			},
+			expectedConfig: Config{
+				RunInterval: duration(expectedRunInterval),
+				GitForges: []GitForgeConfig{
+					{
+						Type:        "github",
+						Name:        "GitHub",
+						Fqdn:        "github.com",
+						TokenSource: TokenSource{Token: "ghp_1234567890abcdef"},
+					},
+				},
+				RSSServer: RSSServerConfig{
+					Name:        "miniflux",
+					URL:         "http://miniflux:8080",
+					TokenSource: TokenSource{Token: "miniflux_api_key_12345"},
+				},
+			},
+			expectErr: false,
+		},
+		{
+			name: "valid config with nextcloud news",
+			mockCfgData: func() []byte {
+				return []byte(`
+run_interval = "24h"
+
+[[git_forges]]
+type = "github"
+name = "GitHub"
+fqdn = "github.com"
+token = "ghp_1234567890abcdef"
+
+[rss_server]
+name = "nextcloud_news"
+url = "https://cloud.example.com"
+user = "testuser"
+token = "nextcloud_app_password"
+`)
+			},
+			expectedConfig: Config{
+				RunInterval: duration(expectedRunInterval),
+				GitForges: []GitForgeConfig{
+					{
+						Type:        "github",
+						Name:        "GitHub",
+						Fqdn:        "github.com",
+						TokenSource: TokenSource{Token: "ghp_1234567890abcdef"},
+					},
+				},
+				RSSServer: RSSServerConfig{
+					Name:        "nextcloud_news",
+					URL:         "https://cloud.example.com",
+					User:        "testuser",
+					TokenSource: TokenSource{Token: "nextcloud_app_password"},
+				},
+			},
+			expectErr: false,
+		},
+		{
+			name: "nextcloud news without user",
+			mockCfgData: func() []byte {
+				return []byte(`
+run_interval = "24h"
+
+[[git_forges]]
+type = "github"
+name = "GitHub"
+fqdn = "github.com"
+token = "ghp_1234567890abcdef"
+
+[rss_server]
+name = "nextcloud_news"
+url = "https://cloud.example.com"
+token = "nextcloud_app_password"
+`)
+			},
+			expectErr: true,
+		},
+		{
+			name: "valid config with tt-rss",
+			mockCfgData: func() []byte {
+				return []byte(`
+run_interval = "24h"
+
+[[git_forges]]
+type = "github"
+name = "GitHub"
+fqdn = "github.com"
+token = "ghp_1234567890abcdef"
+
+[rss_server]
+name = "ttrss"
+url = "https://ttrss.example.com/tt-rss"
+user = "testuser"
+token = "ttrss_password_12345"
+`)
+			},
+			expectedConfig: Config{
+				RunInterval: duration(expectedRunInterval),
+				GitForges: []GitForgeConfig{
+					{
+						Type:        "github",
+						Name:        "GitHub",
+						Fqdn:        "github.com",
+						TokenSource: TokenSource{Token: "ghp_1234567890abcdef"},
+					},
+				},
+				RSSServer: RSSServerConfig{
+					Name:        "ttrss",
+					URL:         "https://ttrss.example.com/tt-rss",
+					User:        "testuser",
+					TokenSource: TokenSource{Token: "ttrss_password_12345"},
+				},
+			},
+			expectErr: false,
+		},
+		{
+			name: "valid config with a greader server",
+			mockCfgData: func() []byte {
+				return []byte(`
+run_interval = "24h"
+
+[[git_forges]]
+type = "github"
+name = "GitHub"
+fqdn = "github.com"
+token = "ghp_1234567890abcdef"
+
+[rss_server]
+name = "greader"
+url = "https://reader.example.com"
+user = "testuser"
+token = "greader_password_12345"
+
+[rss_server.greader]
+base_path = "/api/greader"
+label_prefix = "user/-/label/"
+`)
+			},
+			expectedConfig: Config{
+				RunInterval: duration(expectedRunInterval),
+				GitForges: []GitForgeConfig{
+					{
+						Type:        "github",
+						Name:        "GitHub",
+						Fqdn:        "github.com",
+						TokenSource: TokenSource{Token: "ghp_1234567890abcdef"},
+					},
+				},
+				RSSServer: RSSServerConfig{
+					Name: "greader",
+					URL:  "https://reader.example.com",
+					User: "testuser",
+					GReader: &GReaderConfig{
+						BasePath:    "/api/greader",
+						LabelPrefix: "user/-/label/",
+					},
+					TokenSource: TokenSource{Token: "greader_password_12345"},
+				},
+			},
+			expectErr: false,
+		},
+		{
+			name: "greader options on another rss server",
+			mockCfgData: func() []byte {
+				return []byte(`
+run_interval = "24h"
+
+[[git_forges]]
+type = "github"
+name = "GitHub"
+fqdn = "github.com"
+token = "ghp_1234567890abcdef"
+
+[rss_server]
+name = "freshrss"
+url = "http://freshrss:80"
+user = "testuser"
+token = "freshrss_token_12345"
+
+[rss_server.greader]
+base_path = "/api/greader"
+`)
+			},
+			expectErr: true,
+		},
+		{
+			name: "greader base path without a leading slash",
+			mockCfgData: func() []byte {
+				return []byte(`
+run_interval = "24h"
+
+[[git_forges]]
+type = "github"
+name = "GitHub"
+fqdn = "github.com"
+token = "ghp_1234567890abcdef"
+
+[rss_server]
+name = "greader"
+url = "https://reader.example.com"
+user = "testuser"
+token = "greader_password_12345"
+
+[rss_server.greader]
+base_path = "api/greader"
+`)
+			},
+			expectErr: true,
+		},
+		{
+			name: "valid config with an opml file",
+			mockCfgData: func() []byte {
+				return []byte(`
+run_interval = "24h"
+
+[[git_forges]]
+type = "github"
+name = "GitHub"
+fqdn = "github.com"
+token = "ghp_1234567890abcdef"
+
+[rss_server]
+name = "opml"
+path = "/data/starfeed.opml"
+`)
+			},
+			expectedConfig: Config{
+				RunInterval: duration(expectedRunInterval),
+				GitForges: []GitForgeConfig{
+					{
+						Type:        "github",
+						Name:        "GitHub",
+						Fqdn:        "github.com",
+						TokenSource: TokenSource{Token: "ghp_1234567890abcdef"},
+					},
+				},
+				RSSServer: RSSServerConfig{
+					Name: "opml",
+					Path: "/data/starfeed.opml",
+				},
+			},
+			expectErr: false,
+		},
+		{
+			name: "opml without a path",
+			mockCfgData: func() []byte {
+				return []byte(`
+run_interval = "24h"
+
+[[git_forges]]
+type = "github"
+name = "GitHub"
+fqdn = "github.com"
+token = "ghp_1234567890abcdef"
+
+[rss_server]
+name = "opml"
+`)
+			},
+			expectErr: true,
+		},
+		{
+			name: "path on another rss server",
+			mockCfgData: func() []byte {
+				return []byte(`
+run_interval = "24h"
+
+[[git_forges]]
+type = "github"
+name = "GitHub"
+fqdn = "github.com"
+token = "ghp_1234567890abcdef"
+
+[rss_server]
+name = "freshrss"
+url = "http://freshrss:80"
+user = "testuser"
+token = "freshrss_token_12345"
+path = "/data/starfeed.opml"
+`)
+			},
+			expectErr: true,
+		},
+		{
+			name: "freshrss without user",
+			mockCfgData: func() []byte {
+				return []byte(`
+run_interval = "24h"
+
+[[git_forges]]
+type = "github"
+name = "GitHub"
+fqdn = "github.com"
+token = "ghp_1234567890abcdef"
+
+[rss_server]
+name = "freshrss"
+url = "http://freshrss:80"
+token = "freshrss_token_12345"
+`)
+			},
+			expectErr: true,
+		},
+		{
+			name: "rss server without token",
+			mockCfgData: func() []byte {
+				return []byte(`
+run_interval = "24h"
+
+[[git_forges]]
+type = "github"
+name = "GitHub"
+fqdn = "github.com"
+token = "ghp_1234567890abcdef"
+
+[rss_server]
+name = "freshrss"
+url = "http://freshrss:80"
+user = "testuser"
+`)
+			},
+			expectErr: true,
+		},
 		{
 			name: "missing git_forges",
 			mockCfgData: func() []byte {
@@ -201,10 +2178,10 @@ single_run = true
 run_interval = "24h"
 
 [[git_forges]]
-type = "gitlab"
-name = "GitLab"
-fqdn = "gitlab.com"
-token = "gitlab_token_123456"
+type = "bitbucket"
+name = "Bitbucket"
+fqdn = "bitbucket.org"
+token = "bitbucket_token_123456"
 
 [rss_server]
 name = "freshrss"
@@ -299,7 +2276,7 @@ fqdn = "github.com"
 token = "ghp_1234567890abcdef"
 
 [rss_server]
-name = "miniflux"
+name = "newsblur"
 url = "http://freshrss:80"
 user = "testuser"
 token = "freshrss_token_12345"
@@ -461,17 +2438,17 @@ token = "freshrss_token_12345"
 				RunInterval: duration(90 * time.Minute),
 				GitForges: []GitForgeConfig{
 					{
-						Type:  "github",
-						Name:  "GitHub",
-						Fqdn:  "github.com",
-						Token: "ghp_1234567890abcdef",
+						Type:        "github",
+						Name:        "GitHub",
+						Fqdn:        "github.com",
+						TokenSource: TokenSource{Token: "ghp_1234567890abcdef"},
 					},
 				},
 				RSSServer: RSSServerConfig{
-					Name:  "freshrss",
-					URL:   "http://freshrss:80",
-					User:  "testuser",
-					Token: "freshrss_token_12345",
+					Name:        "freshrss",
+					URL:         "http://freshrss:80",
+					User:        "testuser",
+					TokenSource: TokenSource{Token: "freshrss_token_12345"},
 				},
 			},
 			expectErr: false,
@@ -503,17 +2480,17 @@ token = "freshrss_token_12345"
 				RunInterval: duration(48 * time.Hour),
 				GitForges: []GitForgeConfig{
 					{
-						Type:  "github",
-						Name:  "GitHub",
-						Fqdn:  "github.com",
-						Token: "ghp_1234567890abcdef",
+						Type:        "github",
+						Name:        "GitHub",
+						Fqdn:        "github.com",
+						TokenSource: TokenSource{Token: "ghp_1234567890abcdef"},
 					},
 				},
 				RSSServer: RSSServerConfig{
-					Name:  "freshrss",
-					URL:   "http://freshrss:80",
-					User:  "testuser",
-					Token: "freshrss_token_12345",
+					Name:        "freshrss",
+					URL:         "http://freshrss:80",
+					User:        "testuser",
+					TokenSource: TokenSource{Token: "freshrss_token_12345"},
 				},
 			},
 			expectErr: false,
@@ -637,17 +2614,17 @@ token = "freshrss_token_12345"
 				RunInterval: duration(1 * time.Hour),
 				GitForges: []GitForgeConfig{
 					{
-						Type:  "github",
-						Name:  "GitHub",
-						Fqdn:  "github.com",
-						Token: "ghp_1234567890abcdef",
+						Type:        "github",
+						Name:        "GitHub",
+						Fqdn:        "github.com",
+						TokenSource: TokenSource{Token: "ghp_1234567890abcdef"},
 					},
 				},
 				RSSServer: RSSServerConfig{
-					Name:  "freshrss",
-					URL:   "http://freshrss:80",
-					User:  "testuser",
-					Token: "freshrss_token_12345",
+					Name:        "freshrss",
+					URL:         "http://freshrss:80",
+					User:        "testuser",
+					TokenSource: TokenSource{Token: "freshrss_token_12345"},
 				},
 			},
 			expectErr: false,
@@ -679,17 +2656,17 @@ token = "freshrss_token_12345"
 				RunInterval: duration(168 * time.Hour),
 				GitForges: []GitForgeConfig{
 					{
-						Type:  "github",
-						Name:  "GitHub",
-						Fqdn:  "github.com",
-						Token: "ghp_1234567890abcdef",
+						Type:        "github",
+						Name:        "GitHub",
+						Fqdn:        "github.com",
+						TokenSource: TokenSource{Token: "ghp_1234567890abcdef"},
 					},
 				},
 				RSSServer: RSSServerConfig{
-					Name:  "freshrss",
-					URL:   "http://freshrss:80",
-					User:  "testuser",
-					Token: "freshrss_token_12345",
+					Name:        "freshrss",
+					URL:         "http://freshrss:80",
+					User:        "testuser",
+					TokenSource: TokenSource{Token: "freshrss_token_12345"},
 				},
 			},
 			expectErr: false,
@@ -731,3 +2708,171 @@ token = "freshrss_token_12345"
 		})
 	}
 }
+
+func TestConfig_RetryPolicy(t *testing.T) {
+	testCases := []struct {
+		name     string
+		retry    RetryConfig
+		expected common.RetryPolicy
+	}{
+		{
+			name:     "defaults when nothing is set",
+			retry:    RetryConfig{},
+			expected: common.DefaultRetryPolicy(),
+		},
+		{
+			name: "configured values override the defaults",
+			retry: RetryConfig{
+				MaxAttempts: 5,
+				BaseDelay:   delay(2 * time.Second),
+				MaxDelay:    delay(time.Minute),
+			},
+			expected: common.RetryPolicy{
+				MaxAttempts: 5,
+				BaseDelay:   2 * time.Second,
+				MaxDelay:    time.Minute,
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			cfg := Config{Retry: tc.retry}
+			if got := cfg.RetryPolicy(); got != tc.expected {
+				t.Errorf("RetryPolicy() = %+v, want %+v", got, tc.expected)
+			}
+		})
+	}
+}
+
+func TestGitForgeConfig_ArchivedCategoryName(t *testing.T) {
+	testCases := []struct {
+		name     string
+		forge    GitForgeConfig
+		expected string
+	}{
+		{
+			name:     "defaults to the forge name",
+			forge:    GitForgeConfig{Name: "GitHub"},
+			expected: "GitHub Archived",
+		},
+		{
+			name:     "configured category wins",
+			forge:    GitForgeConfig{Name: "GitHub", ArchivedCategory: "Old Stuff"},
+			expected: "Old Stuff",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			if got := tc.forge.ArchivedCategoryName(); got != tc.expected {
+				t.Errorf("ArchivedCategoryName() = %q, want %q", got, tc.expected)
+			}
+		})
+	}
+}
+
+func TestGitForgeConfig_StarredUsernames(t *testing.T) {
+	testCases := []struct {
+		name     string
+		cfg      GitForgeConfig
+		expected []string
+	}{
+		{name: "Token owner", cfg: GitForgeConfig{}, expected: nil},
+		{
+			name:     "Single username",
+			cfg:      GitForgeConfig{Username: "alice"},
+			expected: []string{"alice"},
+		},
+		{
+			name:     "Multiple usernames",
+			cfg:      GitForgeConfig{Usernames: []string{"team-picks", "alice"}},
+			expected: []string{"team-picks", "alice"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if actual := tc.cfg.StarredUsernames(); !reflect.DeepEqual(actual, tc.expected) {
+				t.Errorf("Expected %v, got %v", tc.expected, actual)
+			}
+		})
+	}
+}
+
+func TestCheckTitleTemplate(t *testing.T) {
+	testCases := []struct {
+		name      string
+		text      string
+		expectErr bool
+	}{
+		{
+			name: "Template using every field",
+			text: "{{.FullName}}: {{.Owner}}/{{.Name}} {{.Description}}",
+		},
+		{name: "Template that does not parse", text: "{{.Owner}/{{.Name}}", expectErr: true},
+		{name: "Template using an unknown field", text: "{{.Stars}} {{.Name}}", expectErr: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			err := checkTitleTemplate("title_template", tc.text)
+			if tc.expectErr && err == nil {
+				t.Fatalf("Expected error but got nil")
+			}
+			if !tc.expectErr && err != nil {
+				t.Fatalf("Expected no error but got %v", err)
+			}
+		})
+	}
+}
+
+func TestUniqueCategories(t *testing.T) {
+	testCases := []struct {
+		name     string
+		forges   []GitForgeConfig
+		expected bool
+	}{
+		{
+			name: "Forges with their own archived categories",
+			forges: []GitForgeConfig{
+				{Name: "GitHub", Archived: "move"},
+				{Name: "GHES", Archived: "move"},
+			},
+			expected: true,
+		},
+		{
+			name: "Forges sharing an archived category",
+			forges: []GitForgeConfig{
+				{Name: "GitHub", Archived: "move", ArchivedCategory: "Archived"},
+				{Name: "GHES", Archived: "move", ArchivedCategory: "Archived"},
+			},
+			expected: false,
+		},
+		{
+			name: "Archived category named after another forge",
+			forges: []GitForgeConfig{
+				{Name: "GitHub", Archived: "move", ArchivedCategory: "GHES"},
+				{Name: "GHES"},
+			},
+			expected: false,
+		},
+		{
+			name:     "Forges with the same name",
+			forges:   []GitForgeConfig{{Name: "GitHub"}, {Name: "GitHub"}},
+			expected: false,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			if actual := uniqueCategories(tc.forges); actual != tc.expected {
+				t.Errorf("uniqueCategories() = %t, want %t", actual, tc.expected)
+			}
+		})
+	}
+}
@@ -0,0 +1,78 @@
+package config
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+
+	"github.com/go-playground/validator/v10"
+)
+
+const minTokenLength = 10
+
+// TokenSource lets a secret be given inline in the config file or loaded from a file (Docker and
+// Kubernetes secrets), an environment variable or the output of a command (pass, vault, op...).
+// At most one of them may be set and the struct it is embedded in decides whether one is
+// required. Tokens are resolved at the start of every run so rotated
+// secrets are picked up without a restart.
+type TokenSource struct {
+	Token        string   `validate:"omitempty,min=10" toml:"token"` // WARNING: This is a secret
+	TokenFile    string   `                            toml:"token_file"`
+	TokenEnv     string   `                            toml:"token_env"`
+	TokenCommand []string `                            toml:"token_command"`
+}
+
+// Reads the token from whichever source was configured
+func (t TokenSource) ResolveToken(ctx context.Context) (string, error) {
+	var token string
+	switch {
+	case t.TokenFile != "":
+		data, err := os.ReadFile(t.TokenFile)
+		if err != nil {
+			return "", fmt.Errorf("could not read token file: %w", err)
+		}
+		token = strings.TrimSpace(string(data))
+	case t.TokenEnv != "":
+		token = strings.TrimSpace(os.Getenv(t.TokenEnv))
+	case len(t.TokenCommand) > 0:
+		// We don't include the output in the error as it may contain the secret
+		out, err := exec.CommandContext(ctx, t.TokenCommand[0], t.TokenCommand[1:]...).Output()
+		if err != nil {
+			return "", fmt.Errorf("token command %s failed: %w", t.TokenCommand[0], err)
+		}
+		token = strings.TrimSpace(string(out))
+	default:
+		token = t.Token
+	}
+
+	if len(token) < minTokenLength {
+		return "", fmt.Errorf("token must be at least %d characters long", minTokenLength)
+	}
+	return token, nil
+}
+
+// How many of the token sources are set
+func (t TokenSource) numSources() int {
+	numSet := 0
+	for _, set := range []bool{
+		t.Token != "",
+		t.TokenFile != "",
+		t.TokenEnv != "",
+		len(t.TokenCommand) > 0,
+	} {
+		if set {
+			numSet++
+		}
+	}
+	return numSet
+}
+
+// The validator can't express "at most one of these" with tags so we check it here
+func validateTokenSource(sl validator.StructLevel) {
+	source := sl.Current().Interface().(TokenSource)
+	if source.numSources() > 1 {
+		sl.ReportError(source.Token, "Token", "Token", "one_token_source", "")
+	}
+}
@@ -0,0 +1,82 @@
+package config
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+const mockToken = "ghp_1234567890abcdef"
+
+func TestTokenSource_ResolveToken(t *testing.T) {
+	tokenFile := filepath.Join(t.TempDir(), "token")
+	if err := os.WriteFile(tokenFile, []byte(mockToken+"\n"), 0o600); err != nil {
+		t.Fatalf("could not write token file: %v", err)
+	}
+	t.Setenv("STARFEED_TEST_TOKEN", mockToken)
+	t.Setenv("STARFEED_TEST_SHORT_TOKEN", "short")
+
+	testCases := []struct {
+		name      string
+		source    TokenSource
+		expectErr bool
+	}{
+		{
+			name:   "inline token",
+			source: TokenSource{Token: mockToken},
+		},
+		{
+			name:   "token file with trailing newline",
+			source: TokenSource{TokenFile: tokenFile},
+		},
+		{
+			name:      "missing token file",
+			source:    TokenSource{TokenFile: filepath.Join(t.TempDir(), "missing")},
+			expectErr: true,
+		},
+		{
+			name:   "token env",
+			source: TokenSource{TokenEnv: "STARFEED_TEST_TOKEN"},
+		},
+		{
+			name:      "unset token env",
+			source:    TokenSource{TokenEnv: "STARFEED_TEST_UNSET_TOKEN"},
+			expectErr: true,
+		},
+		{
+			name:      "token env too short",
+			source:    TokenSource{TokenEnv: "STARFEED_TEST_SHORT_TOKEN"},
+			expectErr: true,
+		},
+		{
+			name:   "token command",
+			source: TokenSource{TokenCommand: []string{"echo", mockToken}},
+		},
+		{
+			name:      "failing token command",
+			source:    TokenSource{TokenCommand: []string{"false"}},
+			expectErr: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			token, err := tc.source.ResolveToken(context.Background())
+
+			if tc.expectErr {
+				if err == nil {
+					t.Fatalf("Expected error but got nil")
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("Expected no error but got %v", err)
+			}
+			if token != mockToken {
+				t.Fatalf("Expected token %q but got %q", mockToken, token)
+			}
+		})
+	}
+}
@@ -3,6 +3,8 @@ package config
 import (
 	"fmt"
 	"os"
+	"strconv"
+	"strings"
 	"time"
 )
 
@@ -11,6 +13,7 @@ const (
 	defaultConfigPath = "./starfeed.toml"
 	minDuration       = 1 * time.Hour
 	maxDuration       = 24 * 7 * time.Hour
+	maxDelay          = 10 * time.Minute
 )
 
 // We can use this internal custom type to enable easy unmarshalling by go-toml
@@ -31,6 +34,46 @@ func (d *duration) UnmarshalText(text []byte) error {
 	return nil
 }
 
+// The delays between retries are much shorter than the run interval so they get their own type
+type delay time.Duration
+
+func (d *delay) UnmarshalText(text []byte) error {
+	parsed, err := time.ParseDuration(string(text))
+	if err != nil {
+		return err
+	}
+	if parsed <= 0 || parsed > maxDelay {
+		return fmt.Errorf("field must be set with a delay above 0s and up to %s", maxDelay)
+	}
+	*d = delay(parsed)
+	return nil
+}
+
+// Release ages are measured in days or years rather than hours so on top of anything
+// time.ParseDuration takes we accept a number of days like "365d"
+type age time.Duration
+
+func (a *age) UnmarshalText(text []byte) error {
+	value := string(text)
+	if days, ok := strings.CutSuffix(value, "d"); ok {
+		numDays, err := strconv.Atoi(days)
+		if err != nil || numDays <= 0 {
+			return fmt.Errorf("field must be a positive number of days like 365d")
+		}
+		*a = age(time.Duration(numDays) * 24 * time.Hour)
+		return nil
+	}
+	parsed, err := time.ParseDuration(value)
+	if err != nil {
+		return err
+	}
+	if parsed <= 0 {
+		return fmt.Errorf("field must be set with an age above 0s")
+	}
+	*a = age(parsed)
+	return nil
+}
+
 // This interface lets us mock our ConfigLoader for testing
 type configLoader interface {
 	LoadConfig() ([]byte, error)
@@ -0,0 +1,194 @@
+package feedproxy
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log/slog"
+	"maps"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/atomicmeganerd/starfeed/common"
+)
+
+const shutdownTimeout = 5 * time.Second
+
+type fetchFunc func(ctx context.Context) ([]byte, error)
+
+// Server serves the feeds of private repos to an RSS server that can only fetch feeds
+// anonymously. The gitforge clients register each private feed along with a function that fetches
+// it with their credentials. Every feed gets its own URL named after an HMAC of the feed URL so
+// the URLs can't be guessed and stay the same across runs and restarts as long as the secret does.
+type Server struct {
+	baseURL string
+	secret  []byte
+	logger  *slog.Logger
+
+	// The feeds of each forge by their id
+	mu    sync.RWMutex
+	feeds map[string]map[string]fetchFunc
+}
+
+func NewServer(baseURL, secret string, logger *slog.Logger) *Server {
+	return &Server{
+		baseURL: strings.TrimSuffix(baseURL, "/"),
+		secret:  []byte(secret),
+		logger:  logger,
+		feeds:   make(map[string]map[string]fetchFunc),
+	}
+}
+
+// Starts a new set of feeds for a run of the forge
+func (s *Server) NewFeedSet(forge string) *FeedSet {
+	return &FeedSet{server: s, forge: forge, feeds: make(map[string]fetchFunc)}
+}
+
+func (s *Server) feedID(feedURL common.FeedURL) string {
+	mac := hmac.New(sha256.New, s.secret)
+	mac.Write([]byte(feedURL))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func (s *Server) proxyURL(id string) common.FeedURL {
+	return common.FeedURL(fmt.Sprintf("%s/feeds/%s.atom", s.baseURL, id))
+}
+
+// The id of one of our URLs or false if it is not one of ours
+func (s *Server) parseProxyURL(feedURL common.FeedURL) (string, bool) {
+	file, ok := strings.CutPrefix(feedURL.String(), s.baseURL+"/feeds/")
+	if !ok {
+		return "", false
+	}
+	return strings.CutSuffix(file, ".atom")
+}
+
+func (s *Server) add(forge, id string, fetch fetchFunc) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.feeds[forge] == nil {
+		s.feeds[forge] = make(map[string]fetchFunc)
+	}
+	s.feeds[forge][id] = fetch
+}
+
+func (s *Server) lookup(id string) (fetchFunc, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	for _, feeds := range s.feeds {
+		if fetch, ok := feeds[id]; ok {
+			return fetch, true
+		}
+	}
+	return nil, false
+}
+
+// FeedSet holds the feeds a forge registers on one run. They are served as soon as they are
+// registered. Publish then stops serving the feeds the forge registered on earlier runs but not on
+// this one, such as those of repos that were unstarred or made public.
+type FeedSet struct {
+	server *Server
+	forge  string
+
+	mu    sync.Mutex
+	feeds map[string]fetchFunc
+}
+
+// Register returns the URL the RSS server should subscribe to for the feed. Registering a feed
+// again replaces how it is fetched so that rotated tokens are picked up.
+func (f *FeedSet) Register(
+	feedURL common.FeedURL,
+	fetch func(ctx context.Context) ([]byte, error),
+) common.FeedURL {
+	id := f.server.feedID(feedURL)
+	f.mu.Lock()
+	f.feeds[id] = fetch
+	f.mu.Unlock()
+	f.server.add(f.forge, id, fetch)
+	return f.server.proxyURL(id)
+}
+
+// Alias serves the feed we serve at the proxy URL to at the proxy URL from as well. RSS servers
+// that can't retarget a subscription stay subscribed to the proxy URL of the old name of a renamed
+// repo, which is only known from the repo index and would be gone after a restart.
+func (f *FeedSet) Alias(from, to common.FeedURL) {
+	fromID, fromOK := f.server.parseProxyURL(from)
+	toID, toOK := f.server.parseProxyURL(to)
+	if !fromOK || !toOK {
+		return
+	}
+	f.mu.Lock()
+	fetch, registered := f.feeds[toID]
+	if registered {
+		f.feeds[fromID] = fetch
+	}
+	f.mu.Unlock()
+	if registered {
+		f.server.add(f.forge, fromID, fetch)
+	}
+}
+
+// Replaces the feeds the forge registered on earlier runs with the ones in the set
+func (f *FeedSet) Publish() {
+	f.mu.Lock()
+	feeds := maps.Clone(f.feeds)
+	f.mu.Unlock()
+	f.server.mu.Lock()
+	defer f.server.mu.Unlock()
+	f.server.feeds[f.forge] = feeds
+}
+
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /feeds/{file}", s.serveFeed)
+	return mux
+}
+
+// Unknown feeds get a 404 so the RSS server can tell them apart from a forge that is down
+func (s *Server) serveFeed(w http.ResponseWriter, r *http.Request) {
+	id, ok := strings.CutSuffix(r.PathValue("file"), ".atom")
+	fetch, registered := s.lookup(id)
+	if !ok || !registered {
+		http.NotFound(w, r)
+		return
+	}
+
+	data, err := fetch(r.Context())
+	if err != nil {
+		s.logger.Warn("Could not fetch proxied feed", "id", id, "error", err)
+		http.Error(w, "could not fetch feed from the gitforge", http.StatusBadGateway)
+		return
+	}
+	w.Header().Set("Content-Type", "application/atom+xml; charset=utf-8")
+	_, _ = w.Write(data)
+}
+
+// Listens on the address and serves in the background until the context is cancelled. We listen
+// before we return so that a bad address fails at startup.
+func (s *Server) Start(ctx context.Context, addr string) error {
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return fmt.Errorf("could not listen on %s: %w", addr, err)
+	}
+	server := &http.Server{Handler: s.Handler(), ReadHeaderTimeout: 10 * time.Second}
+
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+		defer cancel()
+		_ = server.Shutdown(shutdownCtx)
+	}()
+	go func() {
+		if err := server.Serve(listener); !errors.Is(err, http.ErrServerClosed) {
+			s.logger.Error("Feed proxy stopped", "error", err)
+		}
+	}()
+	s.logger.Info("Feed proxy is listening", "addr", listener.Addr().String())
+	return nil
+}
@@ -0,0 +1,163 @@
+package feedproxy
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/atomicmeganerd/starfeed/common"
+	"github.com/atomicmeganerd/starfeed/testutils"
+)
+
+func TestFeedSetRegister(t *testing.T) {
+	server := NewServer(
+		"http://starfeed:8080/", "proxy_secret_12345", testutils.TestLogger(t),
+	).NewFeedSet("GitHub")
+	other := NewServer(
+		"http://starfeed:8080", "other_secret_12345", testutils.TestLogger(t),
+	).NewFeedSet("GitHub")
+	noop := func(ctx context.Context) ([]byte, error) { return nil, nil }
+
+	feedURL := common.FeedURL("https://github.com/org/private/releases.atom")
+	first := server.Register(feedURL, noop)
+	if !strings.HasPrefix(first.String(), "http://starfeed:8080/feeds/") {
+		t.Errorf("Expected a URL of the proxy, got %s", first)
+	}
+	if strings.Contains(first.String(), "private") {
+		t.Errorf("Expected the URL to hide the repo, got %s", first)
+	}
+	if again := server.Register(feedURL, noop); again != first {
+		t.Errorf("Expected the same URL for the same feed, got %s and %s", first, again)
+	}
+	tagsURL := common.FeedURL("https://github.com/org/private/tags.atom")
+	if tags := server.Register(tagsURL, noop); tags == first {
+		t.Errorf("Expected different URLs for different feeds, got %s for both", tags)
+	}
+	if otherSecret := other.Register(feedURL, noop); otherSecret == first {
+		t.Errorf("Expected different URLs for different secrets, got %s for both", otherSecret)
+	}
+}
+
+func TestServerServeFeed(t *testing.T) {
+	feed := `<feed xmlns="http://www.w3.org/2005/Atom"><entry/></feed>`
+	server := NewServer("http://starfeed:8080", "proxy_secret_12345", testutils.TestLogger(t))
+	feeds := server.NewFeedSet("GitHub")
+	okURL := feeds.Register(
+		"https://github.com/org/ok/releases.atom",
+		func(ctx context.Context) ([]byte, error) { return []byte(feed), nil },
+	)
+	failingURL := feeds.Register(
+		"https://github.com/org/failing/releases.atom",
+		func(ctx context.Context) ([]byte, error) {
+			return nil, common.HTTPError{StatusCode: http.StatusInternalServerError}
+		},
+	)
+
+	testCases := []struct {
+		name               string
+		path               string
+		expectedStatusCode int
+		expectedBody       string
+	}{
+		{
+			name:               "Registered feeds are fetched",
+			path:               strings.TrimPrefix(okURL.String(), "http://starfeed:8080"),
+			expectedStatusCode: http.StatusOK,
+			expectedBody:       feed,
+		},
+		{
+			name:               "Forge errors are a bad gateway",
+			path:               strings.TrimPrefix(failingURL.String(), "http://starfeed:8080"),
+			expectedStatusCode: http.StatusBadGateway,
+		},
+		{
+			name:               "Unknown feeds are not found",
+			path:               "/feeds/0123456789abcdef.atom",
+			expectedStatusCode: http.StatusNotFound,
+		},
+		{
+			name:               "Anything else is not found",
+			path:               "/",
+			expectedStatusCode: http.StatusNotFound,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			recorder := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodGet, tc.path, nil)
+			server.Handler().ServeHTTP(recorder, req)
+
+			res := recorder.Result()
+			if res.StatusCode != tc.expectedStatusCode {
+				t.Errorf("Expected status %d, got %d", tc.expectedStatusCode, res.StatusCode)
+			}
+			body, err := io.ReadAll(res.Body)
+			if err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+			if tc.expectedBody != "" && string(body) != tc.expectedBody {
+				t.Errorf("Expected body %s, got %s", tc.expectedBody, body)
+			}
+		})
+	}
+}
+
+func TestFeedSetPublish(t *testing.T) {
+	server := NewServer("http://starfeed:8080", "proxy_secret_12345", testutils.TestLogger(t))
+	fetch := func(ctx context.Context) ([]byte, error) { return []byte("<feed/>"), nil }
+	statusOf := func(feedURL common.FeedURL) int {
+		t.Helper()
+		recorder := httptest.NewRecorder()
+		path := strings.TrimPrefix(feedURL.String(), "http://starfeed:8080")
+		server.Handler().ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, path, nil))
+		return recorder.Code
+	}
+
+	// On the first run we follow two private repos and another forge follows one too
+	firstRun := server.NewFeedSet("GitHub")
+	oldName := firstRun.Register("https://github.com/org/old-name/releases.atom", fetch)
+	unstarred := firstRun.Register("https://github.com/org/unstarred/releases.atom", fetch)
+	firstRun.Publish()
+	otherForge := server.NewFeedSet("GHES")
+	otherFeed := otherForge.Register("https://ghe.corp/team/private/releases.atom", fetch)
+	otherForge.Publish()
+
+	// On the next run one repo has been renamed and the other is no longer starred. Until we
+	// publish we keep serving what we had.
+	nextRun := server.NewFeedSet("GitHub")
+	newName := nextRun.Register("https://github.com/org/new-name/releases.atom", fetch)
+	if status := statusOf(unstarred); status != http.StatusOK {
+		t.Errorf("Expected the feed to be served until we publish, got %d", status)
+	}
+	nextRun.Alias(oldName, newName)
+	nextRun.Alias("https://github.com/org/public/releases.atom", newName)
+	nextRun.Publish()
+
+	expected := map[common.FeedURL]int{
+		oldName:   http.StatusOK,
+		newName:   http.StatusOK,
+		unstarred: http.StatusNotFound,
+		otherFeed: http.StatusOK,
+	}
+	for feedURL, expectedStatus := range expected {
+		if status := statusOf(feedURL); status != expectedStatus {
+			t.Errorf("Expected status %d for %s, got %d", expectedStatus, feedURL, status)
+		}
+	}
+}
+
+func TestServerStart(t *testing.T) {
+	server := NewServer("http://starfeed:8080", "proxy_secret_12345", testutils.TestLogger(t))
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	if err := server.Start(ctx, "256.0.0.1:http"); err == nil {
+		t.Fatal("Expected an error for a bad address, got nil")
+	}
+	if err := server.Start(ctx, "127.0.0.1:0"); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+}
@@ -0,0 +1,186 @@
+package gitforge
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/atomicmeganerd/starfeed/common"
+)
+
+const (
+	// The scope we ask for. Stars are public so GitHub only needs to know who the user is.
+	gitHubDeviceScope = "read:user"
+	// RFC 8628 says to poll every 5 seconds if the forge does not tell us otherwise and to back
+	// off by another 5 seconds every time we are told to slow down. Codes that don't say when
+	// they expire get the 15 minutes GitHub gives them.
+	defaultDevicePollInterval = 5
+	devicePollBackoff         = 5
+	defaultDeviceCodeExpiry   = 900
+)
+
+// DeviceCode is what the forge hands out when a device flow starts. The user opens the
+// VerificationURI and enters the UserCode while we poll for the token with the DeviceCode.
+type DeviceCode struct {
+	DeviceCode              string `json:"device_code"`
+	UserCode                string `json:"user_code"`
+	VerificationURI         string `json:"verification_uri"`
+	VerificationURIComplete string `json:"verification_uri_complete"`
+	ExpiresIn               int    `json:"expires_in"`
+	Interval                int    `json:"interval"`
+}
+
+func (c DeviceCode) pollInterval() int {
+	if c.Interval <= 0 {
+		return defaultDevicePollInterval
+	}
+	return c.Interval
+}
+
+func (c DeviceCode) expiresIn() int {
+	if c.ExpiresIn <= 0 {
+		return defaultDeviceCodeExpiry
+	}
+	return c.ExpiresIn
+}
+
+// Both the token and the errors we get while polling come back in the same shape
+type deviceTokenResponse struct {
+	AccessToken      string `json:"access_token"`
+	Error            string `json:"error"`
+	ErrorDescription string `json:"error_description"`
+}
+
+// DeviceFlow runs the OAuth device authorization flow (RFC 8628) against GitHub so a user can get
+// a token without creating one by hand. Forgejo does not support the flow. GitLab does, but its
+// tokens expire after two hours and only work as a bearer token, so a personal access token it is.
+type DeviceFlow struct {
+	clientID string
+	scope    string
+	codeURL  string
+	tokenURL string
+	client   *http.Client
+	retry    common.RetryPolicy
+	// The intervals the forge gives us are in seconds. Tests shrink this to poll faster.
+	pollUnit time.Duration
+}
+
+func NewDeviceFlow(
+	opts GitForgeOptions,
+	clientID string,
+	client *http.Client,
+	retry common.RetryPolicy,
+) (*DeviceFlow, error) {
+	webURL := opts.webURL()
+	if webURL == "" && opts.Fqdn != "" {
+		webURL = "https://" + opts.Fqdn
+	}
+	if webURL == "" {
+		return nil, errors.New("the device flow needs the fqdn or web_url of the forge")
+	}
+
+	flow := &DeviceFlow{
+		clientID: clientID,
+		client:   client,
+		retry:    retry,
+		pollUnit: time.Second,
+	}
+	switch opts.Type {
+	case GitHubForgeType:
+		flow.scope = gitHubDeviceScope
+		flow.codeURL = webURL + "/login/device/code"
+		flow.tokenURL = webURL + "/login/oauth/access_token"
+	default:
+		return nil, fmt.Errorf("%s does not support the device flow", opts.Type)
+	}
+	return flow, nil
+}
+
+// Starts the flow. The user has to be shown the verification URI and the user code.
+func (f *DeviceFlow) RequestCode(ctx context.Context) (DeviceCode, error) {
+	form := url.Values{"client_id": {f.clientID}, "scope": {f.scope}}
+	data, err := f.post(ctx, f.codeURL, form)
+	if err != nil {
+		return DeviceCode{}, fmt.Errorf("error %w requesting device code from %s", err, f.codeURL)
+	}
+
+	code := DeviceCode{}
+	if err := json.Unmarshal(data, &code); err != nil {
+		return DeviceCode{}, fmt.Errorf("error %w parsing device code response", err)
+	}
+	if code.DeviceCode == "" || code.UserCode == "" {
+		return DeviceCode{}, errors.New("device code response did not contain a code")
+	}
+	return code, nil
+}
+
+// Polls the forge until the user has approved or denied the request or the code expires
+func (f *DeviceFlow) PollToken(ctx context.Context, code DeviceCode) (string, error) {
+	interval := code.pollInterval()
+	expiresAt := time.Now().Add(time.Duration(code.expiresIn()) * f.pollUnit)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return "", ctx.Err()
+		case <-time.After(time.Duration(interval) * f.pollUnit):
+		}
+
+		resp, err := f.requestToken(ctx, code)
+		if err != nil {
+			return "", err
+		}
+		switch resp.Error {
+		case "":
+			return resp.AccessToken, nil
+		case "authorization_pending":
+			// The user has not got to it yet
+		case "slow_down":
+			interval += devicePollBackoff
+		default:
+			return "", fmt.Errorf("device flow failed: %s %s", resp.Error, resp.ErrorDescription)
+		}
+		if time.Now().After(expiresAt) {
+			return "", errors.New("device code expired before it was approved")
+		}
+	}
+}
+
+// GitHub answers a pending request with 200 but the RFC says to answer with 400 so we look at the
+// body before we look at the status
+func (f *DeviceFlow) requestToken(
+	ctx context.Context,
+	code DeviceCode,
+) (deviceTokenResponse, error) {
+	form := url.Values{
+		"client_id":   {f.clientID},
+		"device_code": {code.DeviceCode},
+		"grant_type":  {"urn:ietf:params:oauth:grant-type:device_code"},
+	}
+	data, reqErr := f.post(ctx, f.tokenURL, form)
+
+	resp := deviceTokenResponse{}
+	err := json.Unmarshal(data, &resp)
+	if err == nil && (resp.Error != "" || resp.AccessToken != "") {
+		return resp, nil
+	}
+	if reqErr != nil {
+		return resp, fmt.Errorf("error %w requesting token from %s", reqErr, f.tokenURL)
+	}
+	return resp, errors.New("token response did not contain a token")
+}
+
+func (f *DeviceFlow) post(ctx context.Context, reqURL string, form url.Values) ([]byte, error) {
+	headers := http.Header{}
+	headers.Set("Accept", "application/json")
+	headers.Set("Content-Type", "application/x-www-form-urlencoded")
+	headers.Set("User-Agent", "github.com/atomicmeganerd/starfeed")
+	data, _, err := common.DoAPIRequest(
+		ctx, http.MethodPost, reqURL, []byte(form.Encode()), headers, f.client, f.retry,
+	)
+	return data, err
+}
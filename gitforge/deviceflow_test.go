@@ -0,0 +1,233 @@
+package gitforge
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/atomicmeganerd/starfeed/common"
+)
+
+// A fake of the device flow endpoints of a forge. Each poll of the token endpoint gets the next
+// of the canned responses.
+type fakeDeviceEndpoint struct {
+	t             *testing.T
+	codePath      string
+	tokenPath     string
+	expectedScope string
+	polls         []fakeTokenPoll
+	numPolls      atomic.Int32
+}
+
+type fakeTokenPoll struct {
+	statusCode int
+	body       string
+}
+
+func (f *fakeDeviceEndpoint) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil || r.Method != http.MethodPost {
+		http.Error(w, "bad request", http.StatusBadRequest)
+		return
+	}
+	if clientID := r.PostForm.Get("client_id"); clientID != "starfeed-client" {
+		f.t.Errorf("Expected client id starfeed-client, got %q", clientID)
+	}
+	switch r.URL.Path {
+	case f.codePath:
+		if scope := r.PostForm.Get("scope"); scope != f.expectedScope {
+			f.t.Errorf("Expected scope %s, got %q", f.expectedScope, scope)
+		}
+		_, _ = fmt.Fprint(w, `{"device_code": "dev123", "user_code": "ABCD-1234",
+			"verification_uri": "https://forge/device", "expires_in": 900, "interval": 1}`)
+	case f.tokenPath:
+		if deviceCode := r.PostForm.Get("device_code"); deviceCode != "dev123" {
+			f.t.Errorf("Expected device code dev123, got %q", deviceCode)
+		}
+		poll := f.polls[min(int(f.numPolls.Add(1))-1, len(f.polls)-1)]
+		w.WriteHeader(poll.statusCode)
+		_, _ = fmt.Fprint(w, poll.body)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func TestDeviceFlow(t *testing.T) {
+	pending := `{"error": "authorization_pending"}`
+	token := `{"access_token": "gho_devicetoken", "token_type": "bearer"}`
+
+	testCases := []struct {
+		name          string
+		forgeType     string
+		codePath      string
+		tokenPath     string
+		expectedScope string
+		polls         []fakeTokenPoll
+		expectErr     bool
+		expectedToken string
+		expectedPolls int32
+	}{
+		{
+			name:          "GitHub answers pending polls with 200",
+			forgeType:     GitHubForgeType,
+			codePath:      "/login/device/code",
+			tokenPath:     "/login/oauth/access_token",
+			expectedScope: gitHubDeviceScope,
+			polls: []fakeTokenPoll{
+				{statusCode: http.StatusOK, body: pending},
+				{statusCode: http.StatusOK, body: `{"error": "slow_down"}`},
+				{statusCode: http.StatusOK, body: token},
+			},
+			expectedToken: "gho_devicetoken",
+			expectedPolls: 3,
+		},
+		{
+			name:          "Pending polls answered with 400 like the RFC says",
+			forgeType:     GitHubForgeType,
+			codePath:      "/login/device/code",
+			tokenPath:     "/login/oauth/access_token",
+			expectedScope: gitHubDeviceScope,
+			polls: []fakeTokenPoll{
+				{statusCode: http.StatusBadRequest, body: pending},
+				{statusCode: http.StatusOK, body: token},
+			},
+			expectedToken: "gho_devicetoken",
+			expectedPolls: 2,
+		},
+		{
+			name:          "Denied requests fail",
+			forgeType:     GitHubForgeType,
+			codePath:      "/login/device/code",
+			tokenPath:     "/login/oauth/access_token",
+			expectedScope: gitHubDeviceScope,
+			polls: []fakeTokenPoll{
+				{statusCode: http.StatusOK, body: `{"error": "access_denied"}`},
+			},
+			expectErr:     true,
+			expectedPolls: 1,
+		},
+		{
+			name:          "Server errors fail",
+			forgeType:     GitHubForgeType,
+			codePath:      "/login/device/code",
+			tokenPath:     "/login/oauth/access_token",
+			expectedScope: gitHubDeviceScope,
+			polls: []fakeTokenPoll{
+				{statusCode: http.StatusInternalServerError, body: "oops"},
+			},
+			expectErr:     true,
+			expectedPolls: 1,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			endpoint := &fakeDeviceEndpoint{
+				t:             t,
+				codePath:      tc.codePath,
+				tokenPath:     tc.tokenPath,
+				expectedScope: tc.expectedScope,
+				polls:         tc.polls,
+			}
+			server := httptest.NewServer(endpoint)
+			defer server.Close()
+
+			flow, err := NewDeviceFlow(
+				GitForgeOptions{Type: tc.forgeType, WebURL: server.URL},
+				"starfeed-client",
+				server.Client(),
+				common.RetryPolicy{},
+			)
+			if err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+			flow.pollUnit = time.Millisecond
+
+			code, err := flow.RequestCode(context.Background())
+			if err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+			if code.UserCode != "ABCD-1234" {
+				t.Errorf("Expected user code ABCD-1234, got %s", code.UserCode)
+			}
+
+			actual, err := flow.PollToken(context.Background(), code)
+			if numPolls := endpoint.numPolls.Load(); numPolls != tc.expectedPolls {
+				t.Errorf("Expected %d polls, got %d", tc.expectedPolls, numPolls)
+			}
+			if tc.expectErr {
+				if err == nil {
+					t.Fatal("Expected an error, got nil")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+			if actual != tc.expectedToken {
+				t.Errorf("Expected token %s, got %s", tc.expectedToken, actual)
+			}
+		})
+	}
+}
+
+func TestNewDeviceFlow(t *testing.T) {
+	testCases := []struct {
+		name            string
+		opts            GitForgeOptions
+		expectErr       bool
+		expectedCodeURL string
+	}{
+		{
+			name:            "GitHub from the fqdn",
+			opts:            GitForgeOptions{Type: GitHubForgeType, Fqdn: "github.com"},
+			expectedCodeURL: "https://github.com/login/device/code",
+		},
+		{
+			name: "GitHub Enterprise Server from the web url",
+			opts: GitForgeOptions{
+				Type:   GitHubForgeType,
+				APIURL: "https://ghe.corp/api/v3",
+				WebURL: "https://ghe.corp/",
+			},
+			expectedCodeURL: "https://ghe.corp/login/device/code",
+		},
+		{
+			name:      "Without a web url",
+			opts:      GitForgeOptions{Type: GitHubForgeType, APIURL: "https://ghe.corp/api/v3"},
+			expectErr: true,
+		},
+		{
+			name:      "Forgejo does not support the flow",
+			opts:      GitForgeOptions{Type: ForgejoForgeType, Fqdn: "codeberg.org"},
+			expectErr: true,
+		},
+		{
+			name:      "GitLab tokens from the flow expire so we don't support it",
+			opts:      GitForgeOptions{Type: GitLabForgeType, Fqdn: "gitlab.com"},
+			expectErr: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			flow, err := NewDeviceFlow(tc.opts, "starfeed-client", nil, common.RetryPolicy{})
+			if tc.expectErr {
+				if err == nil {
+					t.Fatal("Expected an error, got nil")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+			if flow.codeURL != tc.expectedCodeURL {
+				t.Errorf("Expected code url %s, got %s", tc.expectedCodeURL, flow.codeURL)
+			}
+		})
+	}
+}
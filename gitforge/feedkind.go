@@ -0,0 +1,56 @@
+package gitforge
+
+import (
+	"context"
+	"strings"
+)
+
+// FeedKind selects which Atom feed of a repo we subscribe to. Many repos never publish releases
+// but do push tags so those can be followed with the tags feed instead.
+type FeedKind string
+
+const (
+	FeedKindReleases FeedKind = "releases"
+	FeedKindTags     FeedKind = "tags"
+	// Use the releases feed but fall back to the tags feed if it has no entries
+	FeedKindReleasesOrTags FeedKind = "releases_or_tags"
+)
+
+// The kind configured for the repo or the default of the forge. Overrides are keyed by the full
+// name of the repo (owner/name) and are not case sensitive.
+func (c GitForgeClient) feedKindFor(repo GitRepo) FeedKind {
+	if kind, ok := c.feedKindOverrides[strings.ToLower(repo.FullName)]; ok {
+		return kind
+	}
+	return c.feedKind
+}
+
+// We only fall back to the tags feed if the releases feed is stale. If checking the releases feed
+// failed we can't tell which feed the repo should use so we report the failure under both URLs
+// so that neither subscription is removed. The copy under the tags URL has no repo id so that it
+// is ignored when following renames.
+func (c GitForgeClient) checkReleasesOrTagsFeed(ctx context.Context, repo GitRepo) FeedResultMap {
+	releases := c.checkFeed(ctx, repo, FeedKindReleases)
+	if releases.IsOK() {
+		return FeedResultMap{repo.FeedURL: releases}
+	}
+	if !releases.IsStale() {
+		tags := releases
+		tags.RepoID = 0
+		tags.FeedKind = FeedKindTags
+		return FeedResultMap{repo.FeedURL: releases, repo.TagsFeedURL: tags}
+	}
+	return FeedResultMap{repo.TagsFeedURL: c.checkFeed(ctx, repo, FeedKindTags)}
+}
+
+// Checks whichever feeds the feed kind of the repo asks for
+func (c GitForgeClient) checkRepoFeeds(ctx context.Context, repo GitRepo) FeedResultMap {
+	switch c.feedKindFor(repo) {
+	case FeedKindTags:
+		return FeedResultMap{repo.TagsFeedURL: c.checkFeed(ctx, repo, FeedKindTags)}
+	case FeedKindReleasesOrTags:
+		return c.checkReleasesOrTagsFeed(ctx, repo)
+	default:
+		return FeedResultMap{repo.FeedURL: c.checkFeed(ctx, repo, FeedKindReleases)}
+	}
+}
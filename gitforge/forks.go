@@ -0,0 +1,94 @@
+package gitforge
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/atomicmeganerd/starfeed/common"
+)
+
+// ForkPolicy decides which feeds we follow for forks. We often star our own forks, which never
+// release, while the upstream repo they were forked from does.
+type ForkPolicy string
+
+const (
+	// Follow the fork itself. This is the default.
+	ForkSelf ForkPolicy = "self"
+	// Follow the parent the fork was forked from instead of the fork
+	ForkParent ForkPolicy = "parent"
+	// Follow both the fork and its parent
+	ForkBoth ForkPolicy = "both"
+)
+
+// Swaps forks for their parents or adds the parents alongside them. A parent is only followed once
+// even if it is starred as well or several of its forks are. Parents are in the same star list
+// and come from the same sources as the fork that brought them in.
+func (c GitForgeClient) followForkParents(ctx context.Context, repos []GitRepo) []GitRepo {
+	if c.forks == ForkSelf {
+		return repos
+	}
+	followed := make(map[common.FeedURL]bool, len(repos))
+	for _, repo := range repos {
+		followed[repo.FeedURL] = true
+	}
+
+	result := make([]GitRepo, 0, len(repos))
+	for _, repo := range repos {
+		parent, ok := c.forkParent(ctx, repo)
+		if !ok {
+			result = append(result, repo)
+			continue
+		}
+		if c.forks == ForkBoth {
+			result = append(result, repo)
+		}
+		if followed[parent.FeedURL] {
+			continue
+		}
+		followed[parent.FeedURL] = true
+		result = append(result, parent)
+	}
+	return result
+}
+
+// Returns the parent of a fork ready to be followed. If we can't find the parent we keep
+// following the fork itself.
+func (c GitForgeClient) forkParent(ctx context.Context, repo GitRepo) (GitRepo, bool) {
+	if !repo.Fork {
+		return GitRepo{}, false
+	}
+	parent := repo.Parent
+	if parent == nil && c.forgeType != GitLabForgeType {
+		var err error
+		if parent, err = c.fetchParent(ctx, repo); err != nil {
+			c.logger.Warn("Could not look up parent of fork", "repo", repo.FullName, "error", err)
+			return GitRepo{}, false
+		}
+	}
+	if parent == nil {
+		return GitRepo{}, false
+	}
+
+	c.logger.Debug("Following parent of fork", "repo", repo.FullName, "parent", parent.FullName)
+	followed := c.withFeedURLs(*parent)
+	followed.StarList = repo.StarList
+	followed.Sources = repo.Sources
+	return followed, true
+}
+
+// GitHub leaves the parent out of the starred repo lists but sends it when we fetch the fork on
+// its own. Forgejo already sends it in the list so forkParent only gets here when it is missing.
+func (c GitForgeClient) fetchParent(ctx context.Context, repo GitRepo) (*GitRepo, error) {
+	repoURL := fmt.Sprintf("%s/repos/%s", c.apiURL, repo.FullName)
+	c.logger.Debug("Looking up parent of fork", "url", repoURL)
+	data, _, err := c.doRequest(ctx, repoURL)
+	if err != nil {
+		return nil, fmt.Errorf("error %w getting repo from %s", err, repoURL)
+	}
+	fork := GitRepo{}
+	if err := json.Unmarshal(data, &fork); err != nil {
+		return nil, fmt.Errorf("error %w parsing repo response", err)
+	}
+	return fork.Parent, nil
+}
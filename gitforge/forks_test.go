@@ -0,0 +1,182 @@
+package gitforge
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/atomicmeganerd/starfeed/common"
+	"github.com/atomicmeganerd/starfeed/testutils"
+)
+
+func TestLoadFeedsForks(t *testing.T) {
+	// Our forks of tool and lib and a fork whose parent we can't look up. lib is starred as well.
+	gitHubStarred := `[
+		{"id": 1, "name": "tool", "full_name": "me/tool", "fork": true,
+			"html_url": "https://github.com/me/tool"},
+		{"id": 2, "name": "lib", "full_name": "me/lib", "fork": true,
+			"html_url": "https://github.com/me/lib"},
+		{"id": 20, "name": "lib", "full_name": "org/lib",
+			"html_url": "https://github.com/org/lib"},
+		{"id": 3, "name": "gone", "full_name": "me/gone", "fork": true,
+			"html_url": "https://github.com/me/gone"}
+	]`
+	gitHubRepos := map[string]string{
+		"me/tool": `{"id": 1, "full_name": "me/tool", "fork": true, "parent": {"id": 10,
+			"name": "tool", "full_name": "org/tool", "html_url": "https://github.com/org/tool"}}`,
+		"me/lib": `{"id": 2, "full_name": "me/lib", "fork": true, "parent": {"id": 20,
+			"name": "lib", "full_name": "org/lib", "html_url": "https://github.com/org/lib"}}`,
+	}
+	// Forgejo sends the parent in the starred repo list so we never have to look it up
+	forgejoStarred := `[
+		{"id": 1, "name": "tool", "full_name": "me/tool", "fork": true,
+			"html_url": "https://github.com/me/tool", "parent": {"id": 10, "name": "tool",
+			"full_name": "org/tool", "html_url": "https://github.com/org/tool"}}
+	]`
+	validFeed := `<feed xmlns="http://www.w3.org/2005/Atom"><entry><title>v1</title></entry></feed>`
+	feedURL := func(fullName string) common.FeedURL {
+		return common.FeedURL(fmt.Sprintf("https://github.com/%s/releases.atom", fullName))
+	}
+
+	testCases := []struct {
+		name          string
+		forgeType     string
+		forks         ForkPolicy
+		starred       string
+		expectedFeeds map[common.FeedURL]int64
+	}{
+		{
+			name:      "Forks are followed by default",
+			forgeType: GitHubForgeType,
+			starred:   gitHubStarred,
+			expectedFeeds: map[common.FeedURL]int64{
+				feedURL("me/tool"): 1,
+				feedURL("me/lib"):  2,
+				feedURL("org/lib"): 20,
+				feedURL("me/gone"): 3,
+			},
+		},
+		{
+			name:      "Parents replace forks",
+			forgeType: GitHubForgeType,
+			forks:     ForkParent,
+			starred:   gitHubStarred,
+			expectedFeeds: map[common.FeedURL]int64{
+				feedURL("org/tool"): 10,
+				feedURL("org/lib"):  20,
+				feedURL("me/gone"):  3,
+			},
+		},
+		{
+			name:      "Parents are followed alongside forks",
+			forgeType: GitHubForgeType,
+			forks:     ForkBoth,
+			starred:   gitHubStarred,
+			expectedFeeds: map[common.FeedURL]int64{
+				feedURL("me/tool"):  1,
+				feedURL("org/tool"): 10,
+				feedURL("me/lib"):   2,
+				feedURL("org/lib"):  20,
+				feedURL("me/gone"):  3,
+			},
+		},
+		{
+			name:      "Forgejo parents come with the starred repos",
+			forgeType: ForgejoForgeType,
+			forks:     ForkParent,
+			starred:   forgejoStarred,
+			expectedFeeds: map[common.FeedURL]int64{
+				feedURL("org/tool"): 10,
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			mocks := []testutils.MockRoutedResponse{
+				{
+					UrlPattern: `/user/starred`,
+					Response: http.Response{
+						Body:       io.NopCloser(strings.NewReader(tc.starred)),
+						StatusCode: http.StatusOK,
+					},
+				},
+				{
+					UrlPattern: `/repos/me/gone$`,
+					Response: http.Response{
+						Body:       io.NopCloser(strings.NewReader(`{}`)),
+						StatusCode: http.StatusNotFound,
+						Status:     testutils.StatusNotFoundString,
+					},
+				},
+			}
+			for fullName, body := range gitHubRepos {
+				mocks = append(mocks, testutils.MockRoutedResponse{
+					UrlPattern: `/repos/` + fullName + `$`,
+					Response: http.Response{
+						Body:       io.NopCloser(strings.NewReader(body)),
+						StatusCode: http.StatusOK,
+					},
+				})
+			}
+			feedRepos := []string{"me/tool", "me/lib", "me/gone", "org/tool", "org/lib"}
+			for _, fullName := range feedRepos {
+				mocks = append(mocks, testutils.MockRoutedResponse{
+					UrlPattern: fullName + `/releases\.atom$`,
+					Response: http.Response{
+						Body:       io.NopCloser(strings.NewReader(validFeed)),
+						StatusCode: http.StatusOK,
+					},
+				})
+			}
+			mockTransport := testutils.NewMockRoutedResponseRoundTripper(mocks)
+
+			forge := NewGitForgeClient(
+				GitForgeOptions{Type: tc.forgeType, APIURL: "https://api.forge", Forks: tc.forks},
+				testutils.GitHubToken,
+				testutils.TestLogger(t),
+				&http.Client{Transport: &mockTransport},
+				common.RetryPolicy{},
+			)
+
+			actual, err := forge.LoadFeeds(context.Background())
+			if err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+			if len(actual) != len(tc.expectedFeeds) {
+				t.Fatalf("Expected %d feeds, got %v", len(tc.expectedFeeds), actual)
+			}
+			for feedURL, expectedID := range tc.expectedFeeds {
+				result, ok := actual[feedURL]
+				if !ok || !result.IsOK() {
+					t.Errorf("Expected a valid feed for %s, got %+v", feedURL, actual)
+				}
+				if actualID := result.RepoID; actualID != expectedID {
+					t.Errorf("Feed %s: expected repo %d, got %d", feedURL, expectedID, actualID)
+				}
+			}
+		})
+	}
+}
+
+func TestParseGitLabProjectsParent(t *testing.T) {
+	data := `[
+		{"id": 1, "path_with_namespace": "me/tool", "forked_from_project": {"id": 10,
+			"path_with_namespace": "org/tool", "web_url": "https://gitlab.com/org/tool"}},
+		{"id": 20, "path_with_namespace": "org/lib"}
+	]`
+	repos, err := parseGitLabProjects([]byte(data))
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if !repos[0].Fork || repos[0].Parent == nil || repos[0].Parent.FullName != "org/tool" {
+		t.Errorf("Expected me/tool to be a fork of org/tool, got %+v", repos[0])
+	}
+	if repos[1].Fork || repos[1].Parent != nil {
+		t.Errorf("Expected org/lib not to be a fork, got %+v", repos[1])
+	}
+}
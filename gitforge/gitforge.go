@@ -7,6 +7,7 @@ import (
 	"fmt"
 	"log/slog"
 	"net/http"
+	"net/url"
 	"regexp"
 	"strings"
 	"sync"
@@ -21,52 +22,110 @@ var nextPagePattern = regexp.MustCompile(`<([^>]+)>; rel="next"`)
 // GitForgeClient struct represents a GitForge. We can load RSS feeds for all starred repos that
 // belong to this Git Forge.
 type GitForgeClient struct {
-	fetchRepoURL string
-	headers      http.Header
-	logger       *slog.Logger
-	client       *http.Client
+	forgeType string
+	apiURL    string
+	webURL    string
+	headers   http.Header
+	logger    *slog.Logger
+	client    *http.Client
+	retry     common.RetryPolicy
+	limiter   *rateLimiter
+	// Which feed we subscribe to by default and per repo
+	feedKind          FeedKind
+	feedKindOverrides map[string]FeedKind
+	// Which starred repos we follow at all
+	rules []RepoRule
+	// Whether we look up the GitHub star list of each repo
+	starLists bool
+	// Whose repos we follow. The token owner's if this is empty.
+	usernames []string
+	// Where we find the repos to follow
+	sources []RepoSource
+	// Replaces the token when we authenticate as a GitHub App installation
+	appAuth *GitHubAppAuth
+	// Serves the feeds of private repos. Private repos are skipped without it.
+	feedProxy FeedProxy
+	// Whether we follow forks, their parents or both
+	forks ForkPolicy
+	// Whether we follow pull mirrors or the projects they mirror
+	mirrors MirrorPolicy
 }
 
 func NewGitForgeClient(
-	forgeType, fqdn, token string,
+	opts GitForgeOptions,
+	token string,
 	logger *slog.Logger,
 	client *http.Client,
+	retry common.RetryPolicy,
 ) GitForgeClient {
 	return GitForgeClient{
-		fetchRepoURL: buildStarredRepoUrl(forgeType, fqdn),
-		headers:      buildHeaders(forgeType, token),
-		logger:       logger,
-		client:       client,
+		forgeType: opts.Type,
+		apiURL:    opts.apiURL(),
+		webURL:    opts.webURL(),
+		headers:   buildHeaders(opts.Type, token),
+		logger:    logger,
+		client:    client,
+		retry:     retry,
+		limiter:   newRateLimiter(logger),
+
+		feedKind:          opts.feedKind(),
+		feedKindOverrides: opts.feedKindOverrides(),
+		rules:             opts.Rules,
+		starLists:         opts.StarLists && opts.Type == GitHubForgeType,
+		usernames:         opts.Usernames,
+		sources:           opts.sources(),
+		appAuth:           opts.AppAuth,
+		feedProxy:         opts.feedProxy(),
+		forks:             opts.forks(),
+		mirrors:           opts.Mirrors,
 	}
 }
 
 func (c GitForgeClient) LoadFeeds(
 	ctx context.Context,
 ) (FeedResultMap, error) {
-	starredRepos, err := c.fetchStarredRepos(ctx)
+	repos, err := c.fetchRepos(ctx)
 	if err != nil {
 		return nil, err
 	}
+	repos = c.filterRepos(repos)
+	if c.starLists {
+		// Without the lists every feed would be moved out of its list category so we give up
+		repoLists, err := c.fetchStarLists(ctx)
+		if err != nil {
+			return nil, err
+		}
+		for ix := range repos {
+			repos[ix].StarList = repoLists[repos[ix].ID]
+		}
+	}
+	// The parent of a fork can be private even if the fork is not while the origin of a mirror
+	// never is
+	repos = c.followMirrorOrigins(c.followForkParents(ctx, repos))
+	repos = c.skipPrivateRepos(repos)
 
 	starredFeeds := make(FeedResultMap)
 
-	// Check each repo to make sure it has valid entries in its ATOM feed for releases
+	// Check each repo to make sure it has valid entries in its ATOM feed for releases (or tags)
 	// This can be done in parallel to make it much faster.
 	mu := sync.Mutex{}
 	// We only use a errgroup here to get SetLimit. None of our goroutines can throw an
 	// error. I just like this better than using the weighted semaphore.
 	eg := &errgroup.Group{}
 	eg.SetLimit(5)
-	for _, repo := range starredRepos {
-		logger := c.logger.With("repoName", repo.Name, "feeedURL", repo.FeedURL)
+	for _, repo := range repos {
+		logger := c.logger.With("repoName", repo.Name)
 		eg.Go(func() error {
-			result := c.repoHasReleaseFeed(ctx, repo)
-			if result.IsOK() {
-				logger.Info("Repo has valid release feed")
-			}
+			checked, results := c.checkMirrorFeeds(ctx, repo)
 			mu.Lock()
-			starredFeeds[repo.FeedURL] = result
-			mu.Unlock()
+			defer mu.Unlock()
+			for feedURL, result := range results {
+				feedURL = c.subscriptionURL(checked, feedURL)
+				if result.IsOK() {
+					logger.Info("Repo has valid feed", "feedURL", feedURL, "kind", result.FeedKind)
+				}
+				starredFeeds[feedURL] = result
+			}
 			return nil
 		})
 	}
@@ -77,68 +136,58 @@ func (c GitForgeClient) LoadFeeds(
 	return starredFeeds, nil
 }
 
-func (c GitForgeClient) fetchStarredRepos(
-	ctx context.Context,
-) ([]GitRepo, error) {
-	allRepos := make([]GitRepo, 0)
-	nextPageURL := c.fetchRepoURL
-	for {
-		c.logger.Debug("Fetching starred repos", "url", nextPageURL)
-		data, respHeaders, err := common.DoAPIRequest(
-			ctx,
-			http.MethodGet,
-			nextPageURL,
-			nil,
-			c.headers,
-			c.client,
-		)
-		if err != nil {
-			return nil, fmt.Errorf(
-				"error %w getting raw data from gitforge url: %s", err, nextPageURL,
-			)
-		}
-
-		repos := make([]GitRepo, 0)
-		if err := json.Unmarshal(data, &repos); err != nil {
-			return nil, fmt.Errorf(
-				"error %w parsing JSON response from gitforge", err,
-			)
-		}
+// GitHub and Forgejo can list the stars of whoever owns the token directly but GitLab needs the
+// numeric id of the user so we have to look that up first. The stars of anyone else are listed
+// by their username on all of the forges.
+func (c GitForgeClient) starredRepoURL(ctx context.Context, username string) (string, error) {
+	if c.forgeType != GitLabForgeType || username != "" {
+		return buildStarredRepoUrl(c.forgeType, c.apiURL, username), nil
+	}
 
-		for ix := range repos {
-			repos[ix].FeedURL = common.FeedURL(
-				fmt.Sprintf(
-					"%s/releases.atom", repos[ix].RepoURL,
-				),
-			)
-		}
-		allRepos = append(allRepos, repos...)
+	userURL := fmt.Sprintf("%s/user", c.apiURL)
+	c.logger.Debug("Looking up GitLab user", "url", userURL)
+	data, _, err := c.doRequest(ctx, userURL)
+	if err != nil {
+		return "", fmt.Errorf("error %w looking up gitlab user from url: %s", err, userURL)
+	}
 
-		nextPageURL = c.parseNextPageURL(respHeaders)
-		if nextPageURL == "" {
-			c.logger.Info("Finished loading starred repos", "numRepos", len(allRepos))
-			return allRepos, nil
-		}
+	user := gitLabUser{}
+	if err := json.Unmarshal(data, &user); err != nil {
+		return "", fmt.Errorf("error %w parsing gitlab user response", err)
+	}
+	if user.ID == 0 {
+		return "", fmt.Errorf("gitlab user response from %s did not contain an id", userURL)
 	}
+	return buildStarredRepoUrl(c.forgeType, c.apiURL, fmt.Sprint(user.ID)), nil
 }
 
-func (c GitForgeClient) repoHasReleaseFeed(
+// Checks that the releases or tags feed of the repo exists and has entries
+func (c GitForgeClient) checkFeed(
 	ctx context.Context,
 	repo GitRepo,
+	kind FeedKind,
 ) GitRepoResult {
 
-	result := GitRepoResult{RepoName: repo.Name}
-
-	logger := c.logger.With("repo", repo.Name, "feed", repo.FeedURL)
-	logger.Debug("Checking if repo has release feed")
-	data, _, err := common.DoAPIRequest(
-		ctx,
-		http.MethodGet,
-		repo.FeedURL.String(),
-		nil,
-		c.headers,
-		c.client,
-	)
+	result := GitRepoResult{
+		RepoID:      repo.ID,
+		RepoName:    repo.Name,
+		Owner:       repo.OwnerName(),
+		FullName:    repo.FullName,
+		Description: repo.Description,
+		Archived:    repo.Archived,
+		Private:     repo.Private,
+		StarList:    repo.StarList,
+		Sources:     repo.Sources,
+		FeedKind:    kind,
+	}
+	feedURL := repo.FeedURL
+	if kind == FeedKindTags {
+		feedURL = repo.TagsFeedURL
+	}
+
+	logger := c.logger.With("repo", repo.Name, "feed", feedURL)
+	logger.Debug("Checking if repo has feed", "kind", kind)
+	data, err := c.fetchFeed(ctx, repo, feedURL)
 	if err != nil {
 		result.Err = err
 		return result
@@ -151,27 +200,128 @@ func (c GitForgeClient) repoHasReleaseFeed(
 	if len(relFeed.Entries) >= 1 {
 		logger.Debug("Repo feed is valid")
 		result.RelFeedHasEntries = true
+		result.LatestRelease = relFeed.NewestEntryTime()
 		return result
 	}
 
 	return result
 }
 
-func (c GitForgeClient) parseNextPageURL(respHeaders http.Header) string {
+func (c GitForgeClient) withFeedURLs(repo GitRepo) GitRepo {
+	repoURL := c.repoWebURL(repo)
+	repo.FeedURL = buildReleaseFeedURL(c.forgeType, repoURL)
+	repo.TagsFeedURL = buildTagsFeedURL(c.forgeType, repoURL)
+	return repo
+}
+
+// If a web URL was configured we build the repo URL from it rather than trusting the html_url
+// from the API which can point at the wrong host or scheme on some self-hosted setups.
+func (c GitForgeClient) repoWebURL(repo GitRepo) GitRepoURL {
+	if c.webURL == "" || repo.FullName == "" {
+		return repo.RepoURL
+	}
+	return GitRepoURL(fmt.Sprintf("%s/%s", c.webURL, repo.FullName))
+}
+
+// All of our requests to the forge go through here so that the rate limiter sees every response
+// and can hold back the other goroutines when we are close to being throttled.
+func (c GitForgeClient) doRequest(ctx context.Context, reqURL string) ([]byte, http.Header, error) {
+	if err := c.limiter.wait(ctx); err != nil {
+		return nil, nil, err
+	}
+	headers, err := c.requestHeaders(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	data, respHeaders, err := common.DoAPIRequest(
+		ctx, http.MethodGet, reqURL, nil, headers, c.client, c.retry,
+	)
+	c.limiter.observe(respHeaders, err)
+	return data, respHeaders, err
+}
+
+// Installation tokens of a GitHub App expire so we set the Authorization header for each request
+// instead of once in buildHeaders
+func (c GitForgeClient) requestHeaders(ctx context.Context) (http.Header, error) {
+	if c.appAuth == nil {
+		return c.headers, nil
+	}
+	token, err := c.appAuth.Token(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("error %w authenticating as github app", err)
+	}
+	headers := c.headers.Clone()
+	headers.Set("Authorization", fmt.Sprintf("Bearer %s", token))
+	return headers, nil
+}
+
+func (c GitForgeClient) parseNextPageURL(currentURL string, respHeaders http.Header) string {
 	linkHeader := respHeaders.Get("Link")
-	if linkHeader == "" {
+	if linkHeader != "" {
+		c.logger.Debug("linkHeader found", "linkHeader", linkHeader)
+		links := strings.SplitSeq(linkHeader, ",")
+		for link := range links {
+			matches := nextPagePattern.FindStringSubmatch(link)
+			if len(matches) == 2 {
+				return matches[1]
+			}
+		}
 		return ""
 	}
 
-	c.logger.Debug("linkHeader found", "linkHeader", linkHeader)
-	links := strings.SplitSeq(linkHeader, ",")
-	for link := range links {
-		matches := nextPagePattern.FindStringSubmatch(link)
-		if len(matches) == 2 {
-			return matches[1]
+	// GitLab leaves out the Link header for some large collections but it will always tell us
+	// the next page number with X-Next-Page. It is empty on the last page.
+	nextPage := respHeaders.Get("X-Next-Page")
+	if nextPage == "" {
+		return ""
+	}
+	c.logger.Debug("X-Next-Page found", "nextPage", nextPage)
+	nextURL, err := url.Parse(currentURL)
+	if err != nil {
+		return ""
+	}
+	query := nextURL.Query()
+	query.Set("page", nextPage)
+	nextURL.RawQuery = query.Encode()
+	return nextURL.String()
+}
+
+// Each forge returns its own flavour of JSON for a repo so we decode into the matching type
+func parseStarredRepos(forgeType string, data []byte) ([]GitRepo, error) {
+	switch forgeType {
+	case GitLabForgeType:
+		return parseGitLabProjects(data)
+	case GitHubForgeType:
+		repos := make([]GitRepo, 0)
+		if err := json.Unmarshal(data, &repos); err != nil {
+			return nil, err
 		}
+		return repos, nil
+	}
+
+	forgejoRepos := make([]forgejoRepo, 0)
+	if err := json.Unmarshal(data, &forgejoRepos); err != nil {
+		return nil, err
 	}
-	return ""
+	repos := make([]GitRepo, len(forgejoRepos))
+	for ix, repo := range forgejoRepos {
+		repos[ix] = repo.GitRepo
+		repos[ix].Stars = repo.StarsCount
+	}
+	return repos, nil
+}
+
+func parseGitLabProjects(data []byte) ([]GitRepo, error) {
+
+	projects := make([]gitLabProject, 0)
+	if err := json.Unmarshal(data, &projects); err != nil {
+		return nil, err
+	}
+	repos := make([]GitRepo, len(projects))
+	for ix, project := range projects {
+		repos[ix] = project.toGitRepo()
+	}
+	return repos, nil
 }
 
 func buildHeaders(forgeType, token string) http.Header {
@@ -179,16 +329,62 @@ func buildHeaders(forgeType, token string) http.Header {
 	headers.Set("Content-Type", "application/json")
 	headers.Set("Accept", "application/json")
 	headers.Set("User-Agent", "github.com/atomicmeganerd/starfeed")
-	headers.Set("Authorization", fmt.Sprintf("Bearer %s", token))
-	if forgeType == GitHubForgeType {
+	switch forgeType {
+	case GitHubForgeType:
+		headers.Set("Authorization", fmt.Sprintf("Bearer %s", token))
 		headers.Set("X-GitHub-Api-Version", "2022-11-28")
+	case GitLabForgeType:
+		headers.Set("PRIVATE-TOKEN", token)
+	default:
+		headers.Set("Authorization", fmt.Sprintf("Bearer %s", token))
 	}
 	return headers
 }
 
-func buildStarredRepoUrl(forgeType, fqdn string) string {
-	if forgeType == GitHubForgeType {
-		return fmt.Sprintf("https://api.%s/user/starred?per_page=100", fqdn)
+func buildAPIURL(forgeType, fqdn string) string {
+	switch forgeType {
+	case GitHubForgeType:
+		return fmt.Sprintf("https://api.%s", fqdn)
+	case GitLabForgeType:
+		return fmt.Sprintf("https://%s/api/v4", fqdn)
+	default:
+		return fmt.Sprintf("https://%s/api/v1", fqdn)
+	}
+}
+
+// Without a user GitHub and Forgejo list the stars of the token owner. GitLab always needs the
+// user as it does not have an endpoint for the token owner's stars.
+func buildStarredRepoUrl(forgeType, apiURL, user string) string {
+	user = url.PathEscape(user)
+	switch forgeType {
+	case GitHubForgeType:
+		if user != "" {
+			return fmt.Sprintf("%s/users/%s/starred?per_page=100", apiURL, user)
+		}
+		return fmt.Sprintf("%s/user/starred?per_page=100", apiURL)
+	case GitLabForgeType:
+		return fmt.Sprintf("%s/users/%s/starred_projects?per_page=100", apiURL, user)
+	default:
+		if user != "" {
+			return fmt.Sprintf("%s/users/%s/starred?limit=100", apiURL, user)
+		}
+		return fmt.Sprintf("%s/user/starred?limit=100", apiURL)
+	}
+}
+
+// GitHub and Forgejo serve the release feed right under the repo but GitLab puts it under /-/
+func buildReleaseFeedURL(forgeType string, repoURL GitRepoURL) common.FeedURL {
+	if forgeType == GitLabForgeType {
+		return common.FeedURL(fmt.Sprintf("%s/-/releases.atom", repoURL))
+	}
+	return common.FeedURL(fmt.Sprintf("%s/releases.atom", repoURL))
+}
+
+// GitHub and Forgejo serve the tags feed next to the release feed. GitLab only has it as a format
+// of the tags page.
+func buildTagsFeedURL(forgeType string, repoURL GitRepoURL) common.FeedURL {
+	if forgeType == GitLabForgeType {
+		return common.FeedURL(fmt.Sprintf("%s/-/tags?format=atom", repoURL))
 	}
-	return fmt.Sprintf("https://%s/api/v1/user/starred?limit=100", fqdn)
+	return common.FeedURL(fmt.Sprintf("%s/tags.atom", repoURL))
 }
@@ -2,10 +2,14 @@ package gitforge
 
 import (
 	"context"
+	"fmt"
 	"io"
 	"net/http"
+	"slices"
+	"strconv"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/atomicmeganerd/starfeed/common"
 	"github.com/atomicmeganerd/starfeed/testutils"
@@ -13,6 +17,7 @@ import (
 
 var (
 	repo1 = GitRepo{
+		ID:      101,
 		Name:    "repo1",
 		RepoURL: "https://github.com/user/repo1",
 		FeedURL: "https://github.com/user/repo1/releases.atom",
@@ -40,6 +45,7 @@ func TestLoadFeeds(t *testing.T) {
 						Body: io.NopCloser(
 							strings.NewReader(`[
 								{
+									"id": 101,
 									"name": "` + repo1.Name.String() + `",
 									"html_url": "` + repo1.RepoURL.String() + `"
 								}
@@ -67,11 +73,66 @@ func TestLoadFeeds(t *testing.T) {
 			},
 			expectedFeeds: FeedResultMap{
 				repo1.FeedURL: GitRepoResult{
+					RepoID:            repo1.ID,
 					RepoName:          repo1.Name,
+					FeedKind:          FeedKindReleases,
+					Sources:           []RepoSource{SourceStarred},
 					RelFeedHasEntries: true,
 				},
 			},
 		},
+		{
+			name: "Archived flag, repo metadata and release time are captured",
+			mocks: []testutils.MockRoutedResponse{
+				{
+					UrlPattern: `api\.github\.com/user/starred`,
+					Response: http.Response{
+						Body: io.NopCloser(
+							strings.NewReader(`[
+								{
+									"name": "` + repo1.Name.String() + `",
+									"full_name": "user/` + repo1.Name.String() + `",
+									"owner": {"login": "user"},
+									"description": "The first repo",
+									"html_url": "` + repo1.RepoURL.String() + `",
+									"archived": true
+								}
+							]`,
+							),
+						),
+						Status:     testutils.StatusOKString,
+						StatusCode: http.StatusOK,
+					},
+				},
+				{
+					UrlPattern: repo1.Name.String() + `/releases\.atom`,
+					Response: http.Response{
+						StatusCode: http.StatusOK,
+						Body: io.NopCloser(strings.NewReader(`
+							<feed xmlns="http://www.w3.org/2005/Atom">
+								<entry>
+									<title>Release 1</title>
+									<updated>2026-01-02T03:04:05Z</updated>
+								</entry>
+							</feed>
+						`)),
+					},
+				},
+			},
+			expectedFeeds: FeedResultMap{
+				repo1.FeedURL: GitRepoResult{
+					RepoName:          repo1.Name,
+					FeedKind:          FeedKindReleases,
+					Sources:           []RepoSource{SourceStarred},
+					Owner:             "user",
+					FullName:          "user/repo1",
+					Description:       "The first repo",
+					RelFeedHasEntries: true,
+					Archived:          true,
+					LatestRelease:     time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC),
+				},
+			},
+		},
 		{
 			name: "Multiple repos with mixed feed states",
 			mocks: []testutils.MockRoutedResponse{
@@ -121,10 +182,14 @@ func TestLoadFeeds(t *testing.T) {
 			expectedFeeds: FeedResultMap{
 				repo1.FeedURL: GitRepoResult{
 					RepoName:          repo1.Name,
+					FeedKind:          FeedKindReleases,
+					Sources:           []RepoSource{SourceStarred},
 					RelFeedHasEntries: true,
 				},
 				repo2.FeedURL: GitRepoResult{
 					RepoName: repo2.Name,
+					FeedKind: FeedKindReleases,
+					Sources:  []RepoSource{SourceStarred},
 				},
 			},
 		},
@@ -237,10 +302,14 @@ func TestLoadFeeds(t *testing.T) {
 			expectedFeeds: FeedResultMap{
 				repo1.FeedURL: GitRepoResult{
 					RepoName:          repo1.Name,
+					FeedKind:          FeedKindReleases,
+					Sources:           []RepoSource{SourceStarred},
 					RelFeedHasEntries: true,
 				},
 				repo2.FeedURL: GitRepoResult{
 					RepoName:          repo2.Name,
+					FeedKind:          FeedKindReleases,
+					Sources:           []RepoSource{SourceStarred},
 					RelFeedHasEntries: true,
 				},
 			},
@@ -273,10 +342,44 @@ func TestLoadFeeds(t *testing.T) {
 			expectedFeeds: FeedResultMap{
 				repo1.FeedURL: GitRepoResult{
 					RepoName: repo1.Name,
+					FeedKind: FeedKindReleases,
+					Sources:  []RepoSource{SourceStarred},
 					Err:      common.HTTPError{StatusCode: http.StatusNotFound},
 				},
 			},
 		},
+		{
+			name: "Exhausted rate limit fails release feeds without making them stale",
+			mocks: []testutils.MockRoutedResponse{
+				{
+					UrlPattern: `api\.github\.com/user/starred`,
+					Response: http.Response{
+						Header: http.Header{
+							"X-Ratelimit-Remaining": {"0"},
+							"X-Ratelimit-Reset": {
+								strconv.FormatInt(time.Now().Add(time.Hour).Unix(), 10),
+							},
+						},
+						Body: io.NopCloser(strings.NewReader(`[
+							{
+								"name": "` + repo1.Name.String() + `",
+								"html_url": "` + repo1.RepoURL.String() + `"
+							}
+						]`)),
+						Status:     testutils.StatusOKString,
+						StatusCode: http.StatusOK,
+					},
+				},
+			},
+			expectedFeeds: FeedResultMap{
+				repo1.FeedURL: GitRepoResult{
+					RepoName: repo1.Name,
+					FeedKind: FeedKindReleases,
+					Sources:  []RepoSource{SourceStarred},
+					Err:      RateLimitError{},
+				},
+			},
+		},
 		{
 			name: "Repo with transient 500 on release feed",
 			mocks: []testutils.MockRoutedResponse{
@@ -305,6 +408,8 @@ func TestLoadFeeds(t *testing.T) {
 			expectedFeeds: FeedResultMap{
 				repo1.FeedURL: GitRepoResult{
 					RepoName: repo1.Name,
+					FeedKind: FeedKindReleases,
+					Sources:  []RepoSource{SourceStarred},
 					Err:      common.HTTPError{StatusCode: http.StatusInternalServerError},
 				},
 			},
@@ -320,11 +425,11 @@ func TestLoadFeeds(t *testing.T) {
 			mockClient := &http.Client{Transport: &mockTransport}
 
 			gh := NewGitForgeClient(
-				GitHubForgeType,
-				testutils.GitHubFqdn,
+				GitForgeOptions{Type: GitHubForgeType, Fqdn: testutils.GitHubFqdn},
 				testutils.GitHubToken,
 				testutils.TestLogger(t),
 				mockClient,
+				common.RetryPolicy{},
 			)
 
 			actual, err := gh.LoadFeeds(ctx)
@@ -364,3 +469,898 @@ func TestLoadFeeds(t *testing.T) {
 		})
 	}
 }
+
+func TestLoadFeedsGitLab(t *testing.T) {
+	project1 := GitRepo{
+		ID:      7,
+		Name:    "project1",
+		RepoURL: "https://gitlab.com/group/project1",
+		FeedURL: "https://gitlab.com/group/project1/-/releases.atom",
+	}
+	project2 := GitRepo{
+		Name:    "project2",
+		RepoURL: "https://gitlab.com/group/project2",
+		FeedURL: "https://gitlab.com/group/project2/-/releases.atom",
+	}
+
+	testCases := []struct {
+		name          string
+		mocks         []testutils.MockRoutedResponse
+		expectedFeeds FeedResultMap
+	}{
+		{
+			name: "Single starred project with valid feed",
+			mocks: []testutils.MockRoutedResponse{
+				{
+					UrlPattern: `gitlab\.com/api/v4/user$`,
+					Response: http.Response{
+						Body:       io.NopCloser(strings.NewReader(`{"id": 42}`)),
+						Status:     testutils.StatusOKString,
+						StatusCode: http.StatusOK,
+					},
+				},
+				{
+					UrlPattern: `gitlab\.com/api/v4/users/42/starred_projects`,
+					Response: http.Response{
+						Body: io.NopCloser(strings.NewReader(`[
+							{
+								"id": 7,
+								"name": "` + project1.Name.String() + `",
+								"path_with_namespace": "group/project1",
+								"namespace": {"full_path": "group"},
+								"description": "A GitLab project",
+								"web_url": "` + project1.RepoURL.String() + `"
+							}
+						]`)),
+						Status:     testutils.StatusOKString,
+						StatusCode: http.StatusOK,
+					},
+				},
+				{
+					UrlPattern: project1.Name.String() + `/-/releases\.atom`,
+					Response: http.Response{
+						StatusCode: http.StatusOK,
+						Body: io.NopCloser(strings.NewReader(`
+							<feed xmlns="http://www.w3.org/2005/Atom">
+								<entry>
+									<title>v1.0.0</title>
+								</entry>
+							</feed>
+						`)),
+					},
+				},
+			},
+			expectedFeeds: FeedResultMap{
+				project1.FeedURL: GitRepoResult{
+					RepoID:            project1.ID,
+					RepoName:          project1.Name,
+					FeedKind:          FeedKindReleases,
+					Sources:           []RepoSource{SourceStarred},
+					Owner:             "group",
+					FullName:          "group/project1",
+					Description:       "A GitLab project",
+					RelFeedHasEntries: true,
+				},
+			},
+		},
+		{
+			name: "Pagination with X-Next-Page",
+			mocks: []testutils.MockRoutedResponse{
+				{
+					UrlPattern: `gitlab\.com/api/v4/user$`,
+					Response: http.Response{
+						Body:       io.NopCloser(strings.NewReader(`{"id": 42}`)),
+						Status:     testutils.StatusOKString,
+						StatusCode: http.StatusOK,
+					},
+				},
+				{
+					UrlPattern: `starred_projects\?page=2`,
+					Response: http.Response{
+						Header: http.Header{"X-Next-Page": []string{""}},
+						Body: io.NopCloser(strings.NewReader(`[
+							{
+								"name": "` + project2.Name.String() + `",
+								"web_url": "` + project2.RepoURL.String() + `"
+							}
+						]`)),
+						Status:     testutils.StatusOKString,
+						StatusCode: http.StatusOK,
+					},
+				},
+				{
+					UrlPattern: `starred_projects\?per_page=100$`,
+					Response: http.Response{
+						Header: http.Header{"X-Next-Page": []string{"2"}},
+						Body: io.NopCloser(strings.NewReader(`[
+							{
+								"name": "` + project1.Name.String() + `",
+								"web_url": "` + project1.RepoURL.String() + `"
+							}
+						]`)),
+						Status:     testutils.StatusOKString,
+						StatusCode: http.StatusOK,
+					},
+				},
+				{
+					UrlPattern: project1.Name.String() + `/-/releases\.atom`,
+					Response: http.Response{
+						StatusCode: http.StatusOK,
+						Body: io.NopCloser(strings.NewReader(`
+							<feed xmlns="http://www.w3.org/2005/Atom">
+								<entry>
+									<title>v1.0.0</title>
+								</entry>
+							</feed>
+						`)),
+					},
+				},
+				{
+					UrlPattern: project2.Name.String() + `/-/releases\.atom`,
+					Response: http.Response{
+						StatusCode: http.StatusOK,
+						Body: io.NopCloser(strings.NewReader(`
+							<feed xmlns="http://www.w3.org/2005/Atom">
+							</feed>
+						`)),
+					},
+				},
+			},
+			expectedFeeds: FeedResultMap{
+				project1.FeedURL: GitRepoResult{
+					RepoName:          project1.Name,
+					FeedKind:          FeedKindReleases,
+					Sources:           []RepoSource{SourceStarred},
+					RelFeedHasEntries: true,
+				},
+				project2.FeedURL: GitRepoResult{
+					RepoName: project2.Name,
+					FeedKind: FeedKindReleases,
+					Sources:  []RepoSource{SourceStarred},
+				},
+			},
+		},
+		{
+			name: "Failed user lookup should trigger an error",
+			mocks: []testutils.MockRoutedResponse{
+				{
+					UrlPattern: `gitlab\.com/api/v4/user$`,
+					Response: http.Response{
+						Body:       io.NopCloser(strings.NewReader(`{"message": "401"}`)),
+						Status:     testutils.StatusUnauthorizedString,
+						StatusCode: http.StatusUnauthorized,
+					},
+				},
+			},
+			expectedFeeds: nil,
+		},
+		{
+			name: "User lookup without an id should trigger an error",
+			mocks: []testutils.MockRoutedResponse{
+				{
+					UrlPattern: `gitlab\.com/api/v4/user$`,
+					Response: http.Response{
+						Body:       io.NopCloser(strings.NewReader(`{}`)),
+						Status:     testutils.StatusOKString,
+						StatusCode: http.StatusOK,
+					},
+				},
+			},
+			expectedFeeds: nil,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			ctx := context.Background()
+
+			mockTransport := testutils.NewMockRoutedResponseRoundTripper(tc.mocks)
+			mockClient := &http.Client{Transport: &mockTransport}
+
+			gl := NewGitForgeClient(
+				GitForgeOptions{Type: GitLabForgeType, Fqdn: testutils.GitLabFqdn},
+				testutils.GitLabToken,
+				testutils.TestLogger(t),
+				mockClient,
+				common.RetryPolicy{},
+			)
+
+			actual, err := gl.LoadFeeds(ctx)
+
+			if tc.expectedFeeds == nil {
+				if err == nil {
+					t.Fatalf("Expected an error, got none")
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+
+			if len(tc.expectedFeeds) != len(actual) {
+				t.Fatalf("Expected %d results, got %d", len(tc.expectedFeeds), len(actual))
+			}
+
+			for feedURL, expectedResult := range tc.expectedFeeds {
+				actualResult, exists := actual[feedURL]
+				if !exists {
+					t.Errorf("Expected feed %s not found in results", feedURL)
+					continue
+				}
+				if !expectedResult.Equal(actualResult) {
+					t.Errorf(
+						"Feed %s: expected %+v, got %+v", feedURL, expectedResult, actualResult,
+					)
+				}
+			}
+		})
+	}
+}
+
+func TestBuildHeaders(t *testing.T) {
+	testCases := []struct {
+		name           string
+		forgeType      string
+		expectedHeader string
+		expectedValue  string
+		missingHeader  string
+	}{
+		{
+			name:           "GitHub uses a bearer token",
+			forgeType:      GitHubForgeType,
+			expectedHeader: "Authorization",
+			expectedValue:  "Bearer " + testutils.GitHubToken,
+			missingHeader:  "PRIVATE-TOKEN",
+		},
+		{
+			name:           "Forgejo uses a bearer token",
+			forgeType:      ForgejoForgeType,
+			expectedHeader: "Authorization",
+			expectedValue:  "Bearer " + testutils.GitHubToken,
+			missingHeader:  "PRIVATE-TOKEN",
+		},
+		{
+			name:           "GitLab uses PRIVATE-TOKEN",
+			forgeType:      GitLabForgeType,
+			expectedHeader: "PRIVATE-TOKEN",
+			expectedValue:  testutils.GitHubToken,
+			missingHeader:  "Authorization",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			headers := buildHeaders(tc.forgeType, testutils.GitHubToken)
+			if got := headers.Get(tc.expectedHeader); got != tc.expectedValue {
+				t.Errorf("header %s = %q, want %q", tc.expectedHeader, got, tc.expectedValue)
+			}
+			if got := headers.Get(tc.missingHeader); got != "" {
+				t.Errorf("header %s should not be set but was %q", tc.missingHeader, got)
+			}
+		})
+	}
+}
+
+func TestLoadFeedsCustomURLs(t *testing.T) {
+	validFeed := `<feed xmlns="http://www.w3.org/2005/Atom"><entry><title>v1</title></entry></feed>`
+	testCases := []struct {
+		name            string
+		opts            GitForgeOptions
+		starredPattern  string
+		starredBody     string
+		feedPattern     string
+		expectedFeedURL common.FeedURL
+	}{
+		{
+			name: "GitHub Enterprise Server API URL",
+			opts: GitForgeOptions{
+				Type:   GitHubForgeType,
+				APIURL: "https://ghe.corp/api/v3/",
+			},
+			starredPattern: `^https://ghe\.corp/api/v3/user/starred\?per_page=100$`,
+			starredBody: `[{
+				"name": "tool",
+				"full_name": "org/tool",
+				"html_url": "https://ghe.corp/org/tool"
+			}]`,
+			feedPattern:     `^https://ghe\.corp/org/tool/releases\.atom$`,
+			expectedFeedURL: "https://ghe.corp/org/tool/releases.atom",
+		},
+		{
+			name: "Plain HTTP Forgejo with web URL override",
+			opts: GitForgeOptions{
+				Type:   ForgejoForgeType,
+				APIURL: "http://forgejo.lab:3000/api/v1",
+				WebURL: "http://forgejo.lab:3000/",
+			},
+			starredPattern: `^http://forgejo\.lab:3000/api/v1/user/starred\?limit=100$`,
+			starredBody: `[{
+				"name": "tool",
+				"full_name": "org/tool",
+				"html_url": "https://localhost:3000/org/tool"
+			}]`,
+			feedPattern:     `^http://forgejo\.lab:3000/org/tool/releases\.atom$`,
+			expectedFeedURL: "http://forgejo.lab:3000/org/tool/releases.atom",
+		},
+		{
+			name: "Self-hosted GitLab with web URL override",
+			opts: GitForgeOptions{
+				Type:   GitLabForgeType,
+				APIURL: "http://gitlab.lab/api/v4",
+				WebURL: "http://gitlab.lab",
+			},
+			starredPattern: `^http://gitlab\.lab/api/v4/users/7/starred_projects`,
+			starredBody: `[{
+				"name": "tool",
+				"path_with_namespace": "group/sub/tool",
+				"web_url": "https://gitlab.example.com/group/sub/tool"
+			}]`,
+			feedPattern:     `^http://gitlab\.lab/group/sub/tool/-/releases\.atom$`,
+			expectedFeedURL: "http://gitlab.lab/group/sub/tool/-/releases.atom",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			mocks := []testutils.MockRoutedResponse{
+				{
+					UrlPattern: `/api/v4/user$`,
+					Response: http.Response{
+						Body:       io.NopCloser(strings.NewReader(`{"id": 7}`)),
+						StatusCode: http.StatusOK,
+					},
+				},
+				{
+					UrlPattern: tc.starredPattern,
+					Response: http.Response{
+						Body:       io.NopCloser(strings.NewReader(tc.starredBody)),
+						StatusCode: http.StatusOK,
+					},
+				},
+				{
+					UrlPattern: tc.feedPattern,
+					Response: http.Response{
+						Body:       io.NopCloser(strings.NewReader(validFeed)),
+						StatusCode: http.StatusOK,
+					},
+				},
+			}
+			mockTransport := testutils.NewMockRoutedResponseRoundTripper(mocks)
+			mockClient := &http.Client{Transport: &mockTransport}
+
+			forge := NewGitForgeClient(
+				tc.opts,
+				testutils.GitHubToken,
+				testutils.TestLogger(t),
+				mockClient,
+				common.RetryPolicy{},
+			)
+
+			actual, err := forge.LoadFeeds(context.Background())
+			if err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+			result, exists := actual[tc.expectedFeedURL]
+			if !exists {
+				t.Fatalf("Expected feed %s in results but got %v", tc.expectedFeedURL, actual)
+			}
+			if !result.IsOK() {
+				t.Errorf("Expected feed %s to be ok but got %+v", tc.expectedFeedURL, result)
+			}
+		})
+	}
+}
+
+func TestLoadFeedsFeedKind(t *testing.T) {
+	const (
+		releasesURL common.FeedURL = "https://github.com/org/tool/releases.atom"
+		tagsURL     common.FeedURL = "https://github.com/org/tool/tags.atom"
+	)
+	validFeed := `<feed xmlns="http://www.w3.org/2005/Atom"><entry><title>v1</title></entry></feed>`
+	emptyFeed := `<feed xmlns="http://www.w3.org/2005/Atom"></feed>`
+	okResult := func(kind FeedKind) GitRepoResult {
+		return GitRepoResult{
+			RepoID:            9,
+			RepoName:          "tool",
+			Owner:             "org",
+			FullName:          "org/tool",
+			RelFeedHasEntries: true,
+			FeedKind:          kind,
+			Sources:           []RepoSource{SourceStarred},
+		}
+	}
+
+	testCases := []struct {
+		name           string
+		opts           GitForgeOptions
+		releasesStatus int
+		releasesBody   string
+		tagsBody       string
+		expectedFeeds  FeedResultMap
+	}{
+		{
+			name:           "Releases by default",
+			opts:           GitForgeOptions{},
+			releasesStatus: http.StatusOK,
+			releasesBody:   validFeed,
+			tagsBody:       validFeed,
+			expectedFeeds:  FeedResultMap{releasesURL: okResult(FeedKindReleases)},
+		},
+		{
+			name:           "Tags only",
+			opts:           GitForgeOptions{FeedKind: FeedKindTags},
+			releasesStatus: http.StatusOK,
+			releasesBody:   validFeed,
+			tagsBody:       validFeed,
+			expectedFeeds:  FeedResultMap{tagsURL: okResult(FeedKindTags)},
+		},
+		{
+			name:           "Fallback keeps a releases feed with entries",
+			opts:           GitForgeOptions{FeedKind: FeedKindReleasesOrTags},
+			releasesStatus: http.StatusOK,
+			releasesBody:   validFeed,
+			tagsBody:       validFeed,
+			expectedFeeds:  FeedResultMap{releasesURL: okResult(FeedKindReleases)},
+		},
+		{
+			name:           "Fallback to tags when the releases feed is empty",
+			opts:           GitForgeOptions{FeedKind: FeedKindReleasesOrTags},
+			releasesStatus: http.StatusOK,
+			releasesBody:   emptyFeed,
+			tagsBody:       validFeed,
+			expectedFeeds:  FeedResultMap{tagsURL: okResult(FeedKindTags)},
+		},
+		{
+			name:           "Fallback reports a failing releases feed under both URLs",
+			opts:           GitForgeOptions{FeedKind: FeedKindReleasesOrTags},
+			releasesStatus: http.StatusInternalServerError,
+			releasesBody:   "",
+			tagsBody:       validFeed,
+			expectedFeeds: FeedResultMap{
+				releasesURL: {
+					RepoID:   9,
+					RepoName: "tool",
+					Owner:    "org",
+					FullName: "org/tool",
+					FeedKind: FeedKindReleases,
+					Sources:  []RepoSource{SourceStarred},
+					Err:      common.HTTPError{},
+				},
+				tagsURL: {
+					RepoName: "tool",
+					Owner:    "org",
+					FullName: "org/tool",
+					FeedKind: FeedKindTags,
+					Sources:  []RepoSource{SourceStarred},
+					Err:      common.HTTPError{},
+				},
+			},
+		},
+		{
+			name: "Per repo override wins and ignores case",
+			opts: GitForgeOptions{
+				FeedKind:          FeedKindReleases,
+				Sources:           []RepoSource{SourceStarred},
+				FeedKindOverrides: map[string]FeedKind{"ORG/Tool": FeedKindTags},
+			},
+			releasesStatus: http.StatusOK,
+			releasesBody:   validFeed,
+			tagsBody:       validFeed,
+			expectedFeeds:  FeedResultMap{tagsURL: okResult(FeedKindTags)},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			mocks := []testutils.MockRoutedResponse{
+				{
+					UrlPattern: `api\.github\.com/user/starred`,
+					Response: http.Response{
+						Body: io.NopCloser(strings.NewReader(`[{
+							"id": 9,
+							"name": "tool",
+							"full_name": "org/tool",
+							"html_url": "https://github.com/org/tool"
+						}]`)),
+						StatusCode: http.StatusOK,
+					},
+				},
+				{
+					UrlPattern: `org/tool/releases\.atom$`,
+					Response: http.Response{
+						Body:       io.NopCloser(strings.NewReader(tc.releasesBody)),
+						StatusCode: tc.releasesStatus,
+					},
+				},
+				{
+					UrlPattern: `org/tool/tags\.atom$`,
+					Response: http.Response{
+						Body:       io.NopCloser(strings.NewReader(tc.tagsBody)),
+						StatusCode: http.StatusOK,
+					},
+				},
+			}
+			mockTransport := testutils.NewMockRoutedResponseRoundTripper(mocks)
+			mockClient := &http.Client{Transport: &mockTransport}
+
+			opts := tc.opts
+			opts.Type = GitHubForgeType
+			opts.Fqdn = testutils.GitHubFqdn
+			forge := NewGitForgeClient(
+				opts,
+				testutils.GitHubToken,
+				testutils.TestLogger(t),
+				mockClient,
+				common.RetryPolicy{},
+			)
+
+			actual, err := forge.LoadFeeds(context.Background())
+			if err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+			if len(tc.expectedFeeds) != len(actual) {
+				t.Fatalf("Expected %d results, got %v", len(tc.expectedFeeds), actual)
+			}
+			for feedURL, expectedResult := range tc.expectedFeeds {
+				if !expectedResult.Equal(actual[feedURL]) {
+					t.Errorf(
+						"Feed %s: expected %+v, got %+v", feedURL, expectedResult, actual[feedURL],
+					)
+				}
+			}
+		})
+	}
+}
+
+func TestBuildTagsFeedURL(t *testing.T) {
+	testCases := []struct {
+		forgeType string
+		repoURL   GitRepoURL
+		expected  common.FeedURL
+	}{
+		{GitHubForgeType, "https://github.com/org/tool", "https://github.com/org/tool/tags.atom"},
+		{
+			ForgejoForgeType,
+			"https://codeberg.org/org/tool",
+			"https://codeberg.org/org/tool/tags.atom",
+		},
+		{
+			GitLabForgeType,
+			"https://gitlab.com/group/tool",
+			"https://gitlab.com/group/tool/-/tags?format=atom",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.forgeType, func(t *testing.T) {
+			t.Parallel()
+			if got := buildTagsFeedURL(tc.forgeType, tc.repoURL); got != tc.expected {
+				t.Errorf("buildTagsFeedURL() = %s, want %s", got, tc.expected)
+			}
+		})
+	}
+}
+
+func TestBuildStarredRepoUrl(t *testing.T) {
+	testCases := []struct {
+		name      string
+		forgeType string
+		apiURL    string
+		user      string
+		expected  string
+	}{
+		{
+			name:      "GitHub token owner",
+			forgeType: GitHubForgeType,
+			apiURL:    "https://api.github.com",
+			expected:  "https://api.github.com/user/starred?per_page=100",
+		},
+		{
+			name:      "GitHub user",
+			forgeType: GitHubForgeType,
+			apiURL:    "https://api.github.com",
+			user:      "team-picks",
+			expected:  "https://api.github.com/users/team-picks/starred?per_page=100",
+		},
+		{
+			name:      "Forgejo token owner",
+			forgeType: ForgejoForgeType,
+			apiURL:    "https://codeberg.org/api/v1",
+			expected:  "https://codeberg.org/api/v1/user/starred?limit=100",
+		},
+		{
+			name:      "Forgejo user",
+			forgeType: ForgejoForgeType,
+			apiURL:    "https://codeberg.org/api/v1",
+			user:      "team-picks",
+			expected:  "https://codeberg.org/api/v1/users/team-picks/starred?limit=100",
+		},
+		{
+			name:      "GitLab user",
+			forgeType: GitLabForgeType,
+			apiURL:    "https://gitlab.com/api/v4",
+			user:      "team-picks",
+			expected:  "https://gitlab.com/api/v4/users/team-picks/starred_projects?per_page=100",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			if got := buildStarredRepoUrl(tc.forgeType, tc.apiURL, tc.user); got != tc.expected {
+				t.Errorf("buildStarredRepoUrl() = %s, want %s", got, tc.expected)
+			}
+		})
+	}
+}
+
+func TestLoadFeedsUsernames(t *testing.T) {
+	validFeed := `<feed xmlns="http://www.w3.org/2005/Atom"><entry><title>v1</title></entry></feed>`
+	starredBody := func(names ...string) string {
+		repos := make([]string, len(names))
+		for ix, name := range names {
+			repos[ix] = fmt.Sprintf(
+				`{"name": "%s", "full_name": "org/%s", "html_url": "https://github.com/org/%s"}`,
+				name, name, name,
+			)
+		}
+		return "[" + strings.Join(repos, ",") + "]"
+	}
+
+	// The team and alice both starred "shared" which must only be checked once
+	mocks := []testutils.MockRoutedResponse{
+		{
+			UrlPattern: `api\.github\.com/users/team-picks/starred`,
+			Response: http.Response{
+				Body:       io.NopCloser(strings.NewReader(starredBody("tool", "shared"))),
+				StatusCode: http.StatusOK,
+			},
+		},
+		{
+			UrlPattern: `api\.github\.com/users/alice/starred`,
+			Response: http.Response{
+				Body:       io.NopCloser(strings.NewReader(starredBody("shared", "lib"))),
+				StatusCode: http.StatusOK,
+			},
+		},
+	}
+	for _, name := range []string{"tool", "shared", "lib"} {
+		mocks = append(mocks, testutils.MockRoutedResponse{
+			UrlPattern: `org/` + name + `/releases\.atom$`,
+			Response: http.Response{
+				Body:       io.NopCloser(strings.NewReader(validFeed)),
+				StatusCode: http.StatusOK,
+			},
+			MaxMatches: 1,
+		})
+	}
+	mockTransport := testutils.NewMockRoutedResponseRoundTripper(mocks)
+	mockClient := &http.Client{Transport: &mockTransport}
+
+	forge := NewGitForgeClient(
+		GitForgeOptions{
+			Type:      GitHubForgeType,
+			Fqdn:      testutils.GitHubFqdn,
+			Usernames: []string{"team-picks", "alice"},
+		},
+		testutils.GitHubToken,
+		testutils.TestLogger(t),
+		mockClient,
+		common.RetryPolicy{},
+	)
+
+	actual, err := forge.LoadFeeds(context.Background())
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	for _, name := range []string{"tool", "shared", "lib"} {
+		feedURL := common.FeedURL(fmt.Sprintf("https://github.com/org/%s/releases.atom", name))
+		if result, ok := actual[feedURL]; !ok || !result.IsOK() {
+			t.Errorf("Expected a valid feed for %s, got %+v", feedURL, actual)
+		}
+	}
+	if len(actual) != 3 {
+		t.Errorf("Expected 3 feeds, got %d", len(actual))
+	}
+}
+
+func TestLoadFeedsSources(t *testing.T) {
+	validFeed := `<feed xmlns="http://www.w3.org/2005/Atom"><entry><title>v1</title></entry></feed>`
+	reposBody := func(names ...string) string {
+		repos := make([]string, len(names))
+		for ix, name := range names {
+			repos[ix] = fmt.Sprintf(
+				`{"name": "%s", "full_name": "org/%s", "html_url": "https://codeberg.org/org/%s"}`,
+				name, name, name,
+			)
+		}
+		return "[" + strings.Join(repos, ",") + "]"
+	}
+
+	testCases := []struct {
+		name            string
+		sources         []RepoSource
+		expectedSources map[string][]RepoSource
+	}{
+		{
+			name:    "Starred repos by default",
+			sources: nil,
+			expectedSources: map[string][]RepoSource{
+				"starred": {SourceStarred},
+				"both":    {SourceStarred},
+			},
+		},
+		{
+			name:    "Watched repos only",
+			sources: []RepoSource{SourceWatched},
+			expectedSources: map[string][]RepoSource{
+				"both":    {SourceWatched},
+				"watched": {SourceWatched},
+			},
+		},
+		{
+			name:    "Starred and watched repos are merged",
+			sources: []RepoSource{SourceStarred, SourceWatched},
+			expectedSources: map[string][]RepoSource{
+				"starred": {SourceStarred},
+				"both":    {SourceStarred, SourceWatched},
+				"watched": {SourceWatched},
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			mocks := []testutils.MockRoutedResponse{
+				{
+					UrlPattern: `codeberg\.org/api/v1/user/starred\?`,
+					Response: http.Response{
+						Body:       io.NopCloser(strings.NewReader(reposBody("starred", "both"))),
+						StatusCode: http.StatusOK,
+					},
+				},
+				{
+					UrlPattern: `codeberg\.org/api/v1/user/subscriptions\?`,
+					Response: http.Response{
+						Body:       io.NopCloser(strings.NewReader(reposBody("both", "watched"))),
+						StatusCode: http.StatusOK,
+					},
+				},
+			}
+			for _, name := range []string{"starred", "both", "watched"} {
+				mocks = append(mocks, testutils.MockRoutedResponse{
+					UrlPattern: `org/` + name + `/releases\.atom$`,
+					Response: http.Response{
+						Body:       io.NopCloser(strings.NewReader(validFeed)),
+						StatusCode: http.StatusOK,
+					},
+				})
+			}
+			mockTransport := testutils.NewMockRoutedResponseRoundTripper(mocks)
+			mockClient := &http.Client{Transport: &mockTransport}
+
+			forge := NewGitForgeClient(
+				GitForgeOptions{Type: ForgejoForgeType, Fqdn: "codeberg.org", Sources: tc.sources},
+				"token",
+				testutils.TestLogger(t),
+				mockClient,
+				common.RetryPolicy{},
+			)
+
+			actual, err := forge.LoadFeeds(context.Background())
+			if err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+			if len(actual) != len(tc.expectedSources) {
+				t.Fatalf("Expected %d feeds, got %v", len(tc.expectedSources), actual)
+			}
+			for name, expected := range tc.expectedSources {
+				feedURL := common.FeedURL(
+					fmt.Sprintf("https://codeberg.org/org/%s/releases.atom", name),
+				)
+				if sources := actual[feedURL].Sources; !slices.Equal(sources, expected) {
+					t.Errorf("Feed %s: expected sources %v, got %v", feedURL, expected, sources)
+				}
+			}
+		})
+	}
+}
+
+func TestBuildWatchedRepoURL(t *testing.T) {
+	testCases := []struct {
+		name      string
+		forgeType string
+		apiURL    string
+		user      string
+		expected  string
+	}{
+		{
+			name:      "GitHub token owner",
+			forgeType: GitHubForgeType,
+			apiURL:    "https://api.github.com",
+			expected:  "https://api.github.com/user/subscriptions?per_page=100",
+		},
+		{
+			name:      "GitHub user",
+			forgeType: GitHubForgeType,
+			apiURL:    "https://api.github.com",
+			user:      "alice",
+			expected:  "https://api.github.com/users/alice/subscriptions?per_page=100",
+		},
+		{
+			name:      "Forgejo token owner",
+			forgeType: ForgejoForgeType,
+			apiURL:    "https://codeberg.org/api/v1",
+			expected:  "https://codeberg.org/api/v1/user/subscriptions?limit=100",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			if got := buildWatchedRepoURL(tc.forgeType, tc.apiURL, tc.user); got != tc.expected {
+				t.Errorf("buildWatchedRepoURL() = %s, want %s", got, tc.expected)
+			}
+		})
+	}
+}
+
+func TestVerifyAccess(t *testing.T) {
+	firstPage := `[{"id": 1, "full_name": "org/one"}, {"id": 2, "full_name": "org/two"}]`
+
+	testCases := []struct {
+		name          string
+		statusCode    int
+		expectErr     bool
+		expectedRepos int
+	}{
+		{name: "Only the first page is counted", statusCode: http.StatusOK, expectedRepos: 2},
+		{name: "Bad tokens fail", statusCode: http.StatusUnauthorized, expectErr: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			header := http.Header{}
+			header.Set("Link", `<https://api.github.com/user/starred?page=2>; rel="next"`)
+			mockTransport := testutils.NewMockRoutedResponseRoundTripper(
+				[]testutils.MockRoutedResponse{{
+					UrlPattern: `/user/starred\?per_page=100$`,
+					Response: http.Response{
+						Body:       io.NopCloser(strings.NewReader(firstPage)),
+						StatusCode: tc.statusCode,
+						Header:     header,
+					},
+					MaxMatches: 1,
+				}},
+			)
+			forge := NewGitForgeClient(
+				GitForgeOptions{Type: GitHubForgeType, Fqdn: "github.com"},
+				testutils.GitHubToken,
+				testutils.TestLogger(t),
+				&http.Client{Transport: &mockTransport},
+				common.RetryPolicy{},
+			)
+
+			actual, err := forge.VerifyAccess(context.Background())
+			if tc.expectErr {
+				if err == nil {
+					t.Fatal("Expected an error, got nil")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+			if actual != tc.expectedRepos {
+				t.Errorf("Expected %d repos, got %d", tc.expectedRepos, actual)
+			}
+		})
+	}
+}
@@ -0,0 +1,159 @@
+package gitforge
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/atomicmeganerd/starfeed/common"
+)
+
+const (
+	// GitHub rejects app JWTs that are valid for more than 10 minutes
+	appJWTLifetime = 9 * time.Minute
+	// We backdate the JWT a little in case our clock is ahead of GitHub's
+	appJWTClockSkew = time.Minute
+	// Installation tokens last an hour. We mint a new one this long before that so that a token
+	// never expires in the middle of a request.
+	appTokenRefreshMargin = 5 * time.Minute
+)
+
+// GitHubApp identifies a GitHub App installation. PrivateKey is the PEM encoded private key of
+// the app as downloaded from GitHub.
+type GitHubApp struct {
+	AppID          int64
+	InstallationID int64
+	PrivateKey     []byte
+}
+
+// GitHubAppAuth authenticates as a GitHub App installation instead of with a personal access
+// token. It signs a JWT with the private key of the app, exchanges it for an installation token
+// and caches that token until shortly before it expires. It is safe to use from many goroutines.
+type GitHubAppAuth struct {
+	appID    int64
+	key      *rsa.PrivateKey
+	tokenURL string
+	client   *http.Client
+	retry    common.RetryPolicy
+	now      func() time.Time
+
+	mu        sync.Mutex
+	token     string
+	expiresAt time.Time
+}
+
+func NewGitHubAppAuth(
+	opts GitForgeOptions,
+	app GitHubApp,
+	client *http.Client,
+	retry common.RetryPolicy,
+) (*GitHubAppAuth, error) {
+	key, err := parsePrivateKey(app.PrivateKey)
+	if err != nil {
+		return nil, fmt.Errorf("could not parse github app private key: %w", err)
+	}
+	return &GitHubAppAuth{
+		appID: app.AppID,
+		key:   key,
+		tokenURL: fmt.Sprintf(
+			"%s/app/installations/%d/access_tokens", opts.apiURL(), app.InstallationID,
+		),
+		client: client,
+		retry:  retry,
+		now:    time.Now,
+	}, nil
+}
+
+// Returns the cached installation token or mints a new one if it is about to expire
+func (a *GitHubAppAuth) Token(ctx context.Context) (string, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.token != "" && a.now().Before(a.expiresAt.Add(-appTokenRefreshMargin)) {
+		return a.token, nil
+	}
+
+	jwt, err := a.signJWT()
+	if err != nil {
+		return "", err
+	}
+	headers := http.Header{}
+	headers.Set("Accept", "application/vnd.github+json")
+	headers.Set("Authorization", fmt.Sprintf("Bearer %s", jwt))
+	headers.Set("User-Agent", "github.com/atomicmeganerd/starfeed")
+	headers.Set("X-GitHub-Api-Version", "2022-11-28")
+	data, _, err := common.DoAPIRequest(
+		ctx, http.MethodPost, a.tokenURL, nil, headers, a.client, a.retry,
+	)
+	if err != nil {
+		return "", fmt.Errorf("error %w getting installation token from %s", err, a.tokenURL)
+	}
+
+	resp := struct {
+		Token     string    `json:"token"`
+		ExpiresAt time.Time `json:"expires_at"`
+	}{}
+	if err := json.Unmarshal(data, &resp); err != nil {
+		return "", fmt.Errorf("error %w parsing installation token response", err)
+	}
+	if resp.Token == "" {
+		return "", errors.New("installation token response did not contain a token")
+	}
+	a.token, a.expiresAt = resp.Token, resp.ExpiresAt
+	return a.token, nil
+}
+
+// Builds the RS256 JWT that the app authenticates with when it asks for an installation token
+func (a *GitHubAppAuth) signJWT() (string, error) {
+	now := a.now()
+	header, err := json.Marshal(map[string]string{"alg": "RS256", "typ": "JWT"})
+	if err != nil {
+		return "", err
+	}
+	claims, err := json.Marshal(map[string]any{
+		"iat": now.Add(-appJWTClockSkew).Unix(),
+		"exp": now.Add(appJWTLifetime).Unix(),
+		"iss": fmt.Sprint(a.appID),
+	})
+	if err != nil {
+		return "", err
+	}
+	signingInput := base64.RawURLEncoding.EncodeToString(header) + "." +
+		base64.RawURLEncoding.EncodeToString(claims)
+	digest := sha256.Sum256([]byte(signingInput))
+	signature, err := rsa.SignPKCS1v15(rand.Reader, a.key, crypto.SHA256, digest[:])
+	if err != nil {
+		return "", fmt.Errorf("could not sign github app jwt: %w", err)
+	}
+	return signingInput + "." + base64.RawURLEncoding.EncodeToString(signature), nil
+}
+
+// GitHub hands out PKCS#1 keys but a key converted to PKCS#8 works just as well
+func parsePrivateKey(data []byte) (*rsa.PrivateKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("no PEM data found")
+	}
+	if key, err := x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
+		return key, nil
+	}
+	parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+	key, ok := parsed.(*rsa.PrivateKey)
+	if !ok {
+		return nil, errors.New("private key is not an RSA key")
+	}
+	return key, nil
+}
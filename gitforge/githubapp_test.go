@@ -0,0 +1,215 @@
+package gitforge
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/atomicmeganerd/starfeed/common"
+	"github.com/atomicmeganerd/starfeed/testutils"
+)
+
+// A fake of the GitHub installation token endpoint. It checks the JWT against the public key of
+// the app and hands out numbered tokens that expire after the given lifetime.
+type fakeTokenEndpoint struct {
+	t         *testing.T
+	key       *rsa.PublicKey
+	appID     int64
+	lifetime  time.Duration
+	numTokens atomic.Int32
+}
+
+func (f *fakeTokenEndpoint) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost || r.URL.Path != "/app/installations/99/access_tokens" {
+		http.NotFound(w, r)
+		return
+	}
+	jwt := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if err := f.verifyJWT(jwt); err != nil {
+		f.t.Errorf("Invalid app JWT: %v", err)
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+	num := f.numTokens.Add(1)
+	w.WriteHeader(http.StatusCreated)
+	_, _ = fmt.Fprintf(
+		w, `{"token": "ghs_token%d", "expires_at": "%s"}`,
+		num, time.Now().Add(f.lifetime).UTC().Format(time.RFC3339),
+	)
+}
+
+func (f *fakeTokenEndpoint) verifyJWT(jwt string) error {
+	parts := strings.Split(jwt, ".")
+	if len(parts) != 3 {
+		return fmt.Errorf("expected 3 parts, got %d", len(parts))
+	}
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return err
+	}
+	digest := sha256.Sum256([]byte(parts[0] + "." + parts[1]))
+	if err := rsa.VerifyPKCS1v15(f.key, crypto.SHA256, digest[:], signature); err != nil {
+		return err
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return err
+	}
+	claims := struct {
+		Iat int64  `json:"iat"`
+		Exp int64  `json:"exp"`
+		Iss string `json:"iss"`
+	}{}
+	if err := json.Unmarshal(payload, &claims); err != nil {
+		return err
+	}
+	if claims.Iss != fmt.Sprint(f.appID) {
+		return fmt.Errorf("expected issuer %d, got %s", f.appID, claims.Iss)
+	}
+	if claims.Exp-claims.Iat > int64((10 * time.Minute).Seconds()) {
+		return fmt.Errorf("jwt is valid for too long")
+	}
+	return nil
+}
+
+func newTestAppKey(t *testing.T) (*rsa.PrivateKey, []byte) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("Could not generate key: %v", err)
+	}
+	keyPEM := pem.EncodeToMemory(&pem.Block{
+		Type:  "RSA PRIVATE KEY",
+		Bytes: x509.MarshalPKCS1PrivateKey(key),
+	})
+	return key, keyPEM
+}
+
+func TestGitHubAppAuthToken(t *testing.T) {
+	key, keyPEM := newTestAppKey(t)
+
+	testCases := []struct {
+		name           string
+		lifetime       time.Duration
+		expectedTokens []string
+	}{
+		{
+			name:           "Token is cached",
+			lifetime:       time.Hour,
+			expectedTokens: []string{"ghs_token1", "ghs_token1"},
+		},
+		{
+			name:           "Token that is about to expire is replaced",
+			lifetime:       appTokenRefreshMargin - time.Minute,
+			expectedTokens: []string{"ghs_token1", "ghs_token2"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			endpoint := &fakeTokenEndpoint{
+				t: t, key: &key.PublicKey, appID: 12, lifetime: tc.lifetime,
+			}
+			server := httptest.NewServer(endpoint)
+			defer server.Close()
+
+			auth, err := NewGitHubAppAuth(
+				GitForgeOptions{Type: GitHubForgeType, APIURL: server.URL},
+				GitHubApp{AppID: 12, InstallationID: 99, PrivateKey: keyPEM},
+				server.Client(),
+				common.RetryPolicy{},
+			)
+			if err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+			for _, expected := range tc.expectedTokens {
+				token, err := auth.Token(context.Background())
+				if err != nil {
+					t.Fatalf("Expected no error, got %v", err)
+				}
+				if token != expected {
+					t.Errorf("Expected token %s, got %s", expected, token)
+				}
+			}
+		})
+	}
+}
+
+func TestNewGitHubAppAuthInvalidKey(t *testing.T) {
+	_, err := NewGitHubAppAuth(
+		GitForgeOptions{Type: GitHubForgeType, Fqdn: "github.com"},
+		GitHubApp{AppID: 12, InstallationID: 99, PrivateKey: []byte("not a key")},
+		http.DefaultClient,
+		common.RetryPolicy{},
+	)
+	if err == nil {
+		t.Fatal("Expected an error, got nil")
+	}
+}
+
+func TestLoadFeedsGitHubApp(t *testing.T) {
+	key, keyPEM := newTestAppKey(t)
+	endpoint := &fakeTokenEndpoint{t: t, key: &key.PublicKey, appID: 12, lifetime: time.Hour}
+
+	// Every request to the API has to carry the installation token
+	mux := http.NewServeMux()
+	mux.Handle("/app/", endpoint)
+	mux.HandleFunc("/users/team-picks/starred", func(w http.ResponseWriter, r *http.Request) {
+		if auth := r.Header.Get("Authorization"); auth != "Bearer ghs_token1" {
+			t.Errorf("Expected the installation token, got %q", auth)
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		_, _ = fmt.Fprintf(w, `[{"id": 5, "name": "tool", "full_name": "org/tool",
+			"html_url": "%s/org/tool"}]`, "http://"+r.Host)
+	})
+	mux.HandleFunc("/org/tool/releases.atom", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = fmt.Fprint(w, `<feed xmlns="http://www.w3.org/2005/Atom"><entry/></feed>`)
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	opts := GitForgeOptions{
+		Type:      GitHubForgeType,
+		APIURL:    server.URL,
+		Usernames: []string{"team-picks"},
+	}
+	auth, err := NewGitHubAppAuth(
+		opts,
+		GitHubApp{AppID: 12, InstallationID: 99, PrivateKey: keyPEM},
+		server.Client(),
+		common.RetryPolicy{},
+	)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	opts.AppAuth = auth
+	forge := NewGitForgeClient(
+		opts, "", testutils.TestLogger(t), server.Client(), common.RetryPolicy{},
+	)
+
+	actual, err := forge.LoadFeeds(context.Background())
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	feedURL := common.FeedURL(server.URL + "/org/tool/releases.atom")
+	if !actual[feedURL].IsOK() {
+		t.Errorf("Expected a valid feed for %s, got %+v", feedURL, actual)
+	}
+	if numTokens := endpoint.numTokens.Load(); numTokens != 1 {
+		t.Errorf("Expected 1 installation token to be minted, got %d", numTokens)
+	}
+}
@@ -0,0 +1,132 @@
+package gitforge
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/atomicmeganerd/starfeed/common"
+)
+
+// MirrorPolicy decides which feeds we follow for pull mirrors on Forgejo. Releases are not
+// mirrored so the feeds of a mirror are often empty while the original project releases.
+type MirrorPolicy string
+
+const (
+	// Follow the feeds of the mirror itself. This is the default.
+	MirrorSelf MirrorPolicy = "self"
+	// Follow the feeds of the project the mirror was pulled from
+	MirrorOrigin MirrorPolicy = "origin"
+)
+
+// Points the feeds of pull mirrors at the project they mirror. Everything else about the repo
+// stays the same so the feeds keep the id, name and category of the mirror. The original project
+// is fetched without our credentials so it can't be private as far as the RSS server is concerned.
+func (c GitForgeClient) followMirrorOrigins(repos []GitRepo) []GitRepo {
+	if c.mirrors != MirrorOrigin {
+		return repos
+	}
+	for ix, repo := range repos {
+		if !repo.Mirror {
+			continue
+		}
+		originURL, err := parseOriginURL(repo.OriginalURL)
+		if err != nil {
+			c.logger.Warn("Can't follow origin of mirror", "repo", repo.FullName, "error", err)
+			continue
+		}
+		mirror := repo
+		originType := originForgeType(originURL)
+		repos[ix].MirrorRepo = &mirror
+		repos[ix].FeedURL = buildReleaseFeedURL(originType, originURL)
+		repos[ix].TagsFeedURL = buildTagsFeedURL(originType, originURL)
+		repos[ix].Origin = originURL
+		repos[ix].Private = false
+		c.logger.Debug("Following origin of mirror", "repo", repo.FullName, "origin", originURL)
+	}
+	return repos
+}
+
+// Turns the URL a mirror was cloned from into the web URL of the project. Any credentials in it
+// are dropped and we only follow projects we can reach over HTTP.
+func parseOriginURL(originalURL string) (GitRepoURL, error) {
+	parsed, err := url.Parse(originalURL)
+	if err != nil {
+		return "", errors.New("original url is not a valid url")
+	}
+	if parsed.Scheme != "http" && parsed.Scheme != "https" || parsed.Host == "" {
+		return "", errors.New("original url is not an http url")
+	}
+	path := strings.TrimSuffix(strings.Trim(parsed.Path, "/"), ".git")
+	if strings.Count(path, "/") < 1 {
+		return "", errors.New("original url does not point at a project")
+	}
+	return GitRepoURL(fmt.Sprintf("%s://%s/%s", parsed.Scheme, parsed.Host, path)), nil
+}
+
+// We can't ask the host of the original project what it runs. GitHub and Forgejo have the same
+// feed URLs so we only need to spot GitLab, which is nearly always in the host name. When we get
+// it wrong the feeds are not found and checkMirrorFeeds falls back to the mirror.
+func originForgeType(originURL GitRepoURL) string {
+	parsed, err := url.Parse(originURL.String())
+	if err == nil && strings.Contains(parsed.Hostname(), "gitlab") {
+		return GitLabForgeType
+	}
+	return GitHubForgeType
+}
+
+// The original project of a mirror may be gone or live on a forge we could not tell from its host
+// name. If none of its feeds are found we follow the feeds of the mirror instead, unless the
+// mirror is private and we would have to skip it. The repo we checked is returned along with the
+// results as the feeds of a private mirror need our feed proxy.
+func (c GitForgeClient) checkMirrorFeeds(
+	ctx context.Context,
+	repo GitRepo,
+) (GitRepo, FeedResultMap) {
+	results := c.checkRepoFeeds(ctx, repo)
+	if repo.MirrorRepo == nil || !allNotFound(results) {
+		return repo, results
+	}
+	mirror := *repo.MirrorRepo
+	if mirror.Private && c.feedProxy == nil {
+		return repo, results
+	}
+	c.logger.Info(
+		"Feeds of the origin of mirror not found, following the mirror instead",
+		"repo", repo.FullName,
+		"origin", repo.Origin,
+	)
+	return mirror, c.checkRepoFeeds(ctx, mirror)
+}
+
+func allNotFound(results FeedResultMap) bool {
+	for _, result := range results {
+		httpErr, ok := errors.AsType[common.HTTPError](result.Err)
+		if !ok || httpErr.StatusCode != http.StatusNotFound {
+			return false
+		}
+	}
+	return true
+}
+
+// Our credentials are only for our forge so feeds of the original project of a mirror are
+// fetched without them
+func (c GitForgeClient) fetchFeed(
+	ctx context.Context,
+	repo GitRepo,
+	feedURL common.FeedURL,
+) ([]byte, error) {
+	if repo.Origin == "" {
+		data, _, err := c.doRequest(ctx, feedURL.String())
+		return data, err
+	}
+	headers := http.Header{}
+	headers.Set("User-Agent", "github.com/atomicmeganerd/starfeed")
+	data, _, err := common.DoAPIRequest(
+		ctx, http.MethodGet, feedURL.String(), nil, headers, c.client, c.retry,
+	)
+	return data, err
+}
@@ -5,6 +5,9 @@ import (
 	"errors"
 	"fmt"
 	"net/http"
+	"slices"
+	"strings"
+	"time"
 
 	"github.com/atomicmeganerd/starfeed/common"
 )
@@ -12,6 +15,7 @@ import (
 const (
 	GitHubForgeType  = "github"
 	ForgejoForgeType = "forgejo"
+	GitLabForgeType  = "gitlab"
 )
 
 type GitRepoName string
@@ -38,10 +42,32 @@ type FeedResultMap map[common.FeedURL]GitRepoResult
 //   - Querying the reslease feed fails due to a GitHub issue (5xx) or network issue of some kind.
 //     Here we need to make sure we don't remove from RSS because this could still be a valid feed
 //     that is just being impacted by the outage.
+//   - Querying the release feed fails because the forge is rate limiting us. This says nothing
+//     about the feed so just like an outage we must not remove it from RSS.
+//   - The repo is archived. Its feed may still have entries but there will never be another
+//     release so the runner applies the archived policy of the forge to it.
 type GitRepoResult struct {
-	RepoName          GitRepoName
+	// The id the forge gave the repo. Unlike the name it survives renames and transfers.
+	RepoID   int64
+	RepoName GitRepoName
+	// These are only used to build the feed title
+	Owner             string
+	FullName          string
+	Description       string
 	RelFeedHasEntries bool
-	Err               error
+	// Archived repos are read-only and will never release again
+	Archived bool
+	// Private repos are only in the results if their feeds are served through the feed proxy
+	Private bool
+	// The GitHub star list the repo is in. It is empty if it is in none or we did not look.
+	StarList string
+	// Whether we follow the repo because it is starred, watched or both
+	Sources []RepoSource
+	// Which feed of the repo this result is for
+	FeedKind FeedKind
+	// When the newest entry in the feed was published. This is the zero time if we don't know.
+	LatestRelease time.Time
+	Err           error
 }
 
 // Is stale means that querying the feed URL results in a 404 or the feed is there but has no
@@ -56,7 +82,12 @@ func (r GitRepoResult) IsStale() bool {
 		return false
 	}
 
-	// If we do have an error it is stale iff it is an HTTPError with code 404
+	// Being throttled tells us nothing about the feed itself
+	if isRateLimitError(r.Err) {
+		return false
+	}
+
+	// Otherwise it is stale iff it is an HTTPError with code 404
 	httpErr, ok := errors.AsType[common.HTTPError](r.Err)
 	if !ok {
 		return false
@@ -71,10 +102,17 @@ func (r GitRepoResult) IsOK() bool {
 
 // Equal compares two GitRepoResult values. Errors are considered equal if they have the same type.
 func (r GitRepoResult) Equal(other GitRepoResult) bool {
-	if r.RepoName != other.RepoName {
+	if r.RepoID != other.RepoID || r.RepoName != other.RepoName {
 		return false
 	}
-	if r.RelFeedHasEntries != other.RelFeedHasEntries {
+	if r.Owner != other.Owner || r.FullName != other.FullName ||
+		r.Description != other.Description {
+		return false
+	}
+	if r.RelFeedHasEntries != other.RelFeedHasEntries || r.Archived != other.Archived ||
+		r.Private != other.Private || r.StarList != other.StarList ||
+		!slices.Equal(r.Sources, other.Sources) || r.FeedKind != other.FeedKind ||
+		!r.LatestRelease.Equal(other.LatestRelease) {
 		return false
 	}
 
@@ -91,9 +129,109 @@ func (r GitRepoResult) Equal(other GitRepoResult) bool {
 // This object represents a Git repo in a supported Git Host that is starred and that we want to
 // get the Atom feed for.
 type GitRepo struct {
-	Name    GitRepoName    `json:"name"`
-	RepoURL GitRepoURL     `json:"html_url"`
-	FeedURL common.FeedURL `json:"feed_url"`
+	ID          int64          `json:"id"`
+	Name        GitRepoName    `json:"name"`
+	FullName    string         `json:"full_name"`
+	Owner       GitRepoOwner   `json:"owner"`
+	Description string         `json:"description"`
+	RepoURL     GitRepoURL     `json:"html_url"`
+	FeedURL     common.FeedURL `json:"feed_url"`
+	TagsFeedURL common.FeedURL `json:"tags_feed_url"`
+	Archived    bool           `json:"archived"`
+	Private     bool           `json:"private"`
+	Language    string         `json:"language"`
+	Topics      []string       `json:"topics"`
+	Fork        bool           `json:"fork"`
+	Stars       int            `json:"stargazers_count"`
+	// The repo this one was forked from. GitHub only sends it when we fetch a single repo.
+	Parent *GitRepo `json:"parent"`
+	// Forgejo pull mirrors and the URL they were cloned from
+	Mirror      bool   `json:"mirror"`
+	OriginalURL string `json:"original_url"`
+	// The web URL of the original project if we follow its feeds instead of the mirror's
+	Origin GitRepoURL `json:"-"`
+	// The mirror itself which we fall back on if the feeds of the original project are not found
+	MirrorRepo *GitRepo `json:"-"`
+	// The GitHub star list the repo is in if we looked them up
+	StarList string `json:"-"`
+	// Where we found the repo
+	Sources []RepoSource `json:"-"`
+}
+
+// GitHub and Forgejo nest the owner of a repo as a user or organisation object
+type GitRepoOwner struct {
+	Login string `json:"login"`
+}
+
+// Falls back to the first part of the full name if the forge did not send the owner
+func (r GitRepo) OwnerName() string {
+	if r.Owner.Login != "" {
+		return r.Owner.Login
+	}
+	owner, _, found := strings.Cut(r.FullName, "/")
+	if !found {
+		return ""
+	}
+	return owner
+}
+
+// Topics are lower case on GitHub but not on every forge
+func (r GitRepo) hasTopic(topic string) bool {
+	return slices.ContainsFunc(r.Topics, func(t string) bool {
+		return strings.EqualFold(t, topic)
+	})
+}
+
+// Forgejo calls the star count stars_count. The rest of the repo matches GitHub.
+type forgejoRepo struct {
+	GitRepo
+	StarsCount int `json:"stars_count"`
+}
+
+// GitLab calls repos projects and uses different field names to GitHub and Forgejo so we decode
+// them into this type and then convert them to a GitRepo.
+type gitLabProject struct {
+	ID                int64       `json:"id"`
+	Name              GitRepoName `json:"name"`
+	PathWithNamespace string      `json:"path_with_namespace"`
+	WebURL            GitRepoURL  `json:"web_url"`
+	Archived          bool        `json:"archived"`
+	Description       string      `json:"description"`
+	Namespace         struct {
+		FullPath string `json:"full_path"`
+	} `json:"namespace"`
+	Topics    []string `json:"topics"`
+	StarCount int      `json:"star_count"`
+	// Only set for forks
+	ForkedFromProject *gitLabProject `json:"forked_from_project"`
+	// public, internal or private. Only public projects can be read anonymously.
+	Visibility string `json:"visibility"`
+}
+
+func (p gitLabProject) toGitRepo() GitRepo {
+	repo := GitRepo{
+		ID:          p.ID,
+		Name:        p.Name,
+		FullName:    p.PathWithNamespace,
+		Owner:       GitRepoOwner{Login: p.Namespace.FullPath},
+		Description: p.Description,
+		RepoURL:     p.WebURL,
+		Archived:    p.Archived,
+		Private:     p.Visibility != "" && p.Visibility != "public",
+		Topics:      p.Topics,
+		Fork:        p.ForkedFromProject != nil,
+		Stars:       p.StarCount,
+	}
+	if p.ForkedFromProject != nil {
+		parent := p.ForkedFromProject.toGitRepo()
+		repo.Parent = &parent
+	}
+	return repo
+}
+
+// We only need the id of the GitLab user to list their starred projects
+type gitLabUser struct {
+	ID int64 `json:"id"`
 }
 
 // This object represents an ATOM feed. We check to make sure that release feeds exist and
@@ -105,5 +243,22 @@ type AtomFeed struct {
 }
 
 type Entry struct {
-	Title string `xml:"title"`
+	Title     string `xml:"title"`
+	Updated   string `xml:"updated"`
+	Published string `xml:"published"`
+}
+
+// Returns the time of the newest entry in the feed or the zero time if none of the entries have
+// a date we can parse. We parse the dates ourselves so one bad date can't break the whole feed.
+func (f AtomFeed) NewestEntryTime() time.Time {
+	var newest time.Time
+	for _, entry := range f.Entries {
+		for _, value := range []string{entry.Updated, entry.Published} {
+			when, err := time.Parse(time.RFC3339, strings.TrimSpace(value))
+			if err == nil && when.After(newest) {
+				newest = when
+			}
+		}
+	}
+	return newest
 }
@@ -4,6 +4,7 @@ import (
 	"errors"
 	"net/http"
 	"testing"
+	"time"
 
 	"github.com/atomicmeganerd/starfeed/common"
 )
@@ -40,6 +41,22 @@ func TestGitRepoResultIsStale(t *testing.T) {
 			},
 			expected: false,
 		},
+		{
+			name: "Rate limit error is not stale",
+			result: GitRepoResult{
+				RepoName: "repo1",
+				Err:      RateLimitError{Reset: time.Now().Add(time.Hour)},
+			},
+			expected: false,
+		},
+		{
+			name: "HTTP 429 error is not stale",
+			result: GitRepoResult{
+				RepoName: "repo1",
+				Err:      common.HTTPError{StatusCode: http.StatusTooManyRequests},
+			},
+			expected: false,
+		},
 		{
 			name:     "Non-HTTP error is not stale",
 			result:   GitRepoResult{RepoName: "repo1", Err: errors.New("network failure")},
@@ -157,6 +174,15 @@ func TestGitRepoResultEqual(t *testing.T) {
 			},
 			expected: false,
 		},
+		{
+			name: "Different sources are not equal",
+			a:    GitRepoResult{RepoName: "repo1", Sources: []RepoSource{SourceStarred}},
+			b: GitRepoResult{
+				RepoName: "repo1",
+				Sources:  []RepoSource{SourceStarred, SourceWatched},
+			},
+			expected: false,
+		},
 		{
 			name: "One nil error and one non-nil error is not equal",
 			a:    GitRepoResult{RepoName: "repo1"},
@@ -177,3 +203,76 @@ func TestGitRepoResultEqual(t *testing.T) {
 		})
 	}
 }
+
+func TestGitRepoOwnerName(t *testing.T) {
+	testCases := []struct {
+		name     string
+		repo     GitRepo
+		expected string
+	}{
+		{
+			name:     "Owner login is used",
+			repo:     GitRepo{FullName: "org/tool", Owner: GitRepoOwner{Login: "org"}},
+			expected: "org",
+		},
+		{
+			name:     "Falls back to the full name",
+			repo:     GitRepo{FullName: "org/tool"},
+			expected: "org",
+		},
+		{
+			name:     "No owner and no full name",
+			repo:     GitRepo{Name: "tool"},
+			expected: "",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			if got := tc.repo.OwnerName(); got != tc.expected {
+				t.Errorf("OwnerName() = %q, want %q", got, tc.expected)
+			}
+		})
+	}
+}
+
+func TestAtomFeedNewestEntryTime(t *testing.T) {
+	testCases := []struct {
+		name     string
+		feed     AtomFeed
+		expected time.Time
+	}{
+		{
+			name:     "No entries",
+			feed:     AtomFeed{},
+			expected: time.Time{},
+		},
+		{
+			name: "Newest of updated and published wins",
+			feed: AtomFeed{Entries: []Entry{
+				{Updated: "2024-05-01T10:00:00Z"},
+				{Published: "2025-02-03T04:05:06+01:00"},
+				{Updated: "2023-01-01T00:00:00Z", Published: "2022-01-01T00:00:00Z"},
+			}},
+			expected: time.Date(2025, 2, 3, 3, 5, 6, 0, time.UTC),
+		},
+		{
+			name: "Dates that don't parse are ignored",
+			feed: AtomFeed{Entries: []Entry{
+				{Updated: "yesterday"},
+				{Updated: " 2024-05-01T10:00:00Z "},
+			}},
+			expected: time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC),
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			if got := tc.feed.NewestEntryTime(); !got.Equal(tc.expected) {
+				t.Errorf("NewestEntryTime() = %s, want %s", got, tc.expected)
+			}
+		})
+	}
+}
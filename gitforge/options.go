@@ -0,0 +1,93 @@
+package gitforge
+
+import "strings"
+
+// GitForgeOptions describes which Git Forge we talk to. Only the Type and either the Fqdn or the
+// APIURL are required.
+//
+//   - APIURL overrides the API endpoint we would otherwise derive from the Fqdn. This is needed for
+//     GitHub Enterprise Server (https://ghe.corp/api/v3), GHE.com tenants or a Forgejo instance
+//     running on a non-standard port or plain HTTP.
+//   - WebURL overrides the base URL we build release feed URLs from. Without it we trust the
+//     html_url (or web_url) the API returns for each repo.
+//   - FeedKind selects the releases feed, the tags feed or the releases feed with a fallback to
+//     tags. It defaults to releases. FeedKindOverrides sets it per repo by full name (owner/name).
+//   - Rules include or exclude starred repos. Without rules every starred repo is followed.
+//   - StarLists looks up which GitHub star list each repo is in. It is ignored on other forges.
+//   - Usernames follows the stars of these users or organisations instead of the token owner.
+//   - Sources selects whether we follow starred repos, watched repos or both. It defaults to
+//     starred repos. GitLab does not have watched repos.
+//   - AppAuth authenticates as a GitHub App installation in place of the token. Installations
+//     have no user of their own so Usernames must be set with it.
+//   - Private decides what happens to private repos, whose feeds the RSS server can't read. They
+//     are skipped by default. With the proxy policy their feeds are served through FeedProxy.
+//   - Forks decides whether we follow forks, the parents they were forked from or both. It
+//     defaults to the forks themselves. Rules are applied to the forks, not to their parents.
+//   - Mirrors decides whether we follow the feeds of Forgejo pull mirrors or of the projects they
+//     were pulled from. It defaults to the mirrors themselves.
+type GitForgeOptions struct {
+	Type              string
+	Fqdn              string
+	APIURL            string
+	WebURL            string
+	FeedKind          FeedKind
+	FeedKindOverrides map[string]FeedKind
+	Rules             []RepoRule
+	StarLists         bool
+	Usernames         []string
+	Sources           []RepoSource
+	AppAuth           *GitHubAppAuth
+	Private           PrivatePolicy
+	FeedProxy         FeedProxy
+	Forks             ForkPolicy
+	Mirrors           MirrorPolicy
+}
+
+func (o GitForgeOptions) apiURL() string {
+	if o.APIURL != "" {
+		return strings.TrimSuffix(o.APIURL, "/")
+	}
+	return buildAPIURL(o.Type, o.Fqdn)
+}
+
+func (o GitForgeOptions) webURL() string {
+	return strings.TrimSuffix(o.WebURL, "/")
+}
+
+func (o GitForgeOptions) feedKind() FeedKind {
+	if o.FeedKind == "" {
+		return FeedKindReleases
+	}
+	return o.FeedKind
+}
+
+// Repo names are not case sensitive on any of the forges
+func (o GitForgeOptions) feedKindOverrides() map[string]FeedKind {
+	overrides := make(map[string]FeedKind, len(o.FeedKindOverrides))
+	for fullName, kind := range o.FeedKindOverrides {
+		overrides[strings.ToLower(fullName)] = kind
+	}
+	return overrides
+}
+
+func (o GitForgeOptions) sources() []RepoSource {
+	if len(o.Sources) == 0 {
+		return []RepoSource{SourceStarred}
+	}
+	return o.Sources
+}
+
+// The proxy is only used if the policy asks for it
+func (o GitForgeOptions) feedProxy() FeedProxy {
+	if o.Private != PrivateProxy {
+		return nil
+	}
+	return o.FeedProxy
+}
+
+func (o GitForgeOptions) forks() ForkPolicy {
+	if o.Forks == "" {
+		return ForkSelf
+	}
+	return o.Forks
+}
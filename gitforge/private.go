@@ -0,0 +1,58 @@
+package gitforge
+
+import (
+	"context"
+
+	"github.com/atomicmeganerd/starfeed/common"
+)
+
+// PrivatePolicy decides what we do with private repos. We can read their feeds with our token but
+// the RSS server fetches feeds anonymously and would get a 404 for them forever.
+type PrivatePolicy string
+
+const (
+	// Don't follow private repos at all. This is the default.
+	PrivateSkip PrivatePolicy = "skip"
+	// Subscribe the RSS server to a URL of our feed proxy which fetches the feed with our token
+	PrivateProxy PrivatePolicy = "proxy"
+)
+
+// FeedProxy serves the feeds of private repos to the RSS server. Register returns the URL the RSS
+// server should subscribe to in place of the feed URL. The proxy gets the feed with fetch, which
+// uses our credentials.
+type FeedProxy interface {
+	Register(
+		feedURL common.FeedURL,
+		fetch func(ctx context.Context) ([]byte, error),
+	) common.FeedURL
+}
+
+// Private repos are dropped before we check their feeds unless we can proxy them
+func (c GitForgeClient) skipPrivateRepos(repos []GitRepo) []GitRepo {
+	if c.feedProxy != nil {
+		return repos
+	}
+	public := make([]GitRepo, 0, len(repos))
+	for _, repo := range repos {
+		if repo.Private {
+			c.logger.Debug("Skipping private repo", "repo", repo.FullName)
+			continue
+		}
+		public = append(public, repo)
+	}
+	if numSkipped := len(repos) - len(public); numSkipped > 0 {
+		c.logger.Info("Skipped private repos", "numSkipped", numSkipped)
+	}
+	return public
+}
+
+// The URL the RSS server subscribes to for a feed of the repo
+func (c GitForgeClient) subscriptionURL(repo GitRepo, feedURL common.FeedURL) common.FeedURL {
+	if !repo.Private || c.feedProxy == nil {
+		return feedURL
+	}
+	return c.feedProxy.Register(feedURL, func(ctx context.Context) ([]byte, error) {
+		data, _, err := c.doRequest(ctx, feedURL.String())
+		return data, err
+	})
+}
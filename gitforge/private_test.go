@@ -0,0 +1,196 @@
+package gitforge
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/atomicmeganerd/starfeed/common"
+	"github.com/atomicmeganerd/starfeed/testutils"
+)
+
+// Records what was registered and hands out predictable proxy URLs
+type fakeFeedProxy struct {
+	mu    sync.Mutex
+	feeds map[common.FeedURL]func(ctx context.Context) ([]byte, error)
+}
+
+func (p *fakeFeedProxy) Register(
+	feedURL common.FeedURL,
+	fetch func(ctx context.Context) ([]byte, error),
+) common.FeedURL {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.feeds == nil {
+		p.feeds = make(map[common.FeedURL]func(ctx context.Context) ([]byte, error))
+	}
+	p.feeds[feedURL] = fetch
+	name := strings.TrimPrefix(feedURL.String(), "https://github.com/")
+	return common.FeedURL("http://starfeed:8080/feeds/" + strings.ReplaceAll(name, "/", "-"))
+}
+
+func TestLoadFeedsPrivate(t *testing.T) {
+	starred := `[
+		{"id": 1, "name": "public", "full_name": "org/public",
+			"html_url": "https://github.com/org/public"},
+		{"id": 2, "name": "secret", "full_name": "org/secret", "private": true,
+			"html_url": "https://github.com/org/secret"}
+	]`
+	validFeed := `<feed xmlns="http://www.w3.org/2005/Atom"><entry><title>v1</title></entry></feed>`
+	publicFeed := common.FeedURL("https://github.com/org/public/releases.atom")
+	secretFeed := common.FeedURL("https://github.com/org/secret/releases.atom")
+	proxiedFeed := common.FeedURL("http://starfeed:8080/feeds/org-secret-releases.atom")
+
+	testCases := []struct {
+		name            string
+		private         PrivatePolicy
+		proxy           *fakeFeedProxy
+		expectedFeeds   []common.FeedURL
+		expectedProxied []common.FeedURL
+	}{
+		{
+			name:          "Private repos are skipped by default",
+			proxy:         &fakeFeedProxy{},
+			expectedFeeds: []common.FeedURL{publicFeed},
+		},
+		{
+			name:          "Private repos are skipped without a proxy",
+			private:       PrivateProxy,
+			expectedFeeds: []common.FeedURL{publicFeed},
+		},
+		{
+			name:            "Private repos are served through the proxy",
+			private:         PrivateProxy,
+			proxy:           &fakeFeedProxy{},
+			expectedFeeds:   []common.FeedURL{publicFeed, proxiedFeed},
+			expectedProxied: []common.FeedURL{secretFeed},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			mockTransport := testutils.NewMockRoutedResponseRoundTripper(
+				[]testutils.MockRoutedResponse{
+					{
+						UrlPattern: `/user/starred`,
+						Response: http.Response{
+							Body:       io.NopCloser(strings.NewReader(starred)),
+							StatusCode: http.StatusOK,
+						},
+					},
+					{
+						UrlPattern: `org/public/releases\.atom$`,
+						Response: http.Response{
+							Body:       io.NopCloser(strings.NewReader(validFeed)),
+							StatusCode: http.StatusOK,
+						},
+					},
+					// Once when we check the feed and once when the proxy fetches it
+					{
+						UrlPattern: `org/secret/releases\.atom$`,
+						Response: http.Response{
+							Body:       io.NopCloser(strings.NewReader(validFeed)),
+							StatusCode: http.StatusOK,
+						},
+						MaxMatches: 1,
+					},
+					{
+						UrlPattern: `org/secret/releases\.atom$`,
+						Response: http.Response{
+							Body:       io.NopCloser(strings.NewReader(validFeed)),
+							StatusCode: http.StatusOK,
+						},
+					},
+				},
+			)
+			opts := GitForgeOptions{Type: GitHubForgeType, Fqdn: "github.com", Private: tc.private}
+			if tc.proxy != nil {
+				opts.FeedProxy = tc.proxy
+			}
+			forge := NewGitForgeClient(
+				opts,
+				testutils.GitHubToken,
+				testutils.TestLogger(t),
+				&http.Client{Transport: &mockTransport},
+				common.RetryPolicy{},
+			)
+
+			actual, err := forge.LoadFeeds(context.Background())
+			if err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+			if len(actual) != len(tc.expectedFeeds) {
+				t.Fatalf("Expected %d feeds, got %v", len(tc.expectedFeeds), actual)
+			}
+			for _, feedURL := range tc.expectedFeeds {
+				result, ok := actual[feedURL]
+				if !ok || !result.IsOK() {
+					t.Errorf("Expected a valid feed for %s, got %+v", feedURL, actual)
+				}
+				if result.Private != (feedURL == proxiedFeed) {
+					t.Errorf("Feed %s: expected private to be %v", feedURL, !result.Private)
+				}
+			}
+			for _, feedURL := range tc.expectedProxied {
+				fetch, ok := tc.proxy.feeds[feedURL]
+				if !ok {
+					t.Fatalf("Expected %s to be registered with the proxy", feedURL)
+				}
+				data, err := fetch(context.Background())
+				if err != nil || string(data) != validFeed {
+					t.Errorf("Expected the proxy to fetch the feed, got %s and %v", data, err)
+				}
+			}
+		})
+	}
+}
+
+func TestParseStarredReposPrivate(t *testing.T) {
+	testCases := []struct {
+		name      string
+		forgeType string
+		data      string
+		expected  []bool
+	}{
+		{
+			name:      "GitHub",
+			forgeType: GitHubForgeType,
+			data:      `[{"id": 1, "private": true}, {"id": 2, "private": false}]`,
+			expected:  []bool{true, false},
+		},
+		{
+			name:      "Forgejo",
+			forgeType: ForgejoForgeType,
+			data:      `[{"id": 1, "private": true}, {"id": 2}]`,
+			expected:  []bool{true, false},
+		},
+		{
+			name:      "GitLab internal projects can't be read anonymously either",
+			forgeType: GitLabForgeType,
+			data: `[{"id": 1, "visibility": "private"}, {"id": 2, "visibility": "internal"},
+				{"id": 3, "visibility": "public"}]`,
+			expected: []bool{true, true, false},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			repos, err := parseStarredRepos(tc.forgeType, []byte(tc.data))
+			if err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+			if len(repos) != len(tc.expected) {
+				t.Fatalf("Expected %d repos, got %d", len(tc.expected), len(repos))
+			}
+			for ix, expected := range tc.expected {
+				if repos[ix].Private != expected {
+					t.Errorf("Repo %d: expected private to be %v", ix, expected)
+				}
+			}
+		})
+	}
+}
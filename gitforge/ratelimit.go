@@ -0,0 +1,189 @@
+package gitforge
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/atomicmeganerd/starfeed/common"
+)
+
+const (
+	// Once the budget gets this low we pause until the rate limit resets
+	rateLimitPauseThreshold = 10
+	// Below this budget we start spacing out our requests
+	rateLimitSlowThreshold = 100
+	// The longest gap we put between two requests when we are slowing down
+	maxRequestSpacing = 2 * time.Second
+	// If we would have to wait longer than this we fail fast instead of stalling the whole run
+	maxRateLimitWait = 5 * time.Minute
+	// GitHub does not always say how long to back off from a secondary rate limit
+	defaultSecondaryLimitPause = time.Minute
+)
+
+// RateLimitError is returned when the forge has throttled us and we are not willing to wait for
+// the limit to reset. A feed that failed because of this is never considered stale.
+type RateLimitError struct {
+	Reset time.Time
+}
+
+func (e RateLimitError) Error() string {
+	return fmt.Sprintf("rate limited by gitforge until %s", e.Reset.Format(time.RFC3339))
+}
+
+// Throttling shows up either as our own RateLimitError or as a 429 from the forge
+func isRateLimitError(err error) bool {
+	if _, ok := errors.AsType[RateLimitError](err); ok {
+		return true
+	}
+	httpErr, ok := errors.AsType[common.HTTPError](err)
+	return ok && httpErr.StatusCode == http.StatusTooManyRequests
+}
+
+// The rateLimiter keeps track of the rate limit budget the forge reports in its response headers
+// and is shared by all of the goroutines that LoadFeeds fans out to. Before each request we ask
+// it to wait which lets it pause or slow down the whole worker pool when the budget is nearly
+// gone or when we have hit a secondary rate limit.
+//
+// GitHub sends X-RateLimit-Remaining and X-RateLimit-Reset, GitLab sends RateLimit-Remaining and
+// RateLimit-Reset. Forgejo does not send either in which case this never waits.
+type rateLimiter struct {
+	mu         sync.Mutex
+	remaining  int
+	reset      time.Time
+	pauseUntil time.Time
+	// When we are slowing down every request reserves the next free slot so that the workers
+	// take turns instead of all waiting out the same gap and firing together
+	nextSlot time.Time
+	logger   *slog.Logger
+	now      func() time.Time
+}
+
+func newRateLimiter(logger *slog.Logger) *rateLimiter {
+	return &rateLimiter{remaining: -1, logger: logger, now: time.Now}
+}
+
+// Blocks until it is ok to make another request or returns a RateLimitError if that would take
+// longer than maxRateLimitWait.
+func (l *rateLimiter) wait(ctx context.Context) error {
+	delay, until := l.nextDelay()
+	if delay <= 0 {
+		return nil
+	}
+	if delay > maxRateLimitWait {
+		return RateLimitError{Reset: until}
+	}
+	if !until.IsZero() {
+		l.logger.Warn("Pausing requests until the gitforge rate limit resets", "until", until)
+	}
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// How long to wait before the next request. The time is when a pause ends and is zero when we
+// are only spacing out requests.
+func (l *rateLimiter) nextDelay() (time.Duration, time.Time) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	now := l.now()
+
+	if l.pauseUntil.After(now) {
+		return l.pauseUntil.Sub(now), l.pauseUntil
+	}
+	if l.remaining < 0 || !l.reset.After(now) {
+		return 0, time.Time{}
+	}
+	untilReset := l.reset.Sub(now)
+	if l.remaining <= rateLimitPauseThreshold {
+		return untilReset, l.reset
+	}
+	if l.remaining <= rateLimitSlowThreshold {
+		// Spread what is left of the budget over the time until it resets
+		spacing := min(untilReset/time.Duration(l.remaining), maxRequestSpacing)
+		l.nextSlot = later(l.nextSlot, now).Add(spacing)
+		return l.nextSlot.Sub(now), time.Time{}
+	}
+	return 0, time.Time{}
+}
+
+func later(a, b time.Time) time.Time {
+	if a.After(b) {
+		return a
+	}
+	return b
+}
+
+// Records the budget from the response headers. If the request was throttled we also work out
+// how long we need to pause for.
+func (l *rateLimiter) observe(respHeaders http.Header, err error) {
+	if respHeaders == nil {
+		return
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	now := l.now()
+
+	if remaining, ok := parseRateLimitHeader(respHeaders, "Remaining"); ok {
+		l.remaining = int(remaining)
+	}
+	if reset, ok := parseRateLimitHeader(respHeaders, "Reset"); ok {
+		l.reset = time.Unix(reset, 0)
+	}
+
+	httpErr, ok := errors.AsType[common.HTTPError](err)
+	if !ok || !isThrottledStatus(httpErr.StatusCode) {
+		return
+	}
+
+	var pauseUntil time.Time
+	switch retryAfter, hasRetryAfter := common.ParseRetryAfter(respHeaders, now); {
+	case hasRetryAfter:
+		pauseUntil = now.Add(retryAfter)
+	case l.remaining == 0 && l.reset.After(now):
+		pauseUntil = l.reset
+	case httpErr.StatusCode == http.StatusTooManyRequests:
+		// This is a secondary rate limit that did not tell us how long to back off for
+		pauseUntil = now.Add(defaultSecondaryLimitPause)
+	default:
+		// A 403 with budget left and no Retry-After is just a normal permission error
+		return
+	}
+	if pauseUntil.After(l.pauseUntil) {
+		l.pauseUntil = pauseUntil
+	}
+	l.logger.Warn(
+		"Throttled by gitforge", "statusCode", httpErr.StatusCode, "pauseUntil", l.pauseUntil,
+	)
+}
+
+// GitHub throttles with both 403 and 429
+func isThrottledStatus(statusCode int) bool {
+	return statusCode == http.StatusForbidden || statusCode == http.StatusTooManyRequests
+}
+
+func parseRateLimitHeader(respHeaders http.Header, name string) (int64, bool) {
+	value := respHeaders.Get("X-RateLimit-" + name)
+	if value == "" {
+		value = respHeaders.Get("RateLimit-" + name)
+	}
+	if value == "" {
+		return 0, false
+	}
+	parsed, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		return 0, false
+	}
+	return parsed, true
+}
@@ -0,0 +1,136 @@
+package gitforge
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/atomicmeganerd/starfeed/common"
+	"github.com/atomicmeganerd/starfeed/testutils"
+)
+
+func TestRateLimiterNextDelay(t *testing.T) {
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	reset := now.Add(10 * time.Minute)
+	resetHeader := strconv.FormatInt(reset.Unix(), 10)
+
+	testCases := []struct {
+		name          string
+		headers       http.Header
+		err           error
+		expectedDelay time.Duration
+	}{
+		{
+			name:          "No rate limit headers never waits",
+			headers:       http.Header{},
+			expectedDelay: 0,
+		},
+		{
+			name: "Plenty of budget left does not wait",
+			headers: http.Header{
+				"X-Ratelimit-Remaining": {"4000"},
+				"X-Ratelimit-Reset":     {resetHeader},
+			},
+			expectedDelay: 0,
+		},
+		{
+			name: "Low budget spaces out requests",
+			headers: http.Header{
+				"X-Ratelimit-Remaining": {"100"},
+				"X-Ratelimit-Reset":     {resetHeader},
+			},
+			expectedDelay: maxRequestSpacing,
+		},
+		{
+			name: "Nearly exhausted budget pauses until reset",
+			headers: http.Header{
+				"X-Ratelimit-Remaining": {"5"},
+				"X-Ratelimit-Reset":     {resetHeader},
+			},
+			expectedDelay: 10 * time.Minute,
+		},
+		{
+			name: "GitLab style headers are understood",
+			headers: http.Header{
+				"Ratelimit-Remaining": {"0"},
+				"Ratelimit-Reset":     {resetHeader},
+			},
+			expectedDelay: 10 * time.Minute,
+		},
+		{
+			name:          "Secondary rate limit honours Retry-After",
+			headers:       http.Header{"Retry-After": {"30"}},
+			err:           common.HTTPError{StatusCode: http.StatusForbidden},
+			expectedDelay: 30 * time.Second,
+		},
+		{
+			name:          "429 without any hints pauses for the default",
+			headers:       http.Header{},
+			err:           common.HTTPError{StatusCode: http.StatusTooManyRequests},
+			expectedDelay: defaultSecondaryLimitPause,
+		},
+		{
+			name: "403 with budget left is a permission error and does not pause",
+			headers: http.Header{
+				"X-Ratelimit-Remaining": {"4000"},
+				"X-Ratelimit-Reset":     {resetHeader},
+			},
+			err:           common.HTTPError{StatusCode: http.StatusForbidden},
+			expectedDelay: 0,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			limiter := newRateLimiter(testutils.TestLogger(t))
+			limiter.now = func() time.Time { return now }
+
+			limiter.observe(tc.headers, tc.err)
+			if delay, _ := limiter.nextDelay(); delay != tc.expectedDelay {
+				t.Errorf("nextDelay() = %s, want %s", delay, tc.expectedDelay)
+			}
+		})
+	}
+}
+
+func TestRateLimiterWaitFailsFast(t *testing.T) {
+	limiter := newRateLimiter(testutils.TestLogger(t))
+	limiter.observe(http.Header{
+		"X-Ratelimit-Remaining": {"0"},
+		"X-Ratelimit-Reset":     {strconv.FormatInt(time.Now().Add(time.Hour).Unix(), 10)},
+	}, nil)
+
+	err := limiter.wait(context.Background())
+	if _, ok := errors.AsType[RateLimitError](err); !ok {
+		t.Fatalf("Expected a RateLimitError but got %v", err)
+	}
+}
+
+func TestRateLimiterSpacesOutWorkers(t *testing.T) {
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	limiter := newRateLimiter(testutils.TestLogger(t))
+	limiter.now = func() time.Time { return now }
+	limiter.observe(http.Header{
+		"X-Ratelimit-Remaining": {"50"},
+		"X-Ratelimit-Reset":     {strconv.FormatInt(now.Add(50*time.Second).Unix(), 10)},
+	}, nil)
+
+	// Workers asking at the same time each get their own slot a second apart
+	for ix := range 3 {
+		expected := time.Duration(ix+1) * time.Second
+		if delay, _ := limiter.nextDelay(); delay != expected {
+			t.Errorf("nextDelay() for worker %d = %s, want %s", ix, delay, expected)
+		}
+	}
+
+	// Once the reserved slots have passed the next request only waits for its own gap, which is
+	// now 40s spread over the 50 requests left
+	now = now.Add(10 * time.Second)
+	if delay, _ := limiter.nextDelay(); delay != 800*time.Millisecond {
+		t.Errorf("nextDelay() after the slots passed = %s, want 800ms", delay)
+	}
+}
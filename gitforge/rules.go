@@ -0,0 +1,145 @@
+package gitforge
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// RepoRule includes or excludes the starred repos it matches. A rule matches a repo when every
+// condition that is set matches so a rule without any conditions matches every repo. Rules are
+// checked in order and the first one that matches decides. Repos that no rule matches are
+// included as they always were.
+//
+//   - Owner and Name are globs (e.g. "my-org-*") and OwnerRegex and NameRegex are regular
+//     expressions. Globs and names are compared without case.
+//   - Language matches the primary language the forge detected. It is not compared with case.
+//   - Topics matches if the repo has any of them.
+//   - Fork and Archived match repos that are (or are not) forks or archived.
+//   - MinStars matches repos with at least this many stars.
+type RepoRule struct {
+	Include    bool
+	Owner      string
+	Name       string
+	OwnerRegex *regexp.Regexp
+	NameRegex  *regexp.Regexp
+	Language   string
+	Topics     []string
+	Fork       *bool
+	Archived   *bool
+	MinStars   int
+}
+
+func (r RepoRule) Matches(repo GitRepo) bool {
+	return r.matchesName(repo) && r.matchesMetadata(repo)
+}
+
+func (r RepoRule) matchesName(repo GitRepo) bool {
+	owner, name := repo.OwnerName(), repo.Name.String()
+	if r.Owner != "" && !matchGlob(r.Owner, owner) {
+		return false
+	}
+	if r.Name != "" && !matchGlob(r.Name, name) {
+		return false
+	}
+	if r.OwnerRegex != nil && !r.OwnerRegex.MatchString(owner) {
+		return false
+	}
+	return r.NameRegex == nil || r.NameRegex.MatchString(name)
+}
+
+func (r RepoRule) matchesMetadata(repo GitRepo) bool {
+	if r.Language != "" && !strings.EqualFold(r.Language, repo.Language) {
+		return false
+	}
+	if len(r.Topics) > 0 && !slices.ContainsFunc(r.Topics, repo.hasTopic) {
+		return false
+	}
+	if r.Fork != nil && *r.Fork != repo.Fork {
+		return false
+	}
+	if r.Archived != nil && *r.Archived != repo.Archived {
+		return false
+	}
+	return repo.Stars >= r.MinStars
+}
+
+// Describes the rule for the debug log, e.g. "exclude fork=true min_stars=10"
+func (r RepoRule) String() string {
+	parts := []string{"exclude"}
+	if r.Include {
+		parts[0] = "include"
+	}
+	addPart := func(key string, value any) {
+		parts = append(parts, fmt.Sprintf("%s=%v", key, value))
+	}
+	if r.Owner != "" {
+		addPart("owner", r.Owner)
+	}
+	if r.Name != "" {
+		addPart("name", r.Name)
+	}
+	if r.OwnerRegex != nil {
+		addPart("owner_regex", r.OwnerRegex)
+	}
+	if r.NameRegex != nil {
+		addPart("name_regex", r.NameRegex)
+	}
+	if r.Language != "" {
+		addPart("language", r.Language)
+	}
+	if len(r.Topics) > 0 {
+		addPart("topics", strings.Join(r.Topics, ","))
+	}
+	if r.Fork != nil {
+		addPart("fork", *r.Fork)
+	}
+	if r.Archived != nil {
+		addPart("archived", *r.Archived)
+	}
+	if r.MinStars > 0 {
+		addPart("min_stars", r.MinStars)
+	}
+	return strings.Join(parts, " ")
+}
+
+// A malformed glob never matches anything. The config is validated so we should not see one.
+func matchGlob(pattern, value string) bool {
+	matched, err := path.Match(strings.ToLower(pattern), strings.ToLower(value))
+	return err == nil && matched
+}
+
+// Returns whether the repo is included and the number of the rule (counting from 1) that decided
+// it. The number is 0 if no rule matched.
+func matchRules(rules []RepoRule, repo GitRepo) (bool, int) {
+	for ix, rule := range rules {
+		if rule.Matches(repo) {
+			return rule.Include, ix + 1
+		}
+	}
+	return true, 0
+}
+
+// Drops the starred repos the rules of the forge exclude
+func (c GitForgeClient) filterRepos(repos []GitRepo) []GitRepo {
+	if len(c.rules) == 0 {
+		return repos
+	}
+	included := make([]GitRepo, 0, len(repos))
+	for _, repo := range repos {
+		include, ruleNum := matchRules(c.rules, repo)
+		logger := c.logger.With("repo", repo.FullName, "included", include)
+		if ruleNum == 0 {
+			logger.Debug("No rule matched repo")
+		} else {
+			logger.Debug("Rule matched repo", "rule", ruleNum, "match", c.rules[ruleNum-1].String())
+		}
+		if include {
+			included = append(included, repo)
+		}
+	}
+	c.logger.Info("Applied repo rules", "numRepos", len(repos), "numIncluded", len(included))
+	return included
+}
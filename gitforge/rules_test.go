@@ -0,0 +1,188 @@
+package gitforge
+
+import (
+	"regexp"
+	"slices"
+	"testing"
+)
+
+func TestRepoRuleMatches(t *testing.T) {
+	yes, no := true, false
+	repo := GitRepo{
+		Name:     "starfeed",
+		FullName: "AtomicMegaNerd/starfeed",
+		Owner:    GitRepoOwner{Login: "AtomicMegaNerd"},
+		Language: "Go",
+		Topics:   []string{"rss", "freshrss"},
+		Stars:    42,
+	}
+
+	testCases := []struct {
+		name     string
+		rule     RepoRule
+		expected bool
+	}{
+		{name: "Empty rule matches everything", rule: RepoRule{}, expected: true},
+		{name: "Owner glob ignores case", rule: RepoRule{Owner: "atomic*"}, expected: true},
+		{name: "Owner glob", rule: RepoRule{Owner: "other-*"}, expected: false},
+		{name: "Name glob", rule: RepoRule{Name: "star?eed"}, expected: true},
+		{
+			name:     "Owner regex",
+			rule:     RepoRule{OwnerRegex: regexp.MustCompile(`^Atomic`)},
+			expected: true,
+		},
+		{
+			name:     "Name regex",
+			rule:     RepoRule{NameRegex: regexp.MustCompile(`^awesome-`)},
+			expected: false,
+		},
+		{name: "Language ignores case", rule: RepoRule{Language: "go"}, expected: true},
+		{name: "Language", rule: RepoRule{Language: "Rust"}, expected: false},
+		{name: "Any topic", rule: RepoRule{Topics: []string{"cli", "RSS"}}, expected: true},
+		{name: "No topic", rule: RepoRule{Topics: []string{"cli"}}, expected: false},
+		{name: "Not a fork", rule: RepoRule{Fork: &no}, expected: true},
+		{name: "Fork", rule: RepoRule{Fork: &yes}, expected: false},
+		{name: "Archived", rule: RepoRule{Archived: &yes}, expected: false},
+		{name: "Enough stars", rule: RepoRule{MinStars: 42}, expected: true},
+		{name: "Too few stars", rule: RepoRule{MinStars: 43}, expected: false},
+		{
+			name:     "Every condition has to match",
+			rule:     RepoRule{Owner: "atomicmeganerd", Language: "Rust"},
+			expected: false,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if actual := tc.rule.Matches(repo); actual != tc.expected {
+				t.Errorf("Expected %v for rule %s, got %v", tc.expected, tc.rule, actual)
+			}
+		})
+	}
+}
+
+func TestMatchRules(t *testing.T) {
+	yes := true
+	rules := []RepoRule{
+		{Include: true, Owner: "atomicmeganerd"},
+		{Include: false, Fork: &yes},
+		{Include: true, MinStars: 100},
+		{Include: false},
+	}
+
+	testCases := []struct {
+		name            string
+		repo            GitRepo
+		expectedInclude bool
+		expectedRule    int
+	}{
+		{
+			name:            "First rule wins",
+			repo:            GitRepo{FullName: "atomicmeganerd/fork", Fork: true},
+			expectedInclude: true,
+			expectedRule:    1,
+		},
+		{
+			name:            "Fork is excluded",
+			repo:            GitRepo{FullName: "other/fork", Fork: true, Stars: 500},
+			expectedInclude: false,
+			expectedRule:    2,
+		},
+		{
+			name:            "Popular repo is included",
+			repo:            GitRepo{FullName: "other/popular", Stars: 500},
+			expectedInclude: true,
+			expectedRule:    3,
+		},
+		{
+			name:            "Everything else is excluded",
+			repo:            GitRepo{FullName: "other/bookmark", Stars: 5},
+			expectedInclude: false,
+			expectedRule:    4,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			include, rule := matchRules(rules, tc.repo)
+			if include != tc.expectedInclude || rule != tc.expectedRule {
+				t.Errorf(
+					"Expected include %v by rule %d, got %v by rule %d",
+					tc.expectedInclude, tc.expectedRule, include, rule,
+				)
+			}
+		})
+	}
+
+	if include, rule := matchRules(nil, GitRepo{}); !include || rule != 0 {
+		t.Errorf("Expected repos to be included without rules, got %v by rule %d", include, rule)
+	}
+}
+
+func TestRepoRuleString(t *testing.T) {
+	yes := true
+	rule := RepoRule{
+		Owner:     "org-*",
+		NameRegex: regexp.MustCompile(`^tool`),
+		Topics:    []string{"cli", "go"},
+		Fork:      &yes,
+		MinStars:  10,
+	}
+	expected := "exclude owner=org-* name_regex=^tool topics=cli,go fork=true min_stars=10"
+	if actual := rule.String(); actual != expected {
+		t.Errorf("Expected %q, got %q", expected, actual)
+	}
+}
+
+func TestParseStarredReposMetadata(t *testing.T) {
+	testCases := []struct {
+		name      string
+		forgeType string
+		data      string
+		expected  GitRepo
+	}{
+		{
+			name:      "GitHub",
+			forgeType: GitHubForgeType,
+			data: `[{"name": "tool", "language": "Go", "topics": ["cli"], "fork": true,
+				"stargazers_count": 12}]`,
+			expected: GitRepo{
+				Name: "tool", Language: "Go", Topics: []string{"cli"}, Fork: true, Stars: 12,
+			},
+		},
+		{
+			name:      "Forgejo",
+			forgeType: ForgejoForgeType,
+			data: `[{"name": "tool", "language": "Go", "topics": ["cli"], "fork": true,
+				"stars_count": 12}]`,
+			expected: GitRepo{
+				Name: "tool", Language: "Go", Topics: []string{"cli"}, Fork: true, Stars: 12,
+			},
+		},
+		{
+			name:      "GitLab",
+			forgeType: GitLabForgeType,
+			data: `[{"name": "tool", "topics": ["cli"], "forked_from_project": {"id": 1},
+				"star_count": 12}]`,
+			expected: GitRepo{Name: "tool", Topics: []string{"cli"}, Fork: true, Stars: 12},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			repos, err := parseStarredRepos(tc.forgeType, []byte(tc.data))
+			if err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+			if len(repos) != 1 {
+				t.Fatalf("Expected 1 repo, got %d", len(repos))
+			}
+			actual := repos[0]
+			if actual.Name != tc.expected.Name || actual.Language != tc.expected.Language ||
+				!slices.Equal(actual.Topics, tc.expected.Topics) ||
+				actual.Fork != tc.expected.Fork || actual.Stars != tc.expected.Stars {
+				t.Errorf("Expected %+v, got %+v", tc.expected, actual)
+			}
+		})
+	}
+}
@@ -0,0 +1,153 @@
+package gitforge
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"slices"
+
+	"github.com/atomicmeganerd/starfeed/common"
+)
+
+// RepoSource is where we found a repo. Starred repos are what we always followed but repos that
+// are watched (subscribed to for notifications) can be followed as well.
+type RepoSource string
+
+const (
+	SourceStarred RepoSource = "starred"
+	SourceWatched RepoSource = "watched"
+)
+
+// Fetches the repos of every source for every user we follow. A repo that turns up more than once
+// is only returned once with all of the sources it came from.
+func (c GitForgeClient) fetchRepos(
+	ctx context.Context,
+) ([]GitRepo, error) {
+	usernames := c.usernames
+	if len(usernames) == 0 {
+		// The owner of the token
+		usernames = []string{""}
+	}
+
+	allRepos := make([]GitRepo, 0)
+	seen := make(map[common.FeedURL]int)
+	for _, source := range c.sources {
+		for _, username := range usernames {
+			repos, err := c.fetchReposOf(ctx, source, username)
+			if err != nil {
+				return nil, err
+			}
+			for _, repo := range repos {
+				ix, ok := seen[repo.FeedURL]
+				if !ok {
+					repo.Sources = []RepoSource{source}
+					seen[repo.FeedURL] = len(allRepos)
+					allRepos = append(allRepos, repo)
+					continue
+				}
+				if !slices.Contains(allRepos[ix].Sources, source) {
+					allRepos[ix].Sources = append(allRepos[ix].Sources, source)
+				}
+			}
+		}
+	}
+	if len(c.usernames) > 0 || len(c.sources) > 1 {
+		c.logger.Info("Finished loading repos of all sources", "numRepos", len(allRepos))
+	}
+	return allRepos, nil
+}
+
+// Fetches the repos of a single source and user or of the token owner if the username is empty
+func (c GitForgeClient) fetchReposOf(
+	ctx context.Context,
+	source RepoSource,
+	username string,
+) ([]GitRepo, error) {
+	nextPageURL, err := c.sourceURL(ctx, source, username)
+	if err != nil {
+		return nil, err
+	}
+
+	allRepos := make([]GitRepo, 0)
+	for {
+		c.logger.Debug("Fetching repos", "source", source, "url", nextPageURL)
+		data, respHeaders, err := c.doRequest(ctx, nextPageURL)
+		if err != nil {
+			return nil, fmt.Errorf(
+				"error %w getting raw data from gitforge url: %s", err, nextPageURL,
+			)
+		}
+
+		repos, err := parseStarredRepos(c.forgeType, data)
+		if err != nil {
+			return nil, fmt.Errorf(
+				"error %w parsing JSON response from gitforge", err,
+			)
+		}
+
+		for ix := range repos {
+			repos[ix] = c.withFeedURLs(repos[ix])
+		}
+		allRepos = append(allRepos, repos...)
+
+		nextPageURL = c.parseNextPageURL(nextPageURL, respHeaders)
+		if nextPageURL == "" {
+			c.logger.Info(
+				"Finished loading repos",
+				"source", source,
+				"username", username,
+				"numRepos", len(allRepos),
+			)
+			return allRepos, nil
+		}
+	}
+}
+
+// VerifyAccess fetches the first page of starred repos to check that the token works without
+// loading every page and feed. It returns how many repos were on that page.
+func (c GitForgeClient) VerifyAccess(ctx context.Context) (int, error) {
+	username := ""
+	if len(c.usernames) > 0 {
+		username = c.usernames[0]
+	}
+	pageURL, err := c.starredRepoURL(ctx, username)
+	if err != nil {
+		return 0, err
+	}
+	data, _, err := c.doRequest(ctx, pageURL)
+	if err != nil {
+		return 0, fmt.Errorf("error %w getting raw data from gitforge url: %s", err, pageURL)
+	}
+	repos, err := parseStarredRepos(c.forgeType, data)
+	if err != nil {
+		return 0, fmt.Errorf("error %w parsing JSON response from gitforge", err)
+	}
+	return len(repos), nil
+}
+
+func (c GitForgeClient) sourceURL(
+	ctx context.Context,
+	source RepoSource,
+	username string,
+) (string, error) {
+	if source == SourceStarred {
+		return c.starredRepoURL(ctx, username)
+	}
+	if c.forgeType == GitLabForgeType {
+		return "", fmt.Errorf("gitlab does not have watched repos")
+	}
+	return buildWatchedRepoURL(c.forgeType, c.apiURL, username), nil
+}
+
+// GitHub and Forgejo call watched repos subscriptions. Without a user they list the repos the
+// token owner watches.
+func buildWatchedRepoURL(forgeType, apiURL, user string) string {
+	path := "user/subscriptions"
+	if user != "" {
+		path = fmt.Sprintf("users/%s/subscriptions", url.PathEscape(user))
+	}
+	if forgeType == GitHubForgeType {
+		return fmt.Sprintf("%s/%s?per_page=100", apiURL, path)
+	}
+	return fmt.Sprintf("%s/%s?limit=100", apiURL, path)
+}
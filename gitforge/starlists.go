@@ -0,0 +1,217 @@
+package gitforge
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/atomicmeganerd/starfeed/common"
+)
+
+// GitHub only exposes star lists through its GraphQL API. We ask for the first page of items of
+// every list along with the lists themselves and only go back for lists with more items. The
+// lists are those of the token owner (the viewer) or of the user we follow under the same alias.
+const starListsFields = `lists(first: 100, after: $after) {
+    pageInfo { hasNextPage endCursor }
+    nodes {
+      id
+      name
+      items(first: 100) {
+        pageInfo { hasNextPage endCursor }
+        nodes { ... on Repository { databaseId } }
+      }
+    }
+  }`
+
+const viewerStarListsQuery = `query($after: String) {
+  owner: viewer { ` + starListsFields + ` }
+}`
+
+const userStarListsQuery = `query($login: String!, $after: String) {
+  owner: user(login: $login) { ` + starListsFields + ` }
+}`
+
+const starListItemsQuery = `query($id: ID!, $after: String) {
+  node(id: $id) {
+    ... on UserList {
+      items(first: 100, after: $after) {
+        pageInfo { hasNextPage endCursor }
+        nodes { ... on Repository { databaseId } }
+      }
+    }
+  }
+}`
+
+type graphQLRequest struct {
+	Query     string         `json:"query"`
+	Variables map[string]any `json:"variables"`
+}
+
+// GraphQL reports most errors with a 200 so we have to look for them in the body
+type graphQLResponse struct {
+	Data   json.RawMessage `json:"data"`
+	Errors []struct {
+		Message string `json:"message"`
+	} `json:"errors"`
+}
+
+type graphQLPageInfo struct {
+	HasNextPage bool   `json:"hasNextPage"`
+	EndCursor   string `json:"endCursor"`
+}
+
+type starListItems struct {
+	PageInfo graphQLPageInfo `json:"pageInfo"`
+	Nodes    []struct {
+		// Items that are not repos have no id
+		DatabaseID int64 `json:"databaseId"`
+	} `json:"nodes"`
+}
+
+type starList struct {
+	ID    string        `json:"id"`
+	Name  string        `json:"name"`
+	Items starListItems `json:"items"`
+}
+
+type starListsData struct {
+	Owner struct {
+		Lists struct {
+			PageInfo graphQLPageInfo `json:"pageInfo"`
+			Nodes    []starList      `json:"nodes"`
+		} `json:"lists"`
+	} `json:"owner"`
+}
+
+type starListItemsData struct {
+	Node struct {
+		Items starListItems `json:"items"`
+	} `json:"node"`
+}
+
+// Returns the name of the star list each repo is in keyed by the repo id. A repo can be in more
+// than one list in which case it goes in the first list GitHub returns. When we follow the stars
+// of other users we use their lists in the order the users were configured.
+func (c GitForgeClient) fetchStarLists(ctx context.Context) (map[int64]string, error) {
+	repoLists := make(map[int64]string)
+	if len(c.usernames) == 0 {
+		if err := c.fetchStarListsOf(ctx, "", repoLists); err != nil {
+			return nil, err
+		}
+	}
+	for _, username := range c.usernames {
+		if err := c.fetchStarListsOf(ctx, username, repoLists); err != nil {
+			return nil, err
+		}
+	}
+	c.logger.Info("Finished loading star lists", "numRepos", len(repoLists))
+	return repoLists, nil
+}
+
+// Adds the lists of a single user or of the token owner if the username is empty
+func (c GitForgeClient) fetchStarListsOf(
+	ctx context.Context,
+	username string,
+	repoLists map[int64]string,
+) error {
+	query, variables := viewerStarListsQuery, map[string]any{}
+	if username != "" {
+		query, variables = userStarListsQuery, map[string]any{"login": username}
+	}
+	after := ""
+	for {
+		data := starListsData{}
+		if err := c.doGraphQL(ctx, query, pageVariables(variables, after), &data); err != nil {
+			return fmt.Errorf("error %w fetching star lists", err)
+		}
+		lists := data.Owner.Lists
+		for _, list := range lists.Nodes {
+			if err := c.addStarListItems(ctx, list, repoLists); err != nil {
+				return err
+			}
+		}
+		if !lists.PageInfo.HasNextPage {
+			return nil
+		}
+		after = lists.PageInfo.EndCursor
+	}
+}
+
+func (c GitForgeClient) addStarListItems(
+	ctx context.Context,
+	list starList,
+	repoLists map[int64]string,
+) error {
+	items := list.Items
+	for {
+		for _, item := range items.Nodes {
+			if _, listed := repoLists[item.DatabaseID]; item.DatabaseID != 0 && !listed {
+				repoLists[item.DatabaseID] = list.Name
+			}
+		}
+		if !items.PageInfo.HasNextPage {
+			return nil
+		}
+		c.logger.Debug("Fetching more items of star list", "list", list.Name)
+		variables := pageVariables(map[string]any{"id": list.ID}, items.PageInfo.EndCursor)
+		data := starListItemsData{}
+		if err := c.doGraphQL(ctx, starListItemsQuery, variables, &data); err != nil {
+			return fmt.Errorf("error %w fetching items of star list %s", err, list.Name)
+		}
+		items = data.Node.Items
+	}
+}
+
+// The first page has no cursor
+func pageVariables(variables map[string]any, after string) map[string]any {
+	if variables == nil {
+		variables = make(map[string]any)
+	}
+	if after != "" {
+		variables["after"] = after
+	}
+	return variables
+}
+
+// Queries the GraphQL API and decodes the data of the response into data
+func (c GitForgeClient) doGraphQL(
+	ctx context.Context,
+	query string,
+	variables map[string]any,
+	data any,
+) error {
+	payload, err := json.Marshal(graphQLRequest{Query: query, Variables: variables})
+	if err != nil {
+		return err
+	}
+	if err := c.limiter.wait(ctx); err != nil {
+		return err
+	}
+	headers, err := c.requestHeaders(ctx)
+	if err != nil {
+		return err
+	}
+	body, respHeaders, err := common.DoAPIRequest(
+		ctx, http.MethodPost, c.graphQLURL(), payload, headers, c.client, c.retry,
+	)
+	c.limiter.observe(respHeaders, err)
+	if err != nil {
+		return err
+	}
+	resp := graphQLResponse{}
+	if err := json.Unmarshal(body, &resp); err != nil {
+		return err
+	}
+	if len(resp.Errors) > 0 {
+		return fmt.Errorf("graphql error: %s", resp.Errors[0].Message)
+	}
+	return json.Unmarshal(resp.Data, data)
+}
+
+// GitHub serves GraphQL next to the REST API. On GitHub Enterprise Server the REST API is under
+// /api/v3 and GraphQL is under /api/graphql.
+func (c GitForgeClient) graphQLURL() string {
+	return strings.TrimSuffix(c.apiURL, "/v3") + "/graphql"
+}
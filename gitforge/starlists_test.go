@@ -0,0 +1,174 @@
+package gitforge
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/atomicmeganerd/starfeed/common"
+	"github.com/atomicmeganerd/starfeed/testutils"
+)
+
+func TestLoadFeedsStarLists(t *testing.T) {
+	starred := `[
+		{"id": 1, "name": "one", "full_name": "org/one", "html_url": "https://github.com/org/one"},
+		{"id": 2, "name": "two", "full_name": "org/two", "html_url": "https://github.com/org/two"},
+		{"id": 3, "name": "three", "full_name": "org/three",
+			"html_url": "https://github.com/org/three"},
+		{"id": 4, "name": "four", "full_name": "org/four",
+			"html_url": "https://github.com/org/four"}
+	]`
+	// The first list has a second page of items and repo 1 is in both lists
+	lists := `{"data": {"owner": {"lists": {
+		"pageInfo": {"hasNextPage": false, "endCursor": "L2"},
+		"nodes": [
+			{"id": "L_db", "name": "Databases", "items": {
+				"pageInfo": {"hasNextPage": true, "endCursor": "I1"},
+				"nodes": [{"databaseId": 1}]
+			}},
+			{"id": "L_go", "name": "Go tooling", "items": {
+				"pageInfo": {"hasNextPage": false, "endCursor": "I2"},
+				"nodes": [{"databaseId": 2}, {"databaseId": 1}]
+			}}
+		]
+	}}}}`
+	moreItems := `{"data": {"node": {"items": {
+		"pageInfo": {"hasNextPage": false, "endCursor": "I3"},
+		"nodes": [{"databaseId": 3}]
+	}}}}`
+	validFeed := `<feed xmlns="http://www.w3.org/2005/Atom"><entry><title>v1</title></entry></feed>`
+
+	testCases := []struct {
+		name              string
+		forgeType         string
+		graphQLResponses  []string
+		expectErr         bool
+		expectedStarLists map[common.FeedURL]string
+	}{
+		{
+			name:             "Repos get the first list they are in",
+			forgeType:        GitHubForgeType,
+			graphQLResponses: []string{lists, moreItems},
+			expectedStarLists: map[common.FeedURL]string{
+				"https://github.com/org/one/releases.atom":   "Databases",
+				"https://github.com/org/two/releases.atom":   "Go tooling",
+				"https://github.com/org/three/releases.atom": "Databases",
+				"https://github.com/org/four/releases.atom":  "",
+			},
+		},
+		{
+			name:             "GraphQL errors fail the load",
+			forgeType:        GitHubForgeType,
+			graphQLResponses: []string{`{"errors": [{"message": "Bad credentials"}]}`},
+			expectErr:        true,
+		},
+		{
+			name:      "Star lists are ignored on other forges",
+			forgeType: ForgejoForgeType,
+			expectedStarLists: map[common.FeedURL]string{
+				"https://github.com/org/one/releases.atom":   "",
+				"https://github.com/org/two/releases.atom":   "",
+				"https://github.com/org/three/releases.atom": "",
+				"https://github.com/org/four/releases.atom":  "",
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			mocks := make([]testutils.MockRoutedResponse, 0)
+			for _, body := range tc.graphQLResponses {
+				mocks = append(mocks, testutils.MockRoutedResponse{
+					UrlPattern: `/graphql$`,
+					Response: http.Response{
+						Body:       io.NopCloser(strings.NewReader(body)),
+						StatusCode: http.StatusOK,
+					},
+					MaxMatches: 1,
+				})
+			}
+			mocks = append(mocks, testutils.MockRoutedResponse{
+				UrlPattern: `/user/starred`,
+				Response: http.Response{
+					Body:       io.NopCloser(strings.NewReader(starred)),
+					StatusCode: http.StatusOK,
+				},
+			})
+			for _, repo := range []string{"one", "two", "three", "four"} {
+				mocks = append(mocks, testutils.MockRoutedResponse{
+					UrlPattern: `org/` + repo + `/releases\.atom$`,
+					Response: http.Response{
+						Body:       io.NopCloser(strings.NewReader(validFeed)),
+						StatusCode: http.StatusOK,
+					},
+				})
+			}
+			mockTransport := testutils.NewMockRoutedResponseRoundTripper(mocks)
+			mockClient := &http.Client{Transport: &mockTransport}
+
+			forge := NewGitForgeClient(
+				GitForgeOptions{
+					Type:      tc.forgeType,
+					APIURL:    "https://api.github.com",
+					StarLists: true,
+				},
+				testutils.GitHubToken,
+				testutils.TestLogger(t),
+				mockClient,
+				common.RetryPolicy{},
+			)
+
+			actual, err := forge.LoadFeeds(context.Background())
+			if tc.expectErr {
+				if err == nil {
+					t.Fatal("Expected an error, got nil")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+			if len(tc.expectedStarLists) != len(actual) {
+				t.Fatalf("Expected %d results, got %v", len(tc.expectedStarLists), actual)
+			}
+			for feedURL, expected := range tc.expectedStarLists {
+				if starList := actual[feedURL].StarList; starList != expected {
+					t.Errorf("Feed %s: expected star list %q, got %q", feedURL, expected, starList)
+				}
+			}
+		})
+	}
+}
+
+func TestGraphQLURL(t *testing.T) {
+	testCases := []struct {
+		name     string
+		opts     GitForgeOptions
+		expected string
+	}{
+		{
+			name:     "GitHub",
+			opts:     GitForgeOptions{Type: GitHubForgeType, Fqdn: "github.com"},
+			expected: "https://api.github.com/graphql",
+		},
+		{
+			name:     "GitHub Enterprise Server",
+			opts:     GitForgeOptions{Type: GitHubForgeType, APIURL: "https://ghe.corp/api/v3/"},
+			expected: "https://ghe.corp/api/graphql",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			forge := NewGitForgeClient(
+				tc.opts, "", testutils.TestLogger(t), nil, common.RetryPolicy{},
+			)
+			if actual := forge.graphQLURL(); actual != tc.expected {
+				t.Errorf("Expected %s, got %s", tc.expected, actual)
+			}
+		})
+	}
+}
@@ -71,6 +71,7 @@ func TestAuthenticate(t *testing.T) {
 				testutils.FreshRSSURL,
 				testutils.TestLogger(t),
 				mockClient,
+				common.RetryPolicy{},
 			)
 			err := f.Authenticate(context.Background(), testutils.FreshRSSToken)
 
@@ -195,6 +196,7 @@ func TestAddFeed(t *testing.T) {
 				testutils.FreshRSSURL,
 				testutils.TestLogger(t),
 				mockClient,
+				common.RetryPolicy{},
 			)
 
 			err := f.AddFeed(ctx, "http://localhost/feeds/123", "name", "category")
@@ -217,7 +219,7 @@ func TestLoadFeeds(t *testing.T) {
 		name            string
 		gitForge        string
 		responses       []http.Response
-		expectedFeedMap *common.Set[common.FeedURL]
+		expectedFeedMap FeedMap
 		expectError     bool
 	}{
 		{
@@ -230,6 +232,7 @@ func TestLoadFeeds(t *testing.T) {
 							"subscriptions": [
 								{
 									"url": "http://localhost/feeds/123",
+									"title": "repo123",
 									"categories": [
 										{
 											"label": "GitHub"
@@ -238,6 +241,7 @@ func TestLoadFeeds(t *testing.T) {
 								},
 								{
 									"url": "http://localhost/feeds/456",
+									"title": "repo456",
 									"categories": [
 										{
 											"label": "GitHub"
@@ -251,10 +255,10 @@ func TestLoadFeeds(t *testing.T) {
 					Status:     testutils.StatusOKString,
 				},
 			},
-			expectedFeedMap: common.NewSet[common.FeedURL](
-				"http://localhost/feeds/123",
-				"http://localhost/feeds/456",
-			),
+			expectedFeedMap: FeedMap{
+				"http://localhost/feeds/123": "repo123",
+				"http://localhost/feeds/456": "repo456",
+			},
 			expectError: false,
 		},
 		{
@@ -266,7 +270,7 @@ func TestLoadFeeds(t *testing.T) {
 					Status:     testutils.StatusUnauthorizedString,
 				},
 			},
-			expectedFeedMap: common.NewSet[common.FeedURL](),
+			expectedFeedMap: FeedMap{},
 			expectError:     true,
 		},
 		{
@@ -278,7 +282,7 @@ func TestLoadFeeds(t *testing.T) {
 					Status:     testutils.StatusOKString,
 				},
 			},
-			expectedFeedMap: common.NewSet[common.FeedURL](),
+			expectedFeedMap: FeedMap{},
 			expectError:     true,
 		},
 	}
@@ -295,6 +299,7 @@ func TestLoadFeeds(t *testing.T) {
 				testutils.FreshRSSURL,
 				testutils.TestLogger(t),
 				mockClient,
+				common.RetryPolicy{},
 			)
 
 			feeds, err := f.LoadFeeds(ctx, FeedCategory(tc.gitForge))
@@ -308,13 +313,14 @@ func TestLoadFeeds(t *testing.T) {
 					t.Errorf("Expected no error but got %v", err)
 				}
 
-				if feeds.Len() != tc.expectedFeedMap.Len() {
-					t.Errorf("Expected %d feeds but got %d", tc.expectedFeedMap.Len(), feeds.Len())
+				if len(feeds) != len(tc.expectedFeedMap) {
+					t.Errorf("Expected %d feeds but got %d", len(tc.expectedFeedMap), len(feeds))
 				}
 
-				for feed := range feeds.All() {
-					if !tc.expectedFeedMap.Contains(feed) {
-						t.Errorf("Unexpected feed %s", feed)
+				for feed, title := range feeds {
+					expectedTitle, ok := tc.expectedFeedMap[feed]
+					if !ok || expectedTitle != title {
+						t.Errorf("Unexpected feed %s with title %s", feed, title)
 					}
 				}
 			}
@@ -367,6 +373,7 @@ func TestRemoveFeed(t *testing.T) {
 				testutils.FreshRSSURL,
 				testutils.TestLogger(t),
 				mockClient,
+				common.RetryPolicy{},
 			)
 
 			err := f.RemoveFeed(ctx, tc.feedURL)
@@ -384,3 +391,201 @@ func TestRemoveFeed(t *testing.T) {
 		})
 	}
 }
+
+func TestMigrateFeed(t *testing.T) {
+
+	testCases := []struct {
+		name        string
+		responses   []http.Response
+		expectURL   common.FeedURL
+		expectError bool
+	}{
+		{
+			name: "Migrating keeps the old feed URL",
+			responses: []http.Response{
+				{
+					Body:       io.NopCloser(strings.NewReader(`OK`)),
+					StatusCode: http.StatusOK,
+					Status:     testutils.StatusOKString,
+				},
+			},
+			expectURL:   "https://github.com/user/old-name/releases.atom",
+			expectError: false,
+		},
+		{
+			name: "Failure response should return error",
+			responses: []http.Response{
+				{
+					Body:       io.NopCloser(strings.NewReader(`{"error": "error"}`)),
+					StatusCode: http.StatusUnauthorized,
+					Status:     testutils.StatusUnauthorizedString,
+				},
+			},
+			expectError: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			ctx := context.Background()
+			mockTransport := testutils.NewMockMultiResponseRoundTripper(tc.responses)
+			mockClient := &http.Client{Transport: &mockTransport}
+
+			f := NewFreshRSSClient(
+				testutils.FreshRSSUser,
+				testutils.FreshRSSURL,
+				testutils.TestLogger(t),
+				mockClient,
+				common.RetryPolicy{},
+			)
+
+			feedURL, err := f.MigrateFeed(
+				ctx,
+				"https://github.com/user/old-name/releases.atom",
+				"https://github.com/user/new-name/releases.atom",
+				"new-name",
+				"category",
+				"category",
+			)
+
+			if tc.expectError {
+				if err == nil {
+					t.Fatalf("Expected error but got nil")
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("Expected no error but got %v", err)
+			}
+			if feedURL != tc.expectURL {
+				t.Errorf("Expected feed URL %s but got %s", tc.expectURL, feedURL)
+			}
+		})
+	}
+}
+
+func TestMoveFeed(t *testing.T) {
+
+	testCases := []struct {
+		name        string
+		responses   []http.Response
+		expectError bool
+	}{
+		{
+			name: "Successful feed move",
+			responses: []http.Response{
+				{
+					Body:       io.NopCloser(strings.NewReader(`OK`)),
+					StatusCode: http.StatusOK,
+					Status:     testutils.StatusOKString,
+				},
+			},
+			expectError: false,
+		},
+		{
+			name: "Failure response should return error",
+			responses: []http.Response{
+				{
+					Body:       io.NopCloser(strings.NewReader(`{"error": "error"}`)),
+					StatusCode: http.StatusUnauthorized,
+					Status:     testutils.StatusUnauthorizedString,
+				},
+			},
+			expectError: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			ctx := context.Background()
+			mockTransport := testutils.NewMockMultiResponseRoundTripper(tc.responses)
+			mockClient := &http.Client{Transport: &mockTransport}
+
+			f := NewFreshRSSClient(
+				testutils.FreshRSSUser,
+				testutils.FreshRSSURL,
+				testutils.TestLogger(t),
+				mockClient,
+				common.RetryPolicy{},
+			)
+
+			err := f.MoveFeed(ctx, "http://localhost/feeds/124", "GitHub", "GitHub Archived")
+
+			if tc.expectError {
+				if err == nil {
+					t.Fatalf("Expected error but got nil")
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("Expected no error but got %v", err)
+			}
+		})
+	}
+}
+
+func TestRenameFeed(t *testing.T) {
+
+	testCases := []struct {
+		name        string
+		responses   []http.Response
+		expectError bool
+	}{
+		{
+			name: "Successful feed rename",
+			responses: []http.Response{
+				{
+					Body:       io.NopCloser(strings.NewReader(`OK`)),
+					StatusCode: http.StatusOK,
+					Status:     testutils.StatusOKString,
+				},
+			},
+			expectError: false,
+		},
+		{
+			name: "Failure response should return error",
+			responses: []http.Response{
+				{
+					Body:       io.NopCloser(strings.NewReader(`{"error": "error"}`)),
+					StatusCode: http.StatusUnauthorized,
+					Status:     testutils.StatusUnauthorizedString,
+				},
+			},
+			expectError: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			ctx := context.Background()
+			mockTransport := testutils.NewMockMultiResponseRoundTripper(tc.responses)
+			mockClient := &http.Client{Transport: &mockTransport}
+
+			f := NewFreshRSSClient(
+				testutils.FreshRSSUser,
+				testutils.FreshRSSURL,
+				testutils.TestLogger(t),
+				mockClient,
+				common.RetryPolicy{},
+			)
+
+			err := f.RenameFeed(ctx, "http://localhost/feeds/124", "user/repo releases")
+
+			if tc.expectError {
+				if err == nil {
+					t.Fatalf("Expected error but got nil")
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("Expected no error but got %v", err)
+			}
+		})
+	}
+}
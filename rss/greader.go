@@ -0,0 +1,324 @@
+package rss
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/atomicmeganerd/starfeed/common"
+)
+
+// The Google Reader API lives under this path on every server, below its base path
+const greaderAPIPath = "/reader/api/0"
+
+// GReaderOptions holds what differs between servers that implement the Google Reader API. The
+// zero value works for servers that serve the API from the root of their URL.
+type GReaderOptions struct {
+	// Path of the API below the server URL, e.g. /api/greader.php for FreshRSS
+	BasePath string
+	// Path of the ClientLogin endpoint below the base path. Defaults to /accounts/ClientLogin.
+	LoginPath string
+	// Prefix of the stream ids of labels, which are our categories. Defaults to user/-/label/
+	// where - stands for the user we logged in as.
+	LabelPrefix string
+}
+
+func (o GReaderOptions) loginPath() string {
+	if o.LoginPath == "" {
+		return "/accounts/ClientLogin"
+	}
+	return o.LoginPath
+}
+
+func (o GReaderOptions) labelPrefix() string {
+	if o.LabelPrefix == "" {
+		return "user/-/label/"
+	}
+	return o.LabelPrefix
+}
+
+// GReaderClient struct is for connecting to servers with the Google Reader API, such as
+// FreshRSS. You can then Load/Add/Remove RSS feeds too/from the server.
+type GReaderClient struct {
+	user    string
+	url     string
+	opts    GReaderOptions
+	logger  *slog.Logger
+	headers http.Header
+	client  *http.Client
+	retry   common.RetryPolicy
+}
+
+func NewGReaderClient(
+	user, url string,
+	opts GReaderOptions,
+	logger *slog.Logger,
+	client *http.Client,
+	retry common.RetryPolicy,
+) *GReaderClient {
+	headers := http.Header{}
+	headers.Set("Content-type", "application/x-www-form-urlencoded")
+	return &GReaderClient{
+		user:    user,
+		url:     url,
+		opts:    opts,
+		logger:  logger,
+		headers: headers,
+		client:  client,
+		retry:   retry,
+	}
+}
+
+// This function will authenticate to the server with ClientLogin.
+func (c *GReaderClient) Authenticate(
+	ctx context.Context,
+	token string,
+) error {
+	reqURL := c.url + c.opts.BasePath + c.opts.loginPath()
+	c.logger.Debug("Authenticating to Google Reader API", "url", reqURL)
+	formData := []byte(
+		url.Values{
+			"Email":  {c.user},
+			"Passwd": {token},
+		}.Encode(),
+	)
+	data, _, err := common.DoAPIRequest(
+		ctx, http.MethodPost, reqURL, formData, c.headers, c.client, c.retry,
+	)
+	if err != nil {
+		return fmt.Errorf("error authenticating to greader server: %w, url: %s", err, reqURL)
+	}
+
+	var authToken string
+	lines := strings.SplitSeq(string(data), "\n")
+	for line := range lines {
+		if after, ok := strings.CutPrefix(line, "Auth="); ok {
+			authToken = after
+		}
+	}
+
+	if authToken == "" {
+		return errors.New("failed to parse authtoken returned from greader server")
+	}
+
+	// We can set all required headers after we authenticate
+	c.headers.Set("Authorization", fmt.Sprintf("GoogleLogin auth=%s", authToken))
+	return nil
+}
+
+// Load all feeds that are under the given category along with their titles.
+func (c *GReaderClient) LoadFeeds(ctx context.Context, category FeedCategory) (FeedMap, error) {
+	newFeeds := FeedMap{}
+	loadUrl := c.apiURL("/subscription/list?output=json")
+	res, _, err := common.DoAPIRequest(
+		ctx, http.MethodGet, loadUrl, nil, c.headers, c.client, c.retry,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	// Parse the response
+	feedList := &RSSFeedList{}
+	if err = json.Unmarshal(res, &feedList); err != nil {
+		return nil, err
+	}
+
+	for _, feed := range feedList.Feeds {
+		// Only add feeds that are from the category that we care about
+		for _, catStruct := range feed.Categories {
+			if c.isLabel(catStruct, category) {
+				newFeeds[feed.URL] = feed.Title
+			}
+		}
+	}
+
+	numFeeds := len(newFeeds)
+	if numFeeds == 0 {
+		c.logger.Warn("No feeds found in our RSS server", "numFeeds", numFeeds)
+	} else {
+		c.logger.Info(
+			"Loaded existing feeds from RSS server", "numFeeds", numFeeds, "category", category,
+		)
+	}
+	return newFeeds, nil
+}
+
+func (c *GReaderClient) AddFeed(
+	ctx context.Context,
+	feedURL common.FeedURL,
+	name FeedName,
+	category FeedCategory,
+) error {
+
+	addUrl := c.apiURL("/subscription/quickadd")
+	formData := url.Values{
+		"quickadd": {feedURL.String()},
+	}
+	res, _, err := common.DoAPIRequest(
+		ctx, http.MethodPost, addUrl, []byte(formData.Encode()), c.headers, c.client, c.retry,
+	)
+	if err != nil {
+		return err
+	}
+
+	feedResponse := &GReaderAddFeedResponse{}
+	if err = json.Unmarshal(res, &feedResponse); err != nil {
+		return err
+	}
+
+	// Add the sub to the category
+	if err = c.addFeedToCategory(ctx, name, category, feedResponse.StreamId); err != nil {
+		return err
+	}
+
+	c.logger.Info("Successfully added feed", "feed", feedURL)
+	return nil
+}
+
+func (c *GReaderClient) RemoveFeed(ctx context.Context, feedURL common.FeedURL) error {
+	editUrl := c.apiURL("/subscription/edit")
+	formData := url.Values{
+		"ac": {"unsubscribe"},
+		"s":  {fmt.Sprintf("feed/%s", feedURL)},
+	}
+
+	// We do not care about the response
+	if _, _, err := common.DoAPIRequest(
+		ctx, http.MethodPost, editUrl, []byte(formData.Encode()), c.headers, c.client, c.retry,
+	); err != nil {
+		return err
+	}
+
+	c.logger.Info("Removed feed", "feed", feedURL)
+	return nil
+}
+
+func (c *GReaderClient) RenameFeed(
+	ctx context.Context,
+	feedURL common.FeedURL,
+	name FeedName,
+) error {
+	editUrl := c.apiURL("/subscription/edit")
+	formData := url.Values{
+		"ac": {"edit"},
+		"s":  {fmt.Sprintf("feed/%s", feedURL)},
+		"t":  {name.String()},
+	}
+
+	if _, _, err := common.DoAPIRequest(
+		ctx, http.MethodPost, editUrl, []byte(formData.Encode()), c.headers, c.client, c.retry,
+	); err != nil {
+		return err
+	}
+
+	c.logger.Info("Renamed feed", "feed", feedURL, "name", name)
+	return nil
+}
+
+// Moves the feed by adding the label of the new category and removing the old one in one edit
+func (c *GReaderClient) MoveFeed(
+	ctx context.Context,
+	feedURL common.FeedURL,
+	from FeedCategory,
+	to FeedCategory,
+) error {
+	editUrl := c.apiURL("/subscription/edit")
+	formData := url.Values{
+		"ac": {"edit"},
+		"s":  {fmt.Sprintf("feed/%s", feedURL)},
+		"a":  {c.label(to)},
+		"r":  {c.label(from)},
+	}
+
+	if _, _, err := common.DoAPIRequest(
+		ctx, http.MethodPost, editUrl, []byte(formData.Encode()), c.headers, c.client, c.retry,
+	); err != nil {
+		return err
+	}
+
+	c.logger.Info("Moved feed", "feed", feedURL, "from", from, "to", to)
+	return nil
+}
+
+// The Google Reader API has no way to change the URL of a subscription and unsubscribing would
+// throw away the read state and history we are trying to keep. The forges redirect the old URL of
+// a renamed or transferred repo so we keep subscribing to it and only update the title and, like
+// MoveFeed, swap the label if the category changed as well.
+func (c *GReaderClient) MigrateFeed(
+	ctx context.Context,
+	from common.FeedURL,
+	to common.FeedURL,
+	name FeedName,
+	fromCategory FeedCategory,
+	toCategory FeedCategory,
+) (common.FeedURL, error) {
+	editUrl := c.apiURL("/subscription/edit")
+	formData := url.Values{
+		"ac": {"edit"},
+		"s":  {fmt.Sprintf("feed/%s", from)},
+		"t":  {name.String()},
+		"a":  {c.label(toCategory)},
+	}
+	if fromCategory != toCategory {
+		formData.Set("r", c.label(fromCategory))
+	}
+
+	if _, _, err := common.DoAPIRequest(
+		ctx, http.MethodPost, editUrl, []byte(formData.Encode()), c.headers, c.client, c.retry,
+	); err != nil {
+		return "", err
+	}
+
+	c.logger.Info("Migrated feed by keeping its old URL", "feed", from, "newFeed", to)
+	return from, nil
+}
+
+func (c *GReaderClient) addFeedToCategory(
+	ctx context.Context,
+	name FeedName,
+	category FeedCategory,
+	streamId string,
+) error {
+	addCategoryUrl := c.apiURL("/subscription/edit")
+	formData := url.Values{
+		"ac": {"edit"},
+		"s":  {streamId},
+		"t":  {name.String()},
+		"a":  {c.label(category)},
+	}
+
+	if _, _, err := common.DoAPIRequest(
+		ctx,
+		http.MethodPost,
+		addCategoryUrl,
+		[]byte(formData.Encode()),
+		c.headers,
+		c.client,
+		c.retry,
+	); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (c *GReaderClient) apiURL(path string) string {
+	return c.url + c.opts.BasePath + greaderAPIPath + path
+}
+
+// The stream id of the label of a category
+func (c *GReaderClient) label(category FeedCategory) string {
+	return c.opts.labelPrefix() + category.String()
+}
+
+// Most servers send the name of the label but some only send its stream id
+func (c *GReaderClient) isLabel(catStruct RSSFeedCategory, category FeedCategory) bool {
+	if catStruct.Label != "" {
+		return catStruct.Label == category
+	}
+	return strings.HasSuffix(catStruct.ID, "/label/"+category.String())
+}
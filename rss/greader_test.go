@@ -0,0 +1,203 @@
+package rss
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+
+	"github.com/atomicmeganerd/starfeed/common"
+	"github.com/atomicmeganerd/starfeed/testutils"
+)
+
+// A fake greader server that remembers the path and form of every request it gets
+type fakeGReader struct {
+	mu        sync.Mutex
+	loginPath string
+	apiPath   string
+	feeds     string
+	requests  []fakeGReaderRequest
+}
+
+type fakeGReaderRequest struct {
+	path string
+	form url.Values
+}
+
+func (f *fakeGReader) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	_ = r.ParseForm()
+	f.requests = append(f.requests, fakeGReaderRequest{path: r.URL.Path, form: r.PostForm})
+	switch r.URL.Path {
+	case f.loginPath:
+		_, _ = fmt.Fprintf(w, "SID=%s\nAuth=%s\n", mockSid, mockAuthToken)
+	case f.apiPath + "/subscription/list":
+		_, _ = fmt.Fprint(w, f.feeds)
+	case f.apiPath + "/subscription/quickadd":
+		_, _ = fmt.Fprint(w, `{"numResults": 1, "streamId": "feed/1"}`)
+	case f.apiPath + "/subscription/edit":
+		_, _ = fmt.Fprint(w, "OK")
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func TestGReaderOptions(t *testing.T) {
+	// The first feed only names its label by stream id like some servers do
+	feeds := `{"subscriptions": [
+		{"url": "https://github.com/org/tool/releases.atom", "title": "org/tool",
+			"categories": [{"id": "user/1005/label/GitHub"}]},
+		{"url": "https://github.com/org/lib/releases.atom", "title": "org/lib",
+			"categories": [{"id": "user/1005/label/GitHub", "label": "GitHub"}]},
+		{"url": "https://blog.example.com/feed", "title": "Blog",
+			"categories": [{"id": "user/1005/label/Personal", "label": "Personal"}]}
+	]}`
+
+	testCases := []struct {
+		name          string
+		opts          GReaderOptions
+		loginPath     string
+		apiPath       string
+		expectedLabel string
+	}{
+		{
+			name:          "Defaults for a server with the API at its root",
+			loginPath:     "/accounts/ClientLogin",
+			apiPath:       "/reader/api/0",
+			expectedLabel: "user/-/label/GitHub",
+		},
+		{
+			name:          "FreshRSS preset",
+			opts:          FreshRSSOptions(testutils.FreshRSSUser),
+			loginPath:     "/api/greader.php/accounts/ClientLogin",
+			apiPath:       "/api/greader.php/reader/api/0",
+			expectedLabel: fmt.Sprintf("user/%s/label/GitHub", testutils.FreshRSSUser),
+		},
+		{
+			name: "Custom base path, login path and label prefix",
+			opts: GReaderOptions{
+				BasePath:    "/greader",
+				LoginPath:   "/login",
+				LabelPrefix: "user/me/tag/",
+			},
+			loginPath:     "/greader/login",
+			apiPath:       "/greader/reader/api/0",
+			expectedLabel: "user/me/tag/GitHub",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			ctx := context.Background()
+			fake := &fakeGReader{loginPath: tc.loginPath, apiPath: tc.apiPath, feeds: feeds}
+			server := httptest.NewServer(fake)
+			t.Cleanup(server.Close)
+			g := NewGReaderClient(
+				testutils.FreshRSSUser,
+				server.URL,
+				tc.opts,
+				testutils.TestLogger(t),
+				server.Client(),
+				common.RetryPolicy{},
+			)
+
+			if err := g.Authenticate(ctx, testutils.FreshRSSToken); err != nil {
+				t.Fatalf("Expected no error authenticating but got %v", err)
+			}
+			actual, err := g.LoadFeeds(ctx, "GitHub")
+			if err != nil {
+				t.Fatalf("Expected no error loading feeds but got %v", err)
+			}
+			if len(actual) != 2 {
+				t.Errorf("Expected both feeds labelled GitHub, got %v", actual)
+			}
+			feedURL := common.FeedURL("https://github.com/org/app/releases.atom")
+			if err := g.AddFeed(ctx, feedURL, "org/app", "GitHub"); err != nil {
+				t.Fatalf("Expected no error adding feed but got %v", err)
+			}
+
+			// The last request put the new feed in its category
+			fake.mu.Lock()
+			defer fake.mu.Unlock()
+			last := fake.requests[len(fake.requests)-1]
+			if last.path != tc.apiPath+"/subscription/edit" {
+				t.Errorf("Expected the feed to be edited, got %s", last.path)
+			}
+			if label := last.form.Get("a"); label != tc.expectedLabel {
+				t.Errorf("Expected label %s, got %s", tc.expectedLabel, label)
+			}
+		})
+	}
+}
+
+func TestGReaderMigrateFeed(t *testing.T) {
+	testCases := []struct {
+		name          string
+		fromCategory  FeedCategory
+		toCategory    FeedCategory
+		expectAdded   string
+		expectRemoved string
+	}{
+		{
+			name:         "Migrating within a category keeps its label",
+			fromCategory: "GitHub",
+			toCategory:   "GitHub",
+			expectAdded:  "user/-/label/GitHub",
+		},
+		{
+			name:          "Migrating to another category swaps the label",
+			fromCategory:  "GitHub",
+			toCategory:    "GitHub (archived)",
+			expectAdded:   "user/-/label/GitHub (archived)",
+			expectRemoved: "user/-/label/GitHub",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			fake := &fakeGReader{apiPath: "/reader/api/0"}
+			server := httptest.NewServer(fake)
+			t.Cleanup(server.Close)
+			g := NewGReaderClient(
+				testutils.FreshRSSUser,
+				server.URL,
+				GReaderOptions{},
+				testutils.TestLogger(t),
+				server.Client(),
+				common.RetryPolicy{},
+			)
+
+			oldURL := common.FeedURL("https://github.com/org/tool/releases.atom")
+			subscribed, err := g.MigrateFeed(
+				context.Background(),
+				oldURL,
+				"https://github.com/neworg/tool/releases.atom",
+				"neworg/tool",
+				tc.fromCategory,
+				tc.toCategory,
+			)
+			if err != nil {
+				t.Fatalf("Expected no error migrating feed but got %v", err)
+			}
+			if subscribed != oldURL {
+				t.Errorf("Expected to stay subscribed to %s, got %s", oldURL, subscribed)
+			}
+
+			fake.mu.Lock()
+			defer fake.mu.Unlock()
+			form := fake.requests[len(fake.requests)-1].form
+			if label := form.Get("a"); label != tc.expectAdded {
+				t.Errorf("Expected label %s to be added, got %s", tc.expectAdded, label)
+			}
+			if label := form.Get("r"); label != tc.expectRemoved {
+				t.Errorf("Expected label %q to be removed, got %q", tc.expectRemoved, label)
+			}
+		})
+	}
+}
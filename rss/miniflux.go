@@ -0,0 +1,293 @@
+package rss
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"sync"
+
+	"github.com/atomicmeganerd/starfeed/common"
+)
+
+// MinifluxClient struct is for connecting to Miniflux servers with its REST API. Feeds are
+// looked up by their URL as Miniflux only knows them by id.
+type MinifluxClient struct {
+	url     string
+	logger  *slog.Logger
+	headers http.Header
+	client  *http.Client
+	retry   common.RetryPolicy
+	// The runners of every forge share the client and could create the same category at once
+	categoryMu sync.Mutex
+}
+
+func NewMinifluxClient(
+	url string,
+	logger *slog.Logger,
+	client *http.Client,
+	retry common.RetryPolicy,
+) *MinifluxClient {
+	headers := http.Header{}
+	headers.Set("Content-type", "application/json")
+	return &MinifluxClient{
+		url:     url,
+		logger:  logger,
+		headers: headers,
+		client:  client,
+		retry:   retry,
+	}
+}
+
+// Miniflux authenticates every request with an API key so we only check that the key works.
+func (c *MinifluxClient) Authenticate(ctx context.Context, token string) error {
+	c.headers.Set("X-Auth-Token", token)
+	reqURL := fmt.Sprintf("%s/v1/me", c.url)
+	c.logger.Debug("Authenticating to Miniflux", "url", reqURL)
+	if _, err := c.do(ctx, http.MethodGet, "/v1/me", nil); err != nil {
+		return fmt.Errorf("error authenticating to miniflux: %w, url: %s", err, reqURL)
+	}
+	return nil
+}
+
+// Load all feeds that are under the given category along with their titles.
+func (c *MinifluxClient) LoadFeeds(ctx context.Context, category FeedCategory) (FeedMap, error) {
+	newFeeds := FeedMap{}
+	minifluxCategory, ok, err := c.findCategory(ctx, category)
+	if err != nil {
+		return nil, err
+	}
+	if ok {
+		res, err := c.do(
+			ctx, http.MethodGet, fmt.Sprintf("/v1/categories/%d/feeds", minifluxCategory.ID), nil,
+		)
+		if err != nil {
+			return nil, err
+		}
+		feeds := []MinifluxFeed{}
+		if err = json.Unmarshal(res, &feeds); err != nil {
+			return nil, err
+		}
+		for _, feed := range feeds {
+			newFeeds[feed.FeedURL] = feed.Title
+		}
+	}
+
+	numFeeds := len(newFeeds)
+	if numFeeds == 0 {
+		c.logger.Warn("No feeds found in our RSS server", "numFeeds", numFeeds)
+	} else {
+		c.logger.Info(
+			"Loaded existing feeds from Miniflux", "numFeeds", numFeeds, "category", category,
+		)
+	}
+	return newFeeds, nil
+}
+
+// Miniflux does not take a title when we subscribe so we set it afterwards. Once the subscription
+// exists the feed has been added even if setting the title fails. Reporting that as a failure
+// would not undo the subscription so we log it instead and the title template, if any, fixes the
+// title on the next run.
+func (c *MinifluxClient) AddFeed(
+	ctx context.Context,
+	feedURL common.FeedURL,
+	name FeedName,
+	category FeedCategory,
+) error {
+	categoryID, err := c.ensureCategory(ctx, category)
+	if err != nil {
+		return err
+	}
+	res, err := c.do(ctx, http.MethodPost, "/v1/feeds", MinifluxCreateFeedRequest{
+		FeedURL:    feedURL,
+		CategoryID: categoryID,
+	})
+	if err != nil {
+		return err
+	}
+
+	if err := c.titleNewFeed(ctx, res, name); err != nil {
+		c.logger.Warn("Added feed but could not set its title", "feed", feedURL, "error", err)
+	}
+
+	c.logger.Info("Successfully added feed", "feed", feedURL)
+	return nil
+}
+
+// Sets the title of the feed we just subscribed to from the response to the subscribe request
+func (c *MinifluxClient) titleNewFeed(ctx context.Context, res []byte, name FeedName) error {
+	feedResponse := &MinifluxCreateFeedResponse{}
+	if err := json.Unmarshal(res, &feedResponse); err != nil {
+		return err
+	}
+	return c.updateFeed(ctx, feedResponse.FeedID, MinifluxUpdateFeedRequest{Title: name})
+}
+
+func (c *MinifluxClient) RemoveFeed(ctx context.Context, feedURL common.FeedURL) error {
+	feedID, err := c.feedID(ctx, feedURL)
+	if err != nil {
+		return err
+	}
+
+	// We do not care about the response
+	deletePath := fmt.Sprintf("/v1/feeds/%d", feedID)
+	if _, err := c.do(ctx, http.MethodDelete, deletePath, nil); err != nil {
+		return err
+	}
+
+	c.logger.Info("Removed feed", "feed", feedURL)
+	return nil
+}
+
+func (c *MinifluxClient) RenameFeed(
+	ctx context.Context,
+	feedURL common.FeedURL,
+	name FeedName,
+) error {
+	feedID, err := c.feedID(ctx, feedURL)
+	if err != nil {
+		return err
+	}
+	if err := c.updateFeed(ctx, feedID, MinifluxUpdateFeedRequest{Title: name}); err != nil {
+		return err
+	}
+
+	c.logger.Info("Renamed feed", "feed", feedURL, "name", name)
+	return nil
+}
+
+// A Miniflux feed is in exactly one category so we don't need to know which one it is leaving
+func (c *MinifluxClient) MoveFeed(
+	ctx context.Context,
+	feedURL common.FeedURL,
+	from FeedCategory,
+	to FeedCategory,
+) error {
+	feedID, err := c.feedID(ctx, feedURL)
+	if err != nil {
+		return err
+	}
+	categoryID, err := c.ensureCategory(ctx, to)
+	if err != nil {
+		return err
+	}
+	if err := c.updateFeed(
+		ctx, feedID, MinifluxUpdateFeedRequest{CategoryID: categoryID},
+	); err != nil {
+		return err
+	}
+
+	c.logger.Info("Moved feed", "feed", feedURL, "from", from, "to", to)
+	return nil
+}
+
+// Unlike the Google Reader API Miniflux can change the URL of a feed in place so the read state
+// and history of the subscription are kept and we follow the new URL from now on.
+func (c *MinifluxClient) MigrateFeed(
+	ctx context.Context,
+	from common.FeedURL,
+	to common.FeedURL,
+	name FeedName,
+	fromCategory FeedCategory,
+	toCategory FeedCategory,
+) (common.FeedURL, error) {
+	feedID, err := c.feedID(ctx, from)
+	if err != nil {
+		return "", err
+	}
+	categoryID, err := c.ensureCategory(ctx, toCategory)
+	if err != nil {
+		return "", err
+	}
+	if err := c.updateFeed(ctx, feedID, MinifluxUpdateFeedRequest{
+		FeedURL:    to,
+		Title:      name,
+		CategoryID: categoryID,
+	}); err != nil {
+		return "", err
+	}
+
+	c.logger.Info("Migrated feed", "feed", from, "newFeed", to)
+	return to, nil
+}
+
+// Miniflux has no way to look up a single feed by URL so we search all of them
+func (c *MinifluxClient) feedID(ctx context.Context, feedURL common.FeedURL) (int64, error) {
+	res, err := c.do(ctx, http.MethodGet, "/v1/feeds", nil)
+	if err != nil {
+		return 0, err
+	}
+	feeds := []MinifluxFeed{}
+	if err = json.Unmarshal(res, &feeds); err != nil {
+		return 0, err
+	}
+	for _, feed := range feeds {
+		if feed.FeedURL == feedURL {
+			return feed.ID, nil
+		}
+	}
+	return 0, fmt.Errorf("feed %s not found in miniflux", feedURL)
+}
+
+func (c *MinifluxClient) findCategory(
+	ctx context.Context,
+	category FeedCategory,
+) (MinifluxCategory, bool, error) {
+	res, err := c.do(ctx, http.MethodGet, "/v1/categories", nil)
+	if err != nil {
+		return MinifluxCategory{}, false, err
+	}
+	categories := []MinifluxCategory{}
+	if err = json.Unmarshal(res, &categories); err != nil {
+		return MinifluxCategory{}, false, err
+	}
+	for _, minifluxCategory := range categories {
+		if minifluxCategory.Title == category {
+			return minifluxCategory, true, nil
+		}
+	}
+	return MinifluxCategory{}, false, nil
+}
+
+// Categories have to exist before we can put a feed in them
+func (c *MinifluxClient) ensureCategory(ctx context.Context, category FeedCategory) (int64, error) {
+	c.categoryMu.Lock()
+	defer c.categoryMu.Unlock()
+
+	minifluxCategory, ok, err := c.findCategory(ctx, category)
+	if err != nil || ok {
+		return minifluxCategory.ID, err
+	}
+	res, err := c.do(
+		ctx, http.MethodPost, "/v1/categories", MinifluxCreateCategoryRequest{Title: category},
+	)
+	if err != nil {
+		return 0, err
+	}
+	if err = json.Unmarshal(res, &minifluxCategory); err != nil {
+		return 0, err
+	}
+
+	c.logger.Info("Created category", "category", category)
+	return minifluxCategory.ID, nil
+}
+
+func (c *MinifluxClient) updateFeed(
+	ctx context.Context,
+	feedID int64,
+	update MinifluxUpdateFeedRequest,
+) error {
+	_, err := c.do(ctx, http.MethodPut, fmt.Sprintf("/v1/feeds/%d", feedID), update)
+	return err
+}
+
+// Sends a request to the Miniflux API with the payload, if any, encoded as JSON
+func (c *MinifluxClient) do(
+	ctx context.Context,
+	method string,
+	path string,
+	payload any,
+) ([]byte, error) {
+	return doJSONRequest(ctx, method, c.url+path, payload, c.headers, c.client, c.retry)
+}
@@ -0,0 +1,271 @@
+package rss
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+
+	"github.com/atomicmeganerd/starfeed/common"
+	"github.com/atomicmeganerd/starfeed/testutils"
+)
+
+const mockMinifluxKey = "miniflux_key123"
+
+// A fake of the parts of the Miniflux API we use that remembers its categories and feeds
+type fakeMiniflux struct {
+	mu         sync.Mutex
+	categories []MinifluxCategory
+	feeds      []MinifluxFeed
+	lastID     int64
+	// Makes every feed update fail
+	failUpdates bool
+}
+
+func newFakeMinifluxServer(t *testing.T, fake *fakeMiniflux) *httptest.Server {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /v1/me", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, map[string]string{"username": "testuser"})
+	})
+	mux.HandleFunc("GET /v1/categories", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, fake.categories)
+	})
+	mux.HandleFunc("POST /v1/categories", func(w http.ResponseWriter, r *http.Request) {
+		req := MinifluxCreateCategoryRequest{}
+		_ = json.NewDecoder(r.Body).Decode(&req)
+		category := MinifluxCategory{ID: fake.nextID(), Title: req.Title}
+		fake.categories = append(fake.categories, category)
+		w.WriteHeader(http.StatusCreated)
+		writeJSON(w, category)
+	})
+	mux.HandleFunc("GET /v1/categories/{id}/feeds", func(w http.ResponseWriter, r *http.Request) {
+		categoryID, _ := strconv.ParseInt(r.PathValue("id"), 10, 64)
+		feeds := []MinifluxFeed{}
+		for _, feed := range fake.feeds {
+			if feed.Category.ID == categoryID {
+				feeds = append(feeds, feed)
+			}
+		}
+		writeJSON(w, feeds)
+	})
+	mux.HandleFunc("GET /v1/feeds", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, fake.feeds)
+	})
+	mux.HandleFunc("POST /v1/feeds", func(w http.ResponseWriter, r *http.Request) {
+		req := MinifluxCreateFeedRequest{}
+		_ = json.NewDecoder(r.Body).Decode(&req)
+		feed := MinifluxFeed{
+			ID:       fake.nextID(),
+			FeedURL:  req.FeedURL,
+			Title:    "Release notes from tool",
+			Category: MinifluxCategory{ID: req.CategoryID},
+		}
+		fake.feeds = append(fake.feeds, feed)
+		w.WriteHeader(http.StatusCreated)
+		writeJSON(w, MinifluxCreateFeedResponse{FeedID: feed.ID})
+	})
+	mux.HandleFunc("PUT /v1/feeds/{id}", func(w http.ResponseWriter, r *http.Request) {
+		if fake.failUpdates {
+			http.Error(w, `{"error_message": "invalid request"}`, http.StatusBadRequest)
+			return
+		}
+		req := MinifluxUpdateFeedRequest{}
+		_ = json.NewDecoder(r.Body).Decode(&req)
+		feed := fake.feed(r.PathValue("id"))
+		if feed == nil {
+			http.NotFound(w, r)
+			return
+		}
+		if req.FeedURL != "" {
+			feed.FeedURL = req.FeedURL
+		}
+		if req.Title != "" {
+			feed.Title = req.Title
+		}
+		if req.CategoryID != 0 {
+			feed.Category.ID = req.CategoryID
+		}
+		w.WriteHeader(http.StatusCreated)
+		writeJSON(w, feed)
+	})
+	mux.HandleFunc("DELETE /v1/feeds/{id}", func(w http.ResponseWriter, r *http.Request) {
+		feedID, _ := strconv.ParseInt(r.PathValue("id"), 10, 64)
+		for ix, feed := range fake.feeds {
+			if feed.ID == feedID {
+				fake.feeds = append(fake.feeds[:ix], fake.feeds[ix+1:]...)
+				w.WriteHeader(http.StatusNoContent)
+				return
+			}
+		}
+		http.NotFound(w, r)
+	})
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Auth-Token") != mockMinifluxKey {
+			http.Error(w, `{"error_message": "access unauthorized"}`, http.StatusUnauthorized)
+			return
+		}
+		fake.mu.Lock()
+		defer fake.mu.Unlock()
+		mux.ServeHTTP(w, r)
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func (f *fakeMiniflux) nextID() int64 {
+	f.lastID++
+	return f.lastID
+}
+
+func (f *fakeMiniflux) feed(id string) *MinifluxFeed {
+	feedID, _ := strconv.ParseInt(id, 10, 64)
+	for ix := range f.feeds {
+		if f.feeds[ix].ID == feedID {
+			return &f.feeds[ix]
+		}
+	}
+	return nil
+}
+
+func writeJSON(w http.ResponseWriter, v any) {
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func TestMinifluxAuthenticate(t *testing.T) {
+	testCases := []struct {
+		name        string
+		token       string
+		expectError bool
+	}{
+		{name: "Successful authentication", token: mockMinifluxKey},
+		{name: "Failed authentication", token: "wrong_key", expectError: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			server := newFakeMinifluxServer(t, &fakeMiniflux{})
+			m := NewMinifluxClient(
+				server.URL, testutils.TestLogger(t), server.Client(), common.RetryPolicy{},
+			)
+			err := m.Authenticate(context.Background(), tc.token)
+			if tc.expectError && err == nil {
+				t.Fatalf("Expected error but got nil")
+			}
+			if !tc.expectError && err != nil {
+				t.Fatalf("Expected no error but got %v", err)
+			}
+		})
+	}
+}
+
+func TestMinifluxFeeds(t *testing.T) {
+	ctx := context.Background()
+	fake := &fakeMiniflux{
+		categories: []MinifluxCategory{{ID: 1, Title: "Personal"}},
+		feeds: []MinifluxFeed{
+			{ID: 2, FeedURL: "https://blog.example.com/feed", Category: MinifluxCategory{ID: 1}},
+		},
+		lastID: 2,
+	}
+	server := newFakeMinifluxServer(t, fake)
+	m := NewMinifluxClient(
+		server.URL, testutils.TestLogger(t), server.Client(), common.RetryPolicy{},
+	)
+	if err := m.Authenticate(ctx, mockMinifluxKey); err != nil {
+		t.Fatalf("Expected no error authenticating but got %v", err)
+	}
+	feedURL := common.FeedURL("https://github.com/org/tool/releases.atom")
+	movedURL := common.FeedURL("https://github.com/neworg/tool/releases.atom")
+
+	expectFeeds := func(category FeedCategory, expected FeedMap) {
+		t.Helper()
+		actual, err := m.LoadFeeds(ctx, category)
+		if err != nil {
+			t.Fatalf("Expected no error loading %s but got %v", category, err)
+		}
+		if len(actual) != len(expected) {
+			t.Fatalf("Expected %v in %s, got %v", expected, category, actual)
+		}
+		for url, title := range expected {
+			if actual[url] != title {
+				t.Errorf("Expected %s to be titled %q, got %q", url, title, actual[url])
+			}
+		}
+	}
+
+	// The category is created along with the first feed in it
+	expectFeeds("GitHub", FeedMap{})
+	if err := m.AddFeed(ctx, feedURL, "org/tool", "GitHub"); err != nil {
+		t.Fatalf("Expected no error adding feed but got %v", err)
+	}
+	expectFeeds("GitHub", FeedMap{feedURL: "org/tool"})
+
+	if err := m.RenameFeed(ctx, feedURL, "tool releases"); err != nil {
+		t.Fatalf("Expected no error renaming feed but got %v", err)
+	}
+	expectFeeds("GitHub", FeedMap{feedURL: "tool releases"})
+
+	if err := m.MoveFeed(ctx, feedURL, "GitHub", "GitHub (archived)"); err != nil {
+		t.Fatalf("Expected no error moving feed but got %v", err)
+	}
+	expectFeeds("GitHub", FeedMap{})
+	expectFeeds("GitHub (archived)", FeedMap{feedURL: "tool releases"})
+
+	// The subscription follows the new URL and keeps its id
+	subscribed, err := m.MigrateFeed(ctx, feedURL, movedURL, "neworg/tool", "GitHub", "GitHub")
+	if err != nil {
+		t.Fatalf("Expected no error migrating feed but got %v", err)
+	}
+	if subscribed != movedURL {
+		t.Errorf("Expected to be subscribed to %s, got %s", movedURL, subscribed)
+	}
+	expectFeeds("GitHub", FeedMap{movedURL: "neworg/tool"})
+
+	if err := m.RemoveFeed(ctx, movedURL); err != nil {
+		t.Fatalf("Expected no error removing feed but got %v", err)
+	}
+	expectFeeds("GitHub", FeedMap{})
+	if err := m.RemoveFeed(ctx, movedURL); err == nil {
+		t.Errorf("Expected an error removing a feed we are not subscribed to")
+	}
+
+	// Feeds outside our categories are left alone
+	expectFeeds("Personal", FeedMap{"https://blog.example.com/feed": ""})
+	if len(fake.categories) != 3 {
+		t.Errorf("Expected each category to be created once, got %v", fake.categories)
+	}
+}
+
+// The feed is subscribed to as soon as it is created so failing to title it does not fail the add
+func TestMinifluxAddFeedTitleFails(t *testing.T) {
+	ctx := context.Background()
+	fake := &fakeMiniflux{
+		categories:  []MinifluxCategory{{ID: 1, Title: "GitHub"}},
+		lastID:      1,
+		failUpdates: true,
+	}
+	server := newFakeMinifluxServer(t, fake)
+	m := NewMinifluxClient(
+		server.URL, testutils.TestLogger(t), server.Client(), common.RetryPolicy{},
+	)
+	if err := m.Authenticate(ctx, mockMinifluxKey); err != nil {
+		t.Fatalf("Expected no error authenticating but got %v", err)
+	}
+	feedURL := common.FeedURL("https://github.com/org/tool/releases.atom")
+
+	if err := m.AddFeed(ctx, feedURL, "org/tool", "GitHub"); err != nil {
+		t.Fatalf("Expected no error adding feed but got %v", err)
+	}
+	actual, err := m.LoadFeeds(ctx, "GitHub")
+	if err != nil {
+		t.Fatalf("Expected no error loading feeds but got %v", err)
+	}
+	if title := actual[feedURL]; title != "Release notes from tool" {
+		t.Errorf("Expected the feed to keep the title Miniflux gave it, got %v", actual)
+	}
+}
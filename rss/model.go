@@ -1,6 +1,11 @@
 package rss
 
-import "github.com/atomicmeganerd/starfeed/common"
+import (
+	"encoding/json"
+	"encoding/xml"
+
+	"github.com/atomicmeganerd/starfeed/common"
+)
 
 type FeedName string
 
@@ -8,13 +13,16 @@ func (n FeedName) String() string {
 	return string(n)
 }
 
+// The feeds we are subscribed to keyed by URL with the title they have in the RSS server
+type FeedMap map[common.FeedURL]FeedName
+
 type FeedCategory string
 
 func (c FeedCategory) String() string {
 	return string(c)
 }
 
-type FreshRSSAddFeedResponse struct {
+type GReaderAddFeedResponse struct {
 	NumResults int    `json:"numResults"`
 	Query      string `json:"query"`
 	StreamId   string `json:"streamId"`
@@ -27,9 +35,146 @@ type RSSFeedList struct {
 
 type RSSFeed struct {
 	URL        common.FeedURL    `json:"url"`
+	Title      FeedName          `json:"title"`
 	Categories []RSSFeedCategory `json:"categories"`
 }
 
 type RSSFeedCategory struct {
+	ID    string       `json:"id"`
 	Label FeedCategory `json:"label"`
 }
+
+type MinifluxCategory struct {
+	ID    int64        `json:"id"`
+	Title FeedCategory `json:"title"`
+}
+
+type MinifluxFeed struct {
+	ID       int64            `json:"id"`
+	FeedURL  common.FeedURL   `json:"feed_url"`
+	Title    FeedName         `json:"title"`
+	Category MinifluxCategory `json:"category"`
+}
+
+type MinifluxCreateCategoryRequest struct {
+	Title FeedCategory `json:"title"`
+}
+
+type MinifluxCreateFeedRequest struct {
+	FeedURL    common.FeedURL `json:"feed_url"`
+	CategoryID int64          `json:"category_id"`
+}
+
+type MinifluxCreateFeedResponse struct {
+	FeedID int64 `json:"feed_id"`
+}
+
+// Only the fields that are set are changed
+type MinifluxUpdateFeedRequest struct {
+	FeedURL    common.FeedURL `json:"feed_url,omitempty"`
+	Title      FeedName       `json:"title,omitempty"`
+	CategoryID int64          `json:"category_id,omitempty"`
+}
+
+type NextcloudNewsFolder struct {
+	ID   int64        `json:"id"`
+	Name FeedCategory `json:"name"`
+}
+
+type NextcloudNewsFolderList struct {
+	Folders []NextcloudNewsFolder `json:"folders"`
+}
+
+// Feeds outside of a folder have a folder id of 0 or null
+type NextcloudNewsFeed struct {
+	ID       int64          `json:"id"`
+	URL      common.FeedURL `json:"url"`
+	Title    FeedName       `json:"title"`
+	FolderID int64          `json:"folderId"`
+}
+
+type NextcloudNewsFeedList struct {
+	Feeds []NextcloudNewsFeed `json:"feeds"`
+}
+
+type NextcloudNewsCreateFolderRequest struct {
+	Name FeedCategory `json:"name"`
+}
+
+type NextcloudNewsCreateFeedRequest struct {
+	URL      common.FeedURL `json:"url"`
+	FolderID int64          `json:"folderId"`
+}
+
+type NextcloudNewsMoveFeedRequest struct {
+	FolderID int64 `json:"folderId"`
+}
+
+type NextcloudNewsRenameFeedRequest struct {
+	FeedTitle FeedName `json:"feedTitle"`
+}
+
+// tt-rss sends ids as numbers but older releases sent some of them as strings
+type TinyTinyRSSID int64
+
+// Every tt-rss response has the same envelope. The content depends on the operation and holds
+// an error code when the status is not OK.
+type TinyTinyRSSResponse struct {
+	Status  int             `json:"status"`
+	Content json.RawMessage `json:"content"`
+}
+
+type TinyTinyRSSError struct {
+	Error string `json:"error"`
+}
+
+type TinyTinyRSSLoginResponse struct {
+	SessionID string `json:"session_id"`
+}
+
+type TinyTinyRSSCategory struct {
+	ID    TinyTinyRSSID `json:"id"`
+	Title FeedCategory  `json:"title"`
+}
+
+type TinyTinyRSSFeed struct {
+	ID         TinyTinyRSSID  `json:"id"`
+	FeedURL    common.FeedURL `json:"feed_url"`
+	Title      FeedName       `json:"title"`
+	CategoryID TinyTinyRSSID  `json:"cat_id"`
+}
+
+type TinyTinyRSSSubscribeResponse struct {
+	Status struct {
+		Code    int    `json:"code"`
+		Message string `json:"message"`
+	} `json:"status"`
+}
+
+type OPMLDocument struct {
+	XMLName xml.Name `xml:"opml"`
+	Version string   `xml:"version,attr"`
+	Head    OPMLHead `xml:"head"`
+	Body    OPMLBody `xml:"body"`
+}
+
+type OPMLHead struct {
+	Title        string `xml:"title,omitempty"`
+	DateModified string `xml:"dateModified,omitempty"`
+}
+
+type OPMLBody struct {
+	Outlines []OPMLOutline `xml:"outline"`
+}
+
+// An outline is either a feed, when it has an xmlUrl, or a group of outlines. Attributes we
+// don't know about are kept so readers don't lose what they stored in the file.
+type OPMLOutline struct {
+	Text     string         `xml:"text,attr"`
+	Title    string         `xml:"title,attr,omitempty"`
+	Type     string         `xml:"type,attr,omitempty"`
+	XMLURL   common.FeedURL `xml:"xmlUrl,attr,omitempty"`
+	HTMLURL  string         `xml:"htmlUrl,attr,omitempty"`
+	Attrs    []xml.Attr     `xml:",any,attr"`
+	Outlines []OPMLOutline  `xml:"outline"`
+}
@@ -0,0 +1,312 @@
+package rss
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"sync"
+
+	"github.com/atomicmeganerd/starfeed/common"
+)
+
+// The v1-2 API is still served next to v1-3 and has everything we need, so we work with older
+// Nextcloud News releases too
+const nextcloudNewsAPIPath = "/index.php/apps/news/api/v1-2"
+
+// NextcloudNewsClient struct is for connecting to the News app of a Nextcloud server. Its
+// folders are our categories and feeds are looked up by their URL as the API only knows them by
+// id.
+type NextcloudNewsClient struct {
+	user    string
+	url     string
+	logger  *slog.Logger
+	headers http.Header
+	client  *http.Client
+	retry   common.RetryPolicy
+	// The runners of every forge share the client and could create the same folder at once
+	folderMu sync.Mutex
+}
+
+func NewNextcloudNewsClient(
+	user, url string,
+	logger *slog.Logger,
+	client *http.Client,
+	retry common.RetryPolicy,
+) *NextcloudNewsClient {
+	headers := http.Header{}
+	headers.Set("Content-type", "application/json")
+	return &NextcloudNewsClient{
+		user:    user,
+		url:     url,
+		logger:  logger,
+		headers: headers,
+		client:  client,
+		retry:   retry,
+	}
+}
+
+// Nextcloud News uses basic auth on every request so we only check that the app password works.
+func (c *NextcloudNewsClient) Authenticate(ctx context.Context, token string) error {
+	credentials := base64.StdEncoding.EncodeToString([]byte(c.user + ":" + token))
+	c.headers.Set("Authorization", "Basic "+credentials)
+	reqURL := c.url + nextcloudNewsAPIPath + "/version"
+	c.logger.Debug("Authenticating to Nextcloud News", "url", reqURL)
+	if _, err := c.do(ctx, http.MethodGet, "/version", nil); err != nil {
+		return fmt.Errorf("error authenticating to nextcloud news: %w, url: %s", err, reqURL)
+	}
+	return nil
+}
+
+// Load all feeds that are in the folder of the given category along with their titles.
+func (c *NextcloudNewsClient) LoadFeeds(
+	ctx context.Context,
+	category FeedCategory,
+) (FeedMap, error) {
+	newFeeds := FeedMap{}
+	folder, ok, err := c.findFolder(ctx, category)
+	if err != nil {
+		return nil, err
+	}
+	if ok {
+		feeds, err := c.feeds(ctx)
+		if err != nil {
+			return nil, err
+		}
+		for _, feed := range feeds {
+			if feed.FolderID == folder.ID {
+				newFeeds[feed.URL] = feed.Title
+			}
+		}
+	}
+
+	numFeeds := len(newFeeds)
+	if numFeeds == 0 {
+		c.logger.Warn("No feeds found in our RSS server", "numFeeds", numFeeds)
+	} else {
+		c.logger.Info(
+			"Loaded existing feeds from Nextcloud News", "numFeeds", numFeeds, "category", category,
+		)
+	}
+	return newFeeds, nil
+}
+
+// Nextcloud News does not take a title when we subscribe so we rename the feed afterwards
+func (c *NextcloudNewsClient) AddFeed(
+	ctx context.Context,
+	feedURL common.FeedURL,
+	name FeedName,
+	category FeedCategory,
+) error {
+	folderID, err := c.ensureFolder(ctx, category)
+	if err != nil {
+		return err
+	}
+	res, err := c.do(ctx, http.MethodPost, "/feeds", NextcloudNewsCreateFeedRequest{
+		URL:      feedURL,
+		FolderID: folderID,
+	})
+	if err != nil {
+		return err
+	}
+
+	feedList := &NextcloudNewsFeedList{}
+	if err = json.Unmarshal(res, &feedList); err != nil {
+		return err
+	}
+	if len(feedList.Feeds) == 0 {
+		return fmt.Errorf("nextcloud news did not return the feed it added for %s", feedURL)
+	}
+	if err = c.renameFeed(ctx, feedList.Feeds[0].ID, name); err != nil {
+		return err
+	}
+
+	c.logger.Info("Successfully added feed", "feed", feedURL)
+	return nil
+}
+
+func (c *NextcloudNewsClient) RemoveFeed(ctx context.Context, feedURL common.FeedURL) error {
+	feedID, err := c.feedID(ctx, feedURL)
+	if err != nil {
+		return err
+	}
+
+	// We do not care about the response
+	deletePath := fmt.Sprintf("/feeds/%d", feedID)
+	if _, err := c.do(ctx, http.MethodDelete, deletePath, nil); err != nil {
+		return err
+	}
+
+	c.logger.Info("Removed feed", "feed", feedURL)
+	return nil
+}
+
+func (c *NextcloudNewsClient) RenameFeed(
+	ctx context.Context,
+	feedURL common.FeedURL,
+	name FeedName,
+) error {
+	feedID, err := c.feedID(ctx, feedURL)
+	if err != nil {
+		return err
+	}
+	if err := c.renameFeed(ctx, feedID, name); err != nil {
+		return err
+	}
+
+	c.logger.Info("Renamed feed", "feed", feedURL, "name", name)
+	return nil
+}
+
+// A feed is in exactly one folder so we don't need to know which one it is leaving
+func (c *NextcloudNewsClient) MoveFeed(
+	ctx context.Context,
+	feedURL common.FeedURL,
+	from FeedCategory,
+	to FeedCategory,
+) error {
+	feedID, err := c.feedID(ctx, feedURL)
+	if err != nil {
+		return err
+	}
+	if err := c.moveFeed(ctx, feedID, to); err != nil {
+		return err
+	}
+
+	c.logger.Info("Moved feed", "feed", feedURL, "from", from, "to", to)
+	return nil
+}
+
+// Nextcloud News has no way to change the URL of a feed and unsubscribing would throw away the
+// read state we are trying to keep. The forges redirect the old URL of a renamed or transferred
+// repo so we keep subscribing to it and only update the title and folder.
+func (c *NextcloudNewsClient) MigrateFeed(
+	ctx context.Context,
+	from common.FeedURL,
+	to common.FeedURL,
+	name FeedName,
+	fromCategory FeedCategory,
+	toCategory FeedCategory,
+) (common.FeedURL, error) {
+	feedID, err := c.feedID(ctx, from)
+	if err != nil {
+		return "", err
+	}
+	if err := c.moveFeed(ctx, feedID, toCategory); err != nil {
+		return "", err
+	}
+	if err := c.renameFeed(ctx, feedID, name); err != nil {
+		return "", err
+	}
+
+	c.logger.Info("Migrated feed by keeping its old URL", "feed", from, "newFeed", to)
+	return from, nil
+}
+
+func (c *NextcloudNewsClient) feeds(ctx context.Context) ([]NextcloudNewsFeed, error) {
+	res, err := c.do(ctx, http.MethodGet, "/feeds", nil)
+	if err != nil {
+		return nil, err
+	}
+	feedList := &NextcloudNewsFeedList{}
+	if err = json.Unmarshal(res, &feedList); err != nil {
+		return nil, err
+	}
+	return feedList.Feeds, nil
+}
+
+func (c *NextcloudNewsClient) feedID(ctx context.Context, feedURL common.FeedURL) (int64, error) {
+	feeds, err := c.feeds(ctx)
+	if err != nil {
+		return 0, err
+	}
+	for _, feed := range feeds {
+		if feed.URL == feedURL {
+			return feed.ID, nil
+		}
+	}
+	return 0, fmt.Errorf("feed %s not found in nextcloud news", feedURL)
+}
+
+func (c *NextcloudNewsClient) findFolder(
+	ctx context.Context,
+	category FeedCategory,
+) (NextcloudNewsFolder, bool, error) {
+	res, err := c.do(ctx, http.MethodGet, "/folders", nil)
+	if err != nil {
+		return NextcloudNewsFolder{}, false, err
+	}
+	folderList := &NextcloudNewsFolderList{}
+	if err = json.Unmarshal(res, &folderList); err != nil {
+		return NextcloudNewsFolder{}, false, err
+	}
+	for _, folder := range folderList.Folders {
+		if folder.Name == category {
+			return folder, true, nil
+		}
+	}
+	return NextcloudNewsFolder{}, false, nil
+}
+
+// Folders have to exist before we can put a feed in them
+func (c *NextcloudNewsClient) ensureFolder(
+	ctx context.Context,
+	category FeedCategory,
+) (int64, error) {
+	c.folderMu.Lock()
+	defer c.folderMu.Unlock()
+
+	folder, ok, err := c.findFolder(ctx, category)
+	if err != nil || ok {
+		return folder.ID, err
+	}
+	res, err := c.do(
+		ctx, http.MethodPost, "/folders", NextcloudNewsCreateFolderRequest{Name: category},
+	)
+	if err != nil {
+		return 0, err
+	}
+	folderList := &NextcloudNewsFolderList{}
+	if err = json.Unmarshal(res, &folderList); err != nil {
+		return 0, err
+	}
+	if len(folderList.Folders) == 0 {
+		return 0, fmt.Errorf("nextcloud news did not return the folder it created for %s", category)
+	}
+
+	c.logger.Info("Created folder", "category", category)
+	return folderList.Folders[0].ID, nil
+}
+
+func (c *NextcloudNewsClient) moveFeed(
+	ctx context.Context,
+	feedID int64,
+	category FeedCategory,
+) error {
+	folderID, err := c.ensureFolder(ctx, category)
+	if err != nil {
+		return err
+	}
+	movePath := fmt.Sprintf("/feeds/%d/move", feedID)
+	_, err = c.do(ctx, http.MethodPut, movePath, NextcloudNewsMoveFeedRequest{FolderID: folderID})
+	return err
+}
+
+func (c *NextcloudNewsClient) renameFeed(ctx context.Context, feedID int64, name FeedName) error {
+	renamePath := fmt.Sprintf("/feeds/%d/rename", feedID)
+	_, err := c.do(ctx, http.MethodPut, renamePath, NextcloudNewsRenameFeedRequest{FeedTitle: name})
+	return err
+}
+
+// Sends a request to the News API with the payload, if any, encoded as JSON
+func (c *NextcloudNewsClient) do(
+	ctx context.Context,
+	method string,
+	path string,
+	payload any,
+) ([]byte, error) {
+	reqURL := c.url + nextcloudNewsAPIPath + path
+	return doJSONRequest(ctx, method, reqURL, payload, c.headers, c.client, c.retry)
+}
@@ -0,0 +1,225 @@
+package rss
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+
+	"github.com/atomicmeganerd/starfeed/common"
+	"github.com/atomicmeganerd/starfeed/testutils"
+)
+
+const mockNextcloudPassword = "nextcloud_app_password"
+
+// A fake of the parts of the News API we use that remembers its folders and feeds
+type fakeNextcloudNews struct {
+	mu      sync.Mutex
+	folders []NextcloudNewsFolder
+	feeds   []NextcloudNewsFeed
+	lastID  int64
+}
+
+func newFakeNextcloudNewsServer(t *testing.T, fake *fakeNextcloudNews) *httptest.Server {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /version", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, map[string]string{"version": "25.0.0"})
+	})
+	mux.HandleFunc("GET /folders", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, NextcloudNewsFolderList{Folders: fake.folders})
+	})
+	mux.HandleFunc("POST /folders", func(w http.ResponseWriter, r *http.Request) {
+		req := NextcloudNewsCreateFolderRequest{}
+		_ = json.NewDecoder(r.Body).Decode(&req)
+		folder := NextcloudNewsFolder{ID: fake.nextID(), Name: req.Name}
+		fake.folders = append(fake.folders, folder)
+		writeJSON(w, NextcloudNewsFolderList{Folders: []NextcloudNewsFolder{folder}})
+	})
+	mux.HandleFunc("GET /feeds", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, NextcloudNewsFeedList{Feeds: fake.feeds})
+	})
+	mux.HandleFunc("POST /feeds", func(w http.ResponseWriter, r *http.Request) {
+		req := NextcloudNewsCreateFeedRequest{}
+		_ = json.NewDecoder(r.Body).Decode(&req)
+		feed := NextcloudNewsFeed{
+			ID:       fake.nextID(),
+			URL:      req.URL,
+			Title:    "Release notes from tool",
+			FolderID: req.FolderID,
+		}
+		fake.feeds = append(fake.feeds, feed)
+		writeJSON(w, NextcloudNewsFeedList{Feeds: []NextcloudNewsFeed{feed}})
+	})
+	mux.HandleFunc("PUT /feeds/{id}/move", func(w http.ResponseWriter, r *http.Request) {
+		req := NextcloudNewsMoveFeedRequest{}
+		_ = json.NewDecoder(r.Body).Decode(&req)
+		if feed := fake.feed(r.PathValue("id")); feed != nil {
+			feed.FolderID = req.FolderID
+			return
+		}
+		http.NotFound(w, r)
+	})
+	mux.HandleFunc("PUT /feeds/{id}/rename", func(w http.ResponseWriter, r *http.Request) {
+		req := NextcloudNewsRenameFeedRequest{}
+		_ = json.NewDecoder(r.Body).Decode(&req)
+		if feed := fake.feed(r.PathValue("id")); feed != nil {
+			feed.Title = req.FeedTitle
+			return
+		}
+		http.NotFound(w, r)
+	})
+	mux.HandleFunc("DELETE /feeds/{id}", func(w http.ResponseWriter, r *http.Request) {
+		feedID, _ := strconv.ParseInt(r.PathValue("id"), 10, 64)
+		for ix, feed := range fake.feeds {
+			if feed.ID == feedID {
+				fake.feeds = append(fake.feeds[:ix], fake.feeds[ix+1:]...)
+				return
+			}
+		}
+		http.NotFound(w, r)
+	})
+
+	server := httptest.NewServer(http.StripPrefix(
+		nextcloudNewsAPIPath,
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			user, password, ok := r.BasicAuth()
+			if !ok || user != testutils.FreshRSSUser || password != mockNextcloudPassword {
+				http.Error(w, `{"message": "Unauthorized"}`, http.StatusUnauthorized)
+				return
+			}
+			fake.mu.Lock()
+			defer fake.mu.Unlock()
+			mux.ServeHTTP(w, r)
+		}),
+	))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func (f *fakeNextcloudNews) nextID() int64 {
+	f.lastID++
+	return f.lastID
+}
+
+func (f *fakeNextcloudNews) feed(id string) *NextcloudNewsFeed {
+	feedID, _ := strconv.ParseInt(id, 10, 64)
+	for ix := range f.feeds {
+		if f.feeds[ix].ID == feedID {
+			return &f.feeds[ix]
+		}
+	}
+	return nil
+}
+
+func TestNextcloudNewsAuthenticate(t *testing.T) {
+	testCases := []struct {
+		name        string
+		token       string
+		expectError bool
+	}{
+		{name: "Successful authentication", token: mockNextcloudPassword},
+		{name: "Failed authentication", token: "wrong_password", expectError: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			server := newFakeNextcloudNewsServer(t, &fakeNextcloudNews{})
+			n := NewNextcloudNewsClient(
+				testutils.FreshRSSUser,
+				server.URL,
+				testutils.TestLogger(t),
+				server.Client(),
+				common.RetryPolicy{},
+			)
+			err := n.Authenticate(context.Background(), tc.token)
+			if tc.expectError && err == nil {
+				t.Fatalf("Expected error but got nil")
+			}
+			if !tc.expectError && err != nil {
+				t.Fatalf("Expected no error but got %v", err)
+			}
+		})
+	}
+}
+
+func TestNextcloudNewsFeeds(t *testing.T) {
+	ctx := context.Background()
+	// A feed of our own outside of any folder
+	fake := &fakeNextcloudNews{
+		feeds:  []NextcloudNewsFeed{{ID: 1, URL: "https://blog.example.com/feed"}},
+		lastID: 1,
+	}
+	server := newFakeNextcloudNewsServer(t, fake)
+	n := NewNextcloudNewsClient(
+		testutils.FreshRSSUser,
+		server.URL,
+		testutils.TestLogger(t),
+		server.Client(),
+		common.RetryPolicy{},
+	)
+	if err := n.Authenticate(ctx, mockNextcloudPassword); err != nil {
+		t.Fatalf("Expected no error authenticating but got %v", err)
+	}
+	feedURL := common.FeedURL("https://github.com/org/tool/releases.atom")
+	movedURL := common.FeedURL("https://github.com/neworg/tool/releases.atom")
+
+	expectFeeds := func(category FeedCategory, expected FeedMap) {
+		t.Helper()
+		actual, err := n.LoadFeeds(ctx, category)
+		if err != nil {
+			t.Fatalf("Expected no error loading %s but got %v", category, err)
+		}
+		if len(actual) != len(expected) {
+			t.Fatalf("Expected %v in %s, got %v", expected, category, actual)
+		}
+		for url, title := range expected {
+			if actual[url] != title {
+				t.Errorf("Expected %s to be titled %q, got %q", url, title, actual[url])
+			}
+		}
+	}
+
+	// The folder is created along with the first feed in it
+	expectFeeds("GitHub", FeedMap{})
+	if err := n.AddFeed(ctx, feedURL, "org/tool", "GitHub"); err != nil {
+		t.Fatalf("Expected no error adding feed but got %v", err)
+	}
+	expectFeeds("GitHub", FeedMap{feedURL: "org/tool"})
+
+	if err := n.RenameFeed(ctx, feedURL, "tool releases"); err != nil {
+		t.Fatalf("Expected no error renaming feed but got %v", err)
+	}
+	expectFeeds("GitHub", FeedMap{feedURL: "tool releases"})
+
+	if err := n.MoveFeed(ctx, feedURL, "GitHub", "GitHub (archived)"); err != nil {
+		t.Fatalf("Expected no error moving feed but got %v", err)
+	}
+	expectFeeds("GitHub", FeedMap{})
+	expectFeeds("GitHub (archived)", FeedMap{feedURL: "tool releases"})
+
+	// We keep the old URL as the News API can't change it
+	subscribed, err := n.MigrateFeed(ctx, feedURL, movedURL, "neworg/tool", "GitHub", "GitHub")
+	if err != nil {
+		t.Fatalf("Expected no error migrating feed but got %v", err)
+	}
+	if subscribed != feedURL {
+		t.Errorf("Expected to stay subscribed to %s, got %s", feedURL, subscribed)
+	}
+	expectFeeds("GitHub", FeedMap{feedURL: "neworg/tool"})
+
+	if err := n.RemoveFeed(ctx, feedURL); err != nil {
+		t.Fatalf("Expected no error removing feed but got %v", err)
+	}
+	expectFeeds("GitHub", FeedMap{})
+	if err := n.RemoveFeed(ctx, feedURL); err == nil {
+		t.Errorf("Expected an error removing a feed we are not subscribed to")
+	}
+
+	if len(fake.feeds) != 1 || len(fake.folders) != 2 {
+		t.Errorf("Expected our own feed and two folders, got %v and %v", fake.feeds, fake.folders)
+	}
+}
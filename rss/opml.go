@@ -0,0 +1,279 @@
+package rss
+
+import (
+	"context"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"os"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/atomicmeganerd/starfeed/common"
+)
+
+// OPMLClient keeps our feeds in an OPML file instead of on a server so any reader can import
+// them. Each category is a group of outlines at the top of the body. Everything else in the file
+// is left as it is.
+type OPMLClient struct {
+	path   string
+	logger *slog.Logger
+	// The runners of every forge share the client and each change rewrites the whole file
+	mu sync.Mutex
+}
+
+func NewOPMLClient(path string, logger *slog.Logger) *OPMLClient {
+	return &OPMLClient{path: path, logger: logger}
+}
+
+// There is nothing to log in to so we only check that we can read the file, if it exists yet.
+func (c *OPMLClient) Authenticate(ctx context.Context, token string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if _, err := c.load(); err != nil {
+		return fmt.Errorf("error opening opml file: %w, path: %s", err, c.path)
+	}
+	return nil
+}
+
+// Load all feeds that are in the group of the given category along with their titles.
+func (c *OPMLClient) LoadFeeds(ctx context.Context, category FeedCategory) (FeedMap, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	doc, err := c.load()
+	if err != nil {
+		return nil, err
+	}
+
+	newFeeds := FeedMap{}
+	if group := doc.group(category); group != nil {
+		for _, outline := range group.Outlines {
+			if outline.XMLURL != "" {
+				newFeeds[outline.XMLURL] = FeedName(outline.name())
+			}
+		}
+	}
+
+	numFeeds := len(newFeeds)
+	if numFeeds == 0 {
+		c.logger.Warn("No feeds found in our RSS server", "numFeeds", numFeeds)
+	} else {
+		c.logger.Info(
+			"Loaded existing feeds from OPML file", "numFeeds", numFeeds, "category", category,
+		)
+	}
+	return newFeeds, nil
+}
+
+func (c *OPMLClient) AddFeed(
+	ctx context.Context,
+	feedURL common.FeedURL,
+	name FeedName,
+	category FeedCategory,
+) error {
+	if err := c.update(func(doc *OPMLDocument) {
+		doc.addFeed(category, newOPMLFeed(feedURL, name))
+	}); err != nil {
+		return err
+	}
+
+	c.logger.Info("Successfully added feed", "feed", feedURL)
+	return nil
+}
+
+// Like unsubscribing on a server this removes the feed from every group it is in
+func (c *OPMLClient) RemoveFeed(ctx context.Context, feedURL common.FeedURL) error {
+	if err := c.update(func(doc *OPMLDocument) {
+		for ix := range doc.Body.Outlines {
+			doc.Body.Outlines[ix].removeFeed(feedURL)
+		}
+	}); err != nil {
+		return err
+	}
+
+	c.logger.Info("Removed feed", "feed", feedURL)
+	return nil
+}
+
+func (c *OPMLClient) RenameFeed(
+	ctx context.Context,
+	feedURL common.FeedURL,
+	name FeedName,
+) error {
+	if err := c.update(func(doc *OPMLDocument) {
+		for _, outline := range doc.feeds(feedURL) {
+			outline.Text = name.String()
+			outline.Title = name.String()
+		}
+	}); err != nil {
+		return err
+	}
+
+	c.logger.Info("Renamed feed", "feed", feedURL, "name", name)
+	return nil
+}
+
+// The outline moves as it is so any attributes a reader added to it are kept
+func (c *OPMLClient) MoveFeed(
+	ctx context.Context,
+	feedURL common.FeedURL,
+	from FeedCategory,
+	to FeedCategory,
+) error {
+	if err := c.update(func(doc *OPMLDocument) {
+		group := doc.group(from)
+		if group == nil {
+			return
+		}
+		for _, outline := range group.removeFeed(feedURL) {
+			doc.addFeed(to, outline)
+		}
+	}); err != nil {
+		return err
+	}
+
+	c.logger.Info("Moved feed", "feed", feedURL, "from", from, "to", to)
+	return nil
+}
+
+// A file has no read state to keep so we simply swap the old feed for the new one
+func (c *OPMLClient) MigrateFeed(
+	ctx context.Context,
+	from common.FeedURL,
+	to common.FeedURL,
+	name FeedName,
+	fromCategory FeedCategory,
+	toCategory FeedCategory,
+) (common.FeedURL, error) {
+	if err := c.update(func(doc *OPMLDocument) {
+		for ix := range doc.Body.Outlines {
+			doc.Body.Outlines[ix].removeFeed(from)
+		}
+		doc.addFeed(toCategory, newOPMLFeed(to, name))
+	}); err != nil {
+		return "", err
+	}
+
+	c.logger.Info("Migrated feed", "feed", from, "newFeed", to)
+	return to, nil
+}
+
+// Loads the file, changes it and writes it back in one go so that no other runner can change
+// the file in between
+func (c *OPMLClient) update(change func(doc *OPMLDocument)) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	doc, err := c.load()
+	if err != nil {
+		return err
+	}
+	change(&doc)
+	return c.save(doc)
+}
+
+// A file that does not exist yet is an empty document
+func (c *OPMLClient) load() (OPMLDocument, error) {
+	data, err := os.ReadFile(c.path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return OPMLDocument{Version: "2.0", Head: OPMLHead{Title: "Starfeed"}}, nil
+	}
+	if err != nil {
+		return OPMLDocument{}, err
+	}
+	doc := OPMLDocument{}
+	if err := xml.Unmarshal(data, &doc); err != nil {
+		return OPMLDocument{}, fmt.Errorf("error %w parsing opml file %s", err, c.path)
+	}
+	return doc, nil
+}
+
+// Readers import the file at any time so we never leave a half written one behind
+func (c *OPMLClient) save(doc OPMLDocument) error {
+	doc.Head.DateModified = time.Now().UTC().Format(time.RFC1123Z)
+	data, err := xml.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return fmt.Errorf("error %w encoding opml file", err)
+	}
+	data = append([]byte(xml.Header), append(data, '\n')...)
+	if err := common.WriteFileAtomic(c.path, data, 0o644); err != nil {
+		return fmt.Errorf("error %w writing opml file %s", err, c.path)
+	}
+	return nil
+}
+
+func newOPMLFeed(feedURL common.FeedURL, name FeedName) OPMLOutline {
+	return OPMLOutline{
+		Text:   name.String(),
+		Title:  name.String(),
+		Type:   "rss",
+		XMLURL: feedURL,
+	}
+}
+
+// The group of a category at the top of the body, if there is one
+func (d *OPMLDocument) group(category FeedCategory) *OPMLOutline {
+	for ix, outline := range d.Body.Outlines {
+		if outline.XMLURL == "" && outline.name() == category.String() {
+			return &d.Body.Outlines[ix]
+		}
+	}
+	return nil
+}
+
+// Adds the feed to the group of the category, creating the group if it is not there yet. A feed
+// that is in the group already is replaced.
+func (d *OPMLDocument) addFeed(category FeedCategory, feed OPMLOutline) {
+	group := d.group(category)
+	if group == nil {
+		d.Body.Outlines = append(d.Body.Outlines, OPMLOutline{
+			Text:  category.String(),
+			Title: category.String(),
+		})
+		group = &d.Body.Outlines[len(d.Body.Outlines)-1]
+	}
+	group.removeFeed(feed.XMLURL)
+	group.Outlines = append(group.Outlines, feed)
+}
+
+// Every outline of the feed wherever it is in the document
+func (d *OPMLDocument) feeds(feedURL common.FeedURL) []*OPMLOutline {
+	var feeds []*OPMLOutline
+	var walk func(outlines []OPMLOutline)
+	walk = func(outlines []OPMLOutline) {
+		for ix := range outlines {
+			if outlines[ix].XMLURL == feedURL {
+				feeds = append(feeds, &outlines[ix])
+			}
+			walk(outlines[ix].Outlines)
+		}
+	}
+	walk(d.Body.Outlines)
+	return feeds
+}
+
+// Removes the feed from this outline and the ones below it and returns what was removed
+func (o *OPMLOutline) removeFeed(feedURL common.FeedURL) []OPMLOutline {
+	var removed []OPMLOutline
+	o.Outlines = slices.DeleteFunc(o.Outlines, func(outline OPMLOutline) bool {
+		if outline.XMLURL == feedURL {
+			removed = append(removed, outline)
+			return true
+		}
+		return false
+	})
+	for ix := range o.Outlines {
+		removed = append(removed, o.Outlines[ix].removeFeed(feedURL)...)
+	}
+	return removed
+}
+
+// Readers show the text of an outline but some files only have a title
+func (o OPMLOutline) name() string {
+	if o.Text != "" {
+		return o.Text
+	}
+	return o.Title
+}
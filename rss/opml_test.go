@@ -0,0 +1,158 @@
+package rss
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/atomicmeganerd/starfeed/common"
+	"github.com/atomicmeganerd/starfeed/testutils"
+)
+
+// A file a reader exported with a group of our own and a feed we added before that the reader
+// put its own attribute on
+const mockOPMLFile = `<?xml version="1.0" encoding="UTF-8"?>
+<opml version="2.0">
+  <head>
+    <title>My feeds</title>
+  </head>
+  <body>
+    <outline text="Personal" title="Personal">
+      <outline text="Blog" type="rss" xmlUrl="https://blog.example.com/feed"></outline>
+    </outline>
+    <outline text="GitHub" title="GitHub">
+      <outline text="org/lib" type="rss" xmlUrl="https://github.com/org/lib/releases.atom"
+        category="starred"></outline>
+    </outline>
+  </body>
+</opml>
+`
+
+func TestOPMLAuthenticate(t *testing.T) {
+	testCases := []struct {
+		name        string
+		content     string
+		expectError bool
+	}{
+		{name: "Existing file", content: mockOPMLFile},
+		{name: "Missing file"},
+		{name: "Broken file", content: "<opml><body>", expectError: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			path := filepath.Join(t.TempDir(), "feeds.opml")
+			if tc.content != "" {
+				if err := os.WriteFile(path, []byte(tc.content), 0o644); err != nil {
+					t.Fatalf("Expected no error writing the file but got %v", err)
+				}
+			}
+			o := NewOPMLClient(path, testutils.TestLogger(t))
+			err := o.Authenticate(context.Background(), "")
+			if tc.expectError && err == nil {
+				t.Fatalf("Expected error but got nil")
+			}
+			if !tc.expectError && err != nil {
+				t.Fatalf("Expected no error but got %v", err)
+			}
+		})
+	}
+}
+
+func TestOPMLFeeds(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "feeds.opml")
+	if err := os.WriteFile(path, []byte(mockOPMLFile), 0o644); err != nil {
+		t.Fatalf("Expected no error writing the file but got %v", err)
+	}
+	o := NewOPMLClient(path, testutils.TestLogger(t))
+	if err := o.Authenticate(ctx, ""); err != nil {
+		t.Fatalf("Expected no error opening the file but got %v", err)
+	}
+	libURL := common.FeedURL("https://github.com/org/lib/releases.atom")
+	feedURL := common.FeedURL("https://github.com/org/tool/releases.atom")
+	movedURL := common.FeedURL("https://github.com/neworg/tool/releases.atom")
+
+	expectFeeds := func(category FeedCategory, expected FeedMap) {
+		t.Helper()
+		actual, err := o.LoadFeeds(ctx, category)
+		if err != nil {
+			t.Fatalf("Expected no error loading %s but got %v", category, err)
+		}
+		if len(actual) != len(expected) {
+			t.Fatalf("Expected %v in %s, got %v", expected, category, actual)
+		}
+		for url, title := range expected {
+			if actual[url] != title {
+				t.Errorf("Expected %s to be titled %q, got %q", url, title, actual[url])
+			}
+		}
+	}
+
+	expectFeeds("GitHub", FeedMap{libURL: "org/lib"})
+	if err := o.AddFeed(ctx, feedURL, "org/tool", "GitHub"); err != nil {
+		t.Fatalf("Expected no error adding feed but got %v", err)
+	}
+	expectFeeds("GitHub", FeedMap{libURL: "org/lib", feedURL: "org/tool"})
+
+	if err := o.RenameFeed(ctx, feedURL, "tool releases"); err != nil {
+		t.Fatalf("Expected no error renaming feed but got %v", err)
+	}
+	expectFeeds("GitHub", FeedMap{libURL: "org/lib", feedURL: "tool releases"})
+
+	// The group is created along with the first feed moved into it
+	if err := o.MoveFeed(ctx, feedURL, "GitHub", "GitHub (archived)"); err != nil {
+		t.Fatalf("Expected no error moving feed but got %v", err)
+	}
+	expectFeeds("GitHub", FeedMap{libURL: "org/lib"})
+	expectFeeds("GitHub (archived)", FeedMap{feedURL: "tool releases"})
+
+	subscribed, err := o.MigrateFeed(
+		ctx, feedURL, movedURL, "neworg/tool", "GitHub (archived)", "GitHub (archived)",
+	)
+	if err != nil {
+		t.Fatalf("Expected no error migrating feed but got %v", err)
+	}
+	if subscribed != movedURL {
+		t.Errorf("Expected to be subscribed to %s, got %s", movedURL, subscribed)
+	}
+	expectFeeds("GitHub (archived)", FeedMap{movedURL: "neworg/tool"})
+
+	if err := o.RemoveFeed(ctx, movedURL); err != nil {
+		t.Fatalf("Expected no error removing feed but got %v", err)
+	}
+	expectFeeds("GitHub (archived)", FeedMap{})
+	expectFeeds("Personal", FeedMap{"https://blog.example.com/feed": "Blog"})
+
+	// Whatever we did not touch is written back as it was
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("Expected no error reading the file but got %v", err)
+	}
+	for _, expected := range []string{`<title>My feeds</title>`, `category="starred"`} {
+		if !strings.Contains(string(data), expected) {
+			t.Errorf("Expected the file to still contain %s, got %s", expected, data)
+		}
+	}
+}
+
+func TestOPMLNewFile(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "feeds.opml")
+	o := NewOPMLClient(path, testutils.TestLogger(t))
+	feedURL := common.FeedURL("https://github.com/org/tool/releases.atom")
+
+	if err := o.AddFeed(ctx, feedURL, "org/tool", "GitHub"); err != nil {
+		t.Fatalf("Expected no error adding feed but got %v", err)
+	}
+	actual, err := NewOPMLClient(path, testutils.TestLogger(t)).LoadFeeds(ctx, "GitHub")
+	if err != nil {
+		t.Fatalf("Expected no error loading feeds but got %v", err)
+	}
+	if actual[feedURL] != "org/tool" {
+		t.Errorf("Expected the new file to hold %s, got %v", feedURL, actual)
+	}
+}
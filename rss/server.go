@@ -0,0 +1,49 @@
+package rss
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+
+	"github.com/atomicmeganerd/starfeed/common"
+)
+
+// Server is what every RSS server we publish to can do. The runners only need part of it but
+// we have to authenticate first.
+type Server interface {
+	Authenticate(ctx context.Context, token string) error
+	LoadFeeds(ctx context.Context, category FeedCategory) (FeedMap, error)
+	AddFeed(ctx context.Context, feedURL common.FeedURL, name FeedName, category FeedCategory) error
+	RemoveFeed(ctx context.Context, feedURL common.FeedURL) error
+	MoveFeed(ctx context.Context, feedURL common.FeedURL, from, to FeedCategory) error
+	RenameFeed(ctx context.Context, feedURL common.FeedURL, name FeedName) error
+	MigrateFeed(
+		ctx context.Context,
+		from common.FeedURL,
+		to common.FeedURL,
+		name FeedName,
+		fromCategory FeedCategory,
+		toCategory FeedCategory,
+	) (common.FeedURL, error)
+}
+
+// The REST APIs of the RSS servers take JSON so we encode the payload, if any, for them
+func doJSONRequest(
+	ctx context.Context,
+	method string,
+	reqURL string,
+	payload any,
+	headers http.Header,
+	client *http.Client,
+	retry common.RetryPolicy,
+) ([]byte, error) {
+	var body []byte
+	if payload != nil {
+		var err error
+		if body, err = json.Marshal(payload); err != nil {
+			return nil, err
+		}
+	}
+	data, _, err := common.DoAPIRequest(ctx, method, reqURL, body, headers, client, retry)
+	return data, err
+}
@@ -0,0 +1,403 @@
+package rss
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"sync"
+
+	"github.com/atomicmeganerd/starfeed/common"
+)
+
+const (
+	// tt-rss answers every call with 200 and reports failures in the status of the response
+	ttrssStatusOK = 0
+	// The session expired or was never there. We log in again and retry the call once.
+	ttrssNotLoggedIn = "NOT_LOGGED_IN"
+	// getFeeds returns every feed but the virtual ones for this category id
+	ttrssAllFeeds = -3
+	// subscribeToFeed codes for a feed that was already there or has just been added
+	ttrssAlreadySubscribed = 0
+	ttrssSubscribed        = 1
+)
+
+// TinyTinyRSSClient struct is for connecting to Tiny Tiny RSS servers with its JSON API. Every
+// call is a POST of an operation to the same endpoint along with the id of our session.
+type TinyTinyRSSClient struct {
+	user    string
+	url     string
+	logger  *slog.Logger
+	headers http.Header
+	client  *http.Client
+	retry   common.RetryPolicy
+	// The runners of every forge share the client so the session can be renewed by any of them
+	sessionMu sync.Mutex
+	password  string
+	sessionID string
+	// The runners could also create the same category at once
+	categoryMu sync.Mutex
+}
+
+func NewTinyTinyRSSClient(
+	user, url string,
+	logger *slog.Logger,
+	client *http.Client,
+	retry common.RetryPolicy,
+) *TinyTinyRSSClient {
+	headers := http.Header{}
+	headers.Set("Content-type", "application/json")
+	return &TinyTinyRSSClient{
+		user:    user,
+		url:     url,
+		logger:  logger,
+		headers: headers,
+		client:  client,
+		retry:   retry,
+	}
+}
+
+// We keep the password so that we can log in again when the session expires between calls.
+func (c *TinyTinyRSSClient) Authenticate(ctx context.Context, token string) error {
+	c.sessionMu.Lock()
+	c.password = token
+	c.sessionMu.Unlock()
+	if err := c.login(ctx); err != nil {
+		return fmt.Errorf("error authenticating to tt-rss: %w, url: %s", err, c.apiURL())
+	}
+	return nil
+}
+
+// Load all feeds that are under the given category along with their titles.
+func (c *TinyTinyRSSClient) LoadFeeds(ctx context.Context, category FeedCategory) (FeedMap, error) {
+	newFeeds := FeedMap{}
+	ttrssCategory, ok, err := c.findCategory(ctx, category)
+	if err != nil {
+		return nil, err
+	}
+	if ok {
+		feeds, err := c.feeds(ctx, ttrssCategory.ID)
+		if err != nil {
+			return nil, err
+		}
+		for _, feed := range feeds {
+			newFeeds[feed.FeedURL] = feed.Title
+		}
+	}
+
+	numFeeds := len(newFeeds)
+	if numFeeds == 0 {
+		c.logger.Warn("No feeds found in our RSS server", "numFeeds", numFeeds)
+	} else {
+		c.logger.Info(
+			"Loaded existing feeds from tt-rss", "numFeeds", numFeeds, "category", category,
+		)
+	}
+	return newFeeds, nil
+}
+
+// tt-rss names feeds after the title in the feed itself so the name we want is ignored
+func (c *TinyTinyRSSClient) AddFeed(
+	ctx context.Context,
+	feedURL common.FeedURL,
+	name FeedName,
+	category FeedCategory,
+) error {
+	categoryID, err := c.ensureCategory(ctx, category)
+	if err != nil {
+		return err
+	}
+	if err := c.subscribe(ctx, feedURL, categoryID); err != nil {
+		return err
+	}
+
+	c.logger.Info("Successfully added feed", "feed", feedURL)
+	return nil
+}
+
+func (c *TinyTinyRSSClient) RemoveFeed(ctx context.Context, feedURL common.FeedURL) error {
+	feedID, err := c.feedID(ctx, feedURL)
+	if err != nil {
+		return err
+	}
+	if err := c.unsubscribe(ctx, feedID); err != nil {
+		return err
+	}
+
+	c.logger.Info("Removed feed", "feed", feedURL)
+	return nil
+}
+
+// The JSON API has no way to rename a feed so feeds keep the title tt-rss gave them
+func (c *TinyTinyRSSClient) RenameFeed(
+	ctx context.Context,
+	feedURL common.FeedURL,
+	name FeedName,
+) error {
+	return fmt.Errorf("error %w renaming feed %s in tt-rss", errors.ErrUnsupported, feedURL)
+}
+
+// Tells the runners not to retitle our feeds as RenameFeed can never succeed
+func (c *TinyTinyRSSClient) CanRenameFeeds() bool {
+	return false
+}
+
+// The JSON API can't move a feed to another category either so we subscribe to it again in the
+// new one. tt-rss throws away the articles of the old subscription but release feeds are short
+// and the feed is fetched again right away.
+func (c *TinyTinyRSSClient) MoveFeed(
+	ctx context.Context,
+	feedURL common.FeedURL,
+	from FeedCategory,
+	to FeedCategory,
+) error {
+	feedID, err := c.feedID(ctx, feedURL)
+	if err != nil {
+		return err
+	}
+	categoryID, err := c.ensureCategory(ctx, to)
+	if err != nil {
+		return err
+	}
+	if err := c.unsubscribe(ctx, feedID); err != nil {
+		return err
+	}
+	if err := c.subscribe(ctx, feedURL, categoryID); err != nil {
+		return err
+	}
+
+	c.logger.Info("Moved feed", "feed", feedURL, "from", from, "to", to)
+	return nil
+}
+
+// Like the Google Reader API the tt-rss API can't change the URL of a subscription. The forges
+// redirect the old URL of a renamed or transferred repo so we keep subscribing to it. If the
+// category changed too the feed is moved on the next run.
+func (c *TinyTinyRSSClient) MigrateFeed(
+	ctx context.Context,
+	from common.FeedURL,
+	to common.FeedURL,
+	name FeedName,
+	fromCategory FeedCategory,
+	toCategory FeedCategory,
+) (common.FeedURL, error) {
+	if _, err := c.feedID(ctx, from); err != nil {
+		return "", err
+	}
+
+	c.logger.Info("Migrated feed by keeping its old URL", "feed", from, "newFeed", to)
+	return from, nil
+}
+
+func (c *TinyTinyRSSClient) subscribe(
+	ctx context.Context,
+	feedURL common.FeedURL,
+	categoryID TinyTinyRSSID,
+) error {
+	subscribed := TinyTinyRSSSubscribeResponse{}
+	if err := c.call(ctx, "subscribeToFeed", map[string]any{
+		"feed_url":    feedURL,
+		"category_id": categoryID,
+	}, &subscribed); err != nil {
+		return err
+	}
+	code := subscribed.Status.Code
+	if code != ttrssSubscribed && code != ttrssAlreadySubscribed {
+		return fmt.Errorf(
+			"tt-rss could not subscribe to %s, code: %d, message: %s",
+			feedURL, code, subscribed.Status.Message,
+		)
+	}
+	return nil
+}
+
+func (c *TinyTinyRSSClient) unsubscribe(ctx context.Context, feedID TinyTinyRSSID) error {
+	return c.call(ctx, "unsubscribeFeed", map[string]any{"feed_id": feedID}, nil)
+}
+
+func (c *TinyTinyRSSClient) feeds(
+	ctx context.Context,
+	categoryID TinyTinyRSSID,
+) ([]TinyTinyRSSFeed, error) {
+	feeds := []TinyTinyRSSFeed{}
+	if err := c.call(ctx, "getFeeds", map[string]any{"cat_id": categoryID}, &feeds); err != nil {
+		return nil, err
+	}
+	return feeds, nil
+}
+
+func (c *TinyTinyRSSClient) feedID(
+	ctx context.Context,
+	feedURL common.FeedURL,
+) (TinyTinyRSSID, error) {
+	feeds, err := c.feeds(ctx, ttrssAllFeeds)
+	if err != nil {
+		return 0, err
+	}
+	for _, feed := range feeds {
+		if feed.FeedURL == feedURL {
+			return feed.ID, nil
+		}
+	}
+	return 0, fmt.Errorf("feed %s not found in tt-rss", feedURL)
+}
+
+// Empty categories are left out unless we ask for them and we may have just created one
+func (c *TinyTinyRSSClient) findCategory(
+	ctx context.Context,
+	category FeedCategory,
+) (TinyTinyRSSCategory, bool, error) {
+	categories := []TinyTinyRSSCategory{}
+	if err := c.call(
+		ctx, "getCategories", map[string]any{"include_empty": true}, &categories,
+	); err != nil {
+		return TinyTinyRSSCategory{}, false, err
+	}
+	for _, ttrssCategory := range categories {
+		if ttrssCategory.Title == category {
+			return ttrssCategory, true, nil
+		}
+	}
+	return TinyTinyRSSCategory{}, false, nil
+}
+
+// Categories have to exist before we can subscribe to a feed in them. We look the new category
+// up afterwards as the id it gets is not part of the response.
+func (c *TinyTinyRSSClient) ensureCategory(
+	ctx context.Context,
+	category FeedCategory,
+) (TinyTinyRSSID, error) {
+	c.categoryMu.Lock()
+	defer c.categoryMu.Unlock()
+
+	ttrssCategory, ok, err := c.findCategory(ctx, category)
+	if err != nil || ok {
+		return ttrssCategory.ID, err
+	}
+	if err := c.call(ctx, "addCategory", map[string]any{"caption": category}, nil); err != nil {
+		return 0, fmt.Errorf("error %w creating tt-rss category %s", err, category)
+	}
+	ttrssCategory, ok, err = c.findCategory(ctx, category)
+	if err != nil {
+		return 0, err
+	}
+	if !ok {
+		return 0, fmt.Errorf("tt-rss category %s is missing after creating it", category)
+	}
+
+	c.logger.Info("Created category", "category", category)
+	return ttrssCategory.ID, nil
+}
+
+func (c *TinyTinyRSSClient) login(ctx context.Context) error {
+	c.sessionMu.Lock()
+	defer c.sessionMu.Unlock()
+
+	c.logger.Debug("Logging in to tt-rss", "url", c.apiURL())
+	res, err := c.post(ctx, "login", map[string]any{"user": c.user, "password": c.password})
+	if err != nil {
+		return err
+	}
+	session := TinyTinyRSSLoginResponse{}
+	if err := res.decode("login", &session); err != nil {
+		return err
+	}
+	if session.SessionID == "" {
+		return errors.New("tt-rss did not return a session id")
+	}
+	c.sessionID = session.SessionID
+	return nil
+}
+
+// Calls an operation of the API with our session and decodes the content of the response into
+// content unless it is nil. If our session has expired we log in again and retry once.
+func (c *TinyTinyRSSClient) call(
+	ctx context.Context,
+	op string,
+	params map[string]any,
+	content any,
+) error {
+	params["sid"] = c.session()
+	res, err := c.post(ctx, op, params)
+	if err != nil {
+		return err
+	}
+	if res.errorCode() == ttrssNotLoggedIn {
+		c.logger.Info("tt-rss session expired, logging in again", "op", op)
+		if err := c.login(ctx); err != nil {
+			return fmt.Errorf("error %w logging in to tt-rss again", err)
+		}
+		params["sid"] = c.session()
+		if res, err = c.post(ctx, op, params); err != nil {
+			return err
+		}
+	}
+	return res.decode(op, content)
+}
+
+func (c *TinyTinyRSSClient) session() string {
+	c.sessionMu.Lock()
+	defer c.sessionMu.Unlock()
+	return c.sessionID
+}
+
+// Sends an operation to the API. The operation goes in the body along with its parameters.
+func (c *TinyTinyRSSClient) post(
+	ctx context.Context,
+	op string,
+	params map[string]any,
+) (TinyTinyRSSResponse, error) {
+	params["op"] = op
+	data, err := doJSONRequest(
+		ctx, http.MethodPost, c.apiURL(), params, c.headers, c.client, c.retry,
+	)
+	if err != nil {
+		return TinyTinyRSSResponse{}, err
+	}
+	res := TinyTinyRSSResponse{}
+	if err := json.Unmarshal(data, &res); err != nil {
+		return TinyTinyRSSResponse{}, fmt.Errorf("error %w parsing tt-rss response to %s", err, op)
+	}
+	return res, nil
+}
+
+func (c *TinyTinyRSSClient) apiURL() string {
+	return c.url + "/api/"
+}
+
+func (id *TinyTinyRSSID) UnmarshalJSON(data []byte) error {
+	var value int64
+	if err := json.Unmarshal(bytes.Trim(data, `"`), &value); err != nil {
+		return fmt.Errorf("error %w parsing tt-rss id %s", err, data)
+	}
+	*id = TinyTinyRSSID(value)
+	return nil
+}
+
+// The error code tt-rss sent if the status is not OK
+func (r TinyTinyRSSResponse) errorCode() string {
+	if r.Status == ttrssStatusOK {
+		return ""
+	}
+	ttrssErr := TinyTinyRSSError{}
+	if err := json.Unmarshal(r.Content, &ttrssErr); err != nil || ttrssErr.Error == "" {
+		return "UNKNOWN_ERROR"
+	}
+	return ttrssErr.Error
+}
+
+func (r TinyTinyRSSResponse) decode(op string, content any) error {
+	if code := r.errorCode(); code != "" {
+		return fmt.Errorf("tt-rss %s failed with %s", op, code)
+	}
+	if content == nil {
+		return nil
+	}
+	if err := json.Unmarshal(r.Content, content); err != nil {
+		return fmt.Errorf("error %w parsing tt-rss response to %s", err, op)
+	}
+	return nil
+}
@@ -0,0 +1,247 @@
+package rss
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+
+	"github.com/atomicmeganerd/starfeed/common"
+	"github.com/atomicmeganerd/starfeed/testutils"
+)
+
+const mockTinyTinyRSSPassword = "ttrss_password123"
+
+// A fake of the parts of the tt-rss JSON API we use that remembers its categories and feeds. It
+// forgets our session whenever we ask it to.
+type fakeTinyTinyRSS struct {
+	mu         sync.Mutex
+	sessionID  string
+	numLogins  int
+	categories []TinyTinyRSSCategory
+	feeds      []TinyTinyRSSFeed
+	lastID     TinyTinyRSSID
+}
+
+type fakeTinyTinyRSSRequest struct {
+	Op          string         `json:"op"`
+	SessionID   string         `json:"sid"`
+	User        string         `json:"user"`
+	Password    string         `json:"password"`
+	Caption     FeedCategory   `json:"caption"`
+	FeedURL     common.FeedURL `json:"feed_url"`
+	FeedID      TinyTinyRSSID  `json:"feed_id"`
+	CategoryID  TinyTinyRSSID  `json:"cat_id"`
+	SubscribeTo TinyTinyRSSID  `json:"category_id"`
+}
+
+func (f *fakeTinyTinyRSS) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	req := fakeTinyTinyRSSRequest{}
+	if r.URL.Path != "/api/" || json.NewDecoder(r.Body).Decode(&req) != nil {
+		http.Error(w, "bad request", http.StatusBadRequest)
+		return
+	}
+	if req.Op == "login" {
+		if req.User != testutils.FreshRSSUser || req.Password != mockTinyTinyRSSPassword {
+			writeTinyTinyRSSError(w, "LOGIN_ERROR")
+			return
+		}
+		f.numLogins++
+		f.sessionID = fmt.Sprintf("session%d", f.numLogins)
+		writeTinyTinyRSSContent(w, TinyTinyRSSLoginResponse{SessionID: f.sessionID})
+		return
+	}
+	if req.SessionID == "" || req.SessionID != f.sessionID {
+		writeTinyTinyRSSError(w, ttrssNotLoggedIn)
+		return
+	}
+	f.serveOp(w, req)
+}
+
+func (f *fakeTinyTinyRSS) serveOp(w http.ResponseWriter, req fakeTinyTinyRSSRequest) {
+	switch req.Op {
+	case "getCategories":
+		// Like older releases of tt-rss we send the ids as strings
+		categories := []map[string]any{}
+		for _, category := range f.categories {
+			categories = append(categories, map[string]any{
+				"id": strconv.FormatInt(int64(category.ID), 10), "title": category.Title,
+			})
+		}
+		writeTinyTinyRSSContent(w, categories)
+	case "addCategory":
+		f.lastID++
+		f.categories = append(f.categories, TinyTinyRSSCategory{ID: f.lastID, Title: req.Caption})
+		writeTinyTinyRSSContent(w, map[string]string{"status": "OK"})
+	case "getFeeds":
+		feeds := []TinyTinyRSSFeed{}
+		for _, feed := range f.feeds {
+			if req.CategoryID == ttrssAllFeeds || feed.CategoryID == req.CategoryID {
+				feeds = append(feeds, feed)
+			}
+		}
+		writeTinyTinyRSSContent(w, feeds)
+	case "subscribeToFeed":
+		f.serveSubscribe(w, req)
+	case "unsubscribeFeed":
+		for ix, feed := range f.feeds {
+			if feed.ID == req.FeedID {
+				f.feeds = append(f.feeds[:ix], f.feeds[ix+1:]...)
+				writeTinyTinyRSSContent(w, map[string]string{"status": "OK"})
+				return
+			}
+		}
+		writeTinyTinyRSSError(w, "FEED_NOT_FOUND")
+	default:
+		writeTinyTinyRSSError(w, "UNKNOWN_METHOD")
+	}
+}
+
+func (f *fakeTinyTinyRSS) serveSubscribe(w http.ResponseWriter, req fakeTinyTinyRSSRequest) {
+	subscribed := TinyTinyRSSSubscribeResponse{}
+	subscribed.Status.Code = ttrssAlreadySubscribed
+	for _, feed := range f.feeds {
+		if feed.FeedURL == req.FeedURL {
+			writeTinyTinyRSSContent(w, subscribed)
+			return
+		}
+	}
+	f.lastID++
+	f.feeds = append(f.feeds, TinyTinyRSSFeed{
+		ID:         f.lastID,
+		FeedURL:    req.FeedURL,
+		Title:      "Release notes from tool",
+		CategoryID: req.SubscribeTo,
+	})
+	subscribed.Status.Code = ttrssSubscribed
+	writeTinyTinyRSSContent(w, subscribed)
+}
+
+func writeTinyTinyRSSContent(w http.ResponseWriter, content any) {
+	data, _ := json.Marshal(content)
+	writeJSON(w, TinyTinyRSSResponse{Status: ttrssStatusOK, Content: data})
+}
+
+func writeTinyTinyRSSError(w http.ResponseWriter, code string) {
+	data, _ := json.Marshal(TinyTinyRSSError{Error: code})
+	writeJSON(w, TinyTinyRSSResponse{Status: 1, Content: data})
+}
+
+func newTestTinyTinyRSSClient(t *testing.T, fake *fakeTinyTinyRSS) *TinyTinyRSSClient {
+	server := httptest.NewServer(fake)
+	t.Cleanup(server.Close)
+	return NewTinyTinyRSSClient(
+		testutils.FreshRSSUser,
+		server.URL,
+		testutils.TestLogger(t),
+		server.Client(),
+		common.RetryPolicy{},
+	)
+}
+
+func TestTinyTinyRSSAuthenticate(t *testing.T) {
+	testCases := []struct {
+		name        string
+		token       string
+		expectError bool
+	}{
+		{name: "Successful authentication", token: mockTinyTinyRSSPassword},
+		{name: "Failed authentication", token: "wrong_password", expectError: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			tt := newTestTinyTinyRSSClient(t, &fakeTinyTinyRSS{})
+			err := tt.Authenticate(context.Background(), tc.token)
+			if tc.expectError && err == nil {
+				t.Fatalf("Expected error but got nil")
+			}
+			if !tc.expectError && err != nil {
+				t.Fatalf("Expected no error but got %v", err)
+			}
+		})
+	}
+}
+
+func TestTinyTinyRSSFeeds(t *testing.T) {
+	ctx := context.Background()
+	fake := &fakeTinyTinyRSS{}
+	tt := newTestTinyTinyRSSClient(t, fake)
+	if err := tt.Authenticate(ctx, mockTinyTinyRSSPassword); err != nil {
+		t.Fatalf("Expected no error authenticating but got %v", err)
+	}
+	feedURL := common.FeedURL("https://github.com/org/tool/releases.atom")
+	movedURL := common.FeedURL("https://github.com/neworg/tool/releases.atom")
+
+	expectFeeds := func(category FeedCategory, expected FeedMap) {
+		t.Helper()
+		actual, err := tt.LoadFeeds(ctx, category)
+		if err != nil {
+			t.Fatalf("Expected no error loading %s but got %v", category, err)
+		}
+		if len(actual) != len(expected) {
+			t.Fatalf("Expected %v in %s, got %v", expected, category, actual)
+		}
+		for url, title := range expected {
+			if actual[url] != title {
+				t.Errorf("Expected %s to be titled %q, got %q", url, title, actual[url])
+			}
+		}
+	}
+
+	// The category is created along with the first feed in it and tt-rss picks the title
+	expectFeeds("GitHub", FeedMap{})
+	if err := tt.AddFeed(ctx, feedURL, "org/tool", "GitHub"); err != nil {
+		t.Fatalf("Expected no error adding feed but got %v", err)
+	}
+	expectFeeds("GitHub", FeedMap{feedURL: "Release notes from tool"})
+	if err := tt.RenameFeed(ctx, feedURL, "tool releases"); !errors.Is(err, errors.ErrUnsupported) {
+		t.Errorf("Expected renaming to be unsupported but got %v", err)
+	}
+	if tt.CanRenameFeeds() {
+		t.Errorf("Expected tt-rss to say it can't rename feeds")
+	}
+
+	// Our session is gone so we have to log in again before we can move the feed
+	fake.mu.Lock()
+	fake.sessionID = ""
+	fake.mu.Unlock()
+	if err := tt.MoveFeed(ctx, feedURL, "GitHub", "GitHub (archived)"); err != nil {
+		t.Fatalf("Expected no error moving feed but got %v", err)
+	}
+	if fake.numLogins != 2 {
+		t.Errorf("Expected to log in again once, got %d logins", fake.numLogins)
+	}
+	expectFeeds("GitHub", FeedMap{})
+	expectFeeds("GitHub (archived)", FeedMap{feedURL: "Release notes from tool"})
+
+	// We keep the old URL as the API can't change it
+	subscribed, err := tt.MigrateFeed(
+		ctx, feedURL, movedURL, "neworg/tool", "GitHub (archived)", "GitHub (archived)",
+	)
+	if err != nil {
+		t.Fatalf("Expected no error migrating feed but got %v", err)
+	}
+	if subscribed != feedURL {
+		t.Errorf("Expected to stay subscribed to %s, got %s", feedURL, subscribed)
+	}
+
+	if err := tt.RemoveFeed(ctx, feedURL); err != nil {
+		t.Fatalf("Expected no error removing feed but got %v", err)
+	}
+	expectFeeds("GitHub (archived)", FeedMap{})
+	if err := tt.RemoveFeed(ctx, feedURL); err == nil {
+		t.Errorf("Expected an error removing a feed we are not subscribed to")
+	}
+	if len(fake.categories) != 2 {
+		t.Errorf("Expected each category to be created once, got %v", fake.categories)
+	}
+}
@@ -2,6 +2,7 @@ package runners
 
 import (
 	"context"
+	"sync"
 	"sync/atomic"
 
 	"github.com/atomicmeganerd/starfeed/common"
@@ -19,24 +20,81 @@ func (m *MockGitForge) LoadFeeds(ctx context.Context) (gitforge.FeedResultMap, e
 }
 
 type MockRssServer struct {
-	ExpectedLoadError   error
-	ExpectedAddError    error
-	ExpectedRemoveError error
-	ExpectedFeeds       *common.Set[common.FeedURL]
+	ExpectedLoadError    error
+	ExpectedAddError     error
+	ExpectedRemoveError  error
+	ExpectedMigrateError error
+	ExpectedFeeds        *common.Set[common.FeedURL]
+	// If set the feeds of each category are taken from here instead of ExpectedFeeds
+	ExpectedCategoryFeeds map[rss.FeedCategory]*common.Set[common.FeedURL]
+	// Feeds that are not in here have their URL as their title
+	ExpectedTitles map[common.FeedURL]rss.FeedName
+	// Like tt-rss the server can't rename feeds
+	CannotRename bool
 
 	// These need to be atomic because we call the real RSS server with multiple goroutines. It
 	// has no state to protect but this mock does
-	NumAdded   atomic.Int32
-	NumRemoved atomic.Int32
+	NumAdded    atomic.Int32
+	NumRemoved  atomic.Int32
+	NumMoved    atomic.Int32
+	NumRenamed  atomic.Int32
+	NumMigrated atomic.Int32
+
+	// The last name each feed was added or renamed with and the category it was added or moved to
+	mu         sync.Mutex
+	names      map[common.FeedURL]rss.FeedName
+	categories map[common.FeedURL]rss.FeedCategory
+}
+
+func (m *MockRssServer) setName(feedURL common.FeedURL, name rss.FeedName) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.names == nil {
+		m.names = make(map[common.FeedURL]rss.FeedName)
+	}
+	m.names[feedURL] = name
+}
+
+func (m *MockRssServer) setCategory(feedURL common.FeedURL, category rss.FeedCategory) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.categories == nil {
+		m.categories = make(map[common.FeedURL]rss.FeedCategory)
+	}
+	m.categories[feedURL] = category
+}
+
+func (m *MockRssServer) CategoryOf(feedURL common.FeedURL) rss.FeedCategory {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.categories[feedURL]
+}
+
+func (m *MockRssServer) NameOf(feedURL common.FeedURL) rss.FeedName {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.names[feedURL]
 }
 
 func (m *MockRssServer) LoadFeeds(
 	ctx context.Context, category rss.FeedCategory,
-) (*common.Set[common.FeedURL], error) {
-	if m.ExpectedFeeds == nil {
-		m.ExpectedFeeds = common.NewSet[common.FeedURL]()
+) (rss.FeedMap, error) {
+	feeds := m.ExpectedFeeds
+	if m.ExpectedCategoryFeeds != nil {
+		feeds = m.ExpectedCategoryFeeds[category]
+	}
+	feedMap := rss.FeedMap{}
+	if feeds == nil {
+		return feedMap, m.ExpectedLoadError
+	}
+	for feedURL := range feeds.All() {
+		title, ok := m.ExpectedTitles[feedURL]
+		if !ok {
+			title = rss.FeedName(feedURL)
+		}
+		feedMap[feedURL] = title
 	}
-	return m.ExpectedFeeds, m.ExpectedLoadError
+	return feedMap, m.ExpectedLoadError
 }
 
 func (m *MockRssServer) AddFeed(
@@ -47,6 +105,8 @@ func (m *MockRssServer) AddFeed(
 ) error {
 	if m.ExpectedAddError == nil {
 		m.NumAdded.Add(1)
+		m.setName(feedURL, name)
+		m.setCategory(feedURL, category)
 	}
 	return m.ExpectedAddError
 }
@@ -57,3 +117,73 @@ func (m *MockRssServer) RemoveFeed(ctx context.Context, feedURL common.FeedURL)
 	}
 	return m.ExpectedRemoveError
 }
+
+func (m *MockRssServer) MoveFeed(
+	ctx context.Context, feedURL common.FeedURL, from, to rss.FeedCategory,
+) error {
+	m.NumMoved.Add(1)
+	m.setCategory(feedURL, to)
+	return nil
+}
+
+func (m *MockRssServer) RenameFeed(
+	ctx context.Context, feedURL common.FeedURL, name rss.FeedName,
+) error {
+	m.NumRenamed.Add(1)
+	m.setName(feedURL, name)
+	return nil
+}
+
+func (m *MockRssServer) CanRenameFeeds() bool {
+	return !m.CannotRename
+}
+
+// Like the FreshRSS client this keeps subscribing to the old URL
+func (m *MockRssServer) MigrateFeed(
+	ctx context.Context,
+	from common.FeedURL,
+	to common.FeedURL,
+	name rss.FeedName,
+	fromCategory rss.FeedCategory,
+	toCategory rss.FeedCategory,
+) (common.FeedURL, error) {
+	if m.ExpectedMigrateError != nil {
+		return "", m.ExpectedMigrateError
+	}
+	m.NumMigrated.Add(1)
+	return from, nil
+}
+
+type MockRepoIndex struct {
+	ExpectedLoadError error
+	Index             RepoIndex
+	Saved             RepoIndex
+}
+
+func (m *MockRepoIndex) Load() (RepoIndex, error) {
+	if m.Index == nil {
+		m.Index = RepoIndex{}
+	}
+	return m.Index, m.ExpectedLoadError
+}
+
+func (m *MockRepoIndex) Save(index RepoIndex) error {
+	m.Saved = index
+	return nil
+}
+
+type MockFeedProxy struct {
+	Aliases   map[common.FeedURL]common.FeedURL
+	Published bool
+}
+
+func (m *MockFeedProxy) Alias(from, to common.FeedURL) {
+	if m.Aliases == nil {
+		m.Aliases = make(map[common.FeedURL]common.FeedURL)
+	}
+	m.Aliases[from] = to
+}
+
+func (m *MockFeedProxy) Publish() {
+	m.Published = true
+}
@@ -0,0 +1,168 @@
+package runners
+
+import (
+	"fmt"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/atomicmeganerd/starfeed/common"
+	"github.com/atomicmeganerd/starfeed/gitforge"
+	"github.com/atomicmeganerd/starfeed/rss"
+)
+
+// What we do with the feeds of archived repos. Their feeds still have entries but they will never
+// release again.
+type ArchivedPolicy string
+
+const (
+	ArchivedKeep   ArchivedPolicy = "keep"
+	ArchivedRemove ArchivedPolicy = "remove"
+	ArchivedMove   ArchivedPolicy = "move"
+)
+
+// What we do with feeds whose newest release is older than the max release age
+type DormantPolicy string
+
+const (
+	// Don't add dormant feeds but keep the ones we already have
+	DormantSkip DormantPolicy = "skip"
+	// Don't add dormant feeds and remove the ones we already have
+	DormantRemove DormantPolicy = "remove"
+)
+
+// SyncFeedsOptions controls where and how a SyncFeedsRunner publishes the feeds of a GitForge.
+// The zero value of anything optional keeps the behaviour we had before it was added.
+type SyncFeedsOptions struct {
+	// The category we publish feeds in. It is normally the name of the GitForge.
+	Category rss.FeedCategory
+	// Archived repos are kept like any other repo if this is not set
+	Archived ArchivedPolicy
+	// Where archived repos are moved to with ArchivedMove
+	ArchivedCategory rss.FeedCategory
+	// Feeds are titled with the bare repo name if this is nil
+	TitleTemplate *template.Template
+	// Feeds whose newest release is older than this are dormant. Zero disables the check.
+	MaxReleaseAge time.Duration
+	// Dormant feeds are skipped if this is not set
+	Dormant DormantPolicy
+	// Repos in a GitHub star list go in a category named after the list. With PrefixStarLists
+	// the list name is prefixed with the Category, e.g. "GitHub: Databases".
+	PrefixStarLists bool
+	// The feed proxy that serves the private feeds the GitForge registered on this run. It is nil
+	// if there is no proxy.
+	FeedProxy FeedProxy
+}
+
+// FeedProxy is what the runner needs of the feed proxy. At the end of a run we keep the proxy URLs
+// of renamed repos that we are still subscribed to working and then Publish, which stops serving
+// the feeds that were not registered again.
+type FeedProxy interface {
+	Alias(from, to common.FeedURL)
+	Publish()
+}
+
+// The fields a title template can use, e.g. "{{.Owner}}/{{.Name}} releases"
+type FeedTitleData struct {
+	Owner       string
+	Name        string
+	FullName    string
+	Description string
+}
+
+// All of the categories the runner owns. Feeds in them that we don't want are removed. On top of
+// the fixed ones we own the category of every star list a repo is in now or was in on the last
+// run so that we can still move the feeds out of a list that has been deleted.
+func (o SyncFeedsOptions) categories(
+	gitForgeFeedResults gitforge.FeedResultMap,
+	index RepoIndex,
+) []rss.FeedCategory {
+	categories := []rss.FeedCategory{o.Category}
+	if o.Archived == ArchivedMove {
+		categories = append(categories, o.ArchivedCategory)
+	}
+	owned := common.NewSet(categories...)
+	addStarList := func(starList string) {
+		category := o.starListCategory(starList)
+		if starList != "" && !owned.Contains(category) {
+			owned.Add(category)
+			categories = append(categories, category)
+		}
+	}
+	for _, repoResult := range gitForgeFeedResults {
+		addStarList(repoResult.StarList)
+	}
+	for _, entry := range index {
+		addStarList(entry.StarList)
+	}
+	return categories
+}
+
+func (o SyncFeedsOptions) starListCategory(starList string) rss.FeedCategory {
+	if o.PrefixStarLists {
+		return rss.FeedCategory(fmt.Sprintf("%s: %s", o.Category, starList))
+	}
+	return rss.FeedCategory(starList)
+}
+
+// Without the prefix a list category is named after the list alone, so another forge or the user
+// may have feeds in a category of the same name
+func (o SyncFeedsOptions) isSharedCategory(category rss.FeedCategory) bool {
+	if o.PrefixStarLists || category == o.Category {
+		return false
+	}
+	return o.Archived != ArchivedMove || category != o.ArchivedCategory
+}
+
+// A feed is dormant if its newest release is older than the max release age. If we don't know
+// when it last released we can't say that it is.
+func (o SyncFeedsOptions) isDormant(repoResult gitforge.GitRepoResult) bool {
+	if o.MaxReleaseAge <= 0 || repoResult.LatestRelease.IsZero() {
+		return false
+	}
+	return time.Since(repoResult.LatestRelease) > o.MaxReleaseAge
+}
+
+// Returns the category the feed of this repo belongs in or false if we don't want it at all
+func (o SyncFeedsOptions) categoryFor(repoResult gitforge.GitRepoResult) (rss.FeedCategory, bool) {
+	if o.Dormant == DormantRemove && o.isDormant(repoResult) {
+		return "", false
+	}
+	category := o.Category
+	if repoResult.StarList != "" {
+		category = o.starListCategory(repoResult.StarList)
+	}
+	if !repoResult.Archived {
+		return category, true
+	}
+	switch o.Archived {
+	case ArchivedRemove:
+		return "", false
+	case ArchivedMove:
+		return o.ArchivedCategory, true
+	}
+	return category, true
+}
+
+// Renders the title template for the repo. If the template fails or renders nothing we fall back
+// to the repo name as a feed without a title is no use to anyone. The error is returned along with
+// the fallback so that the caller can tell the user the template is broken.
+func (o SyncFeedsOptions) titleFor(repoResult gitforge.GitRepoResult) (rss.FeedName, error) {
+	name := rss.FeedName(repoResult.RepoName.String())
+	if o.TitleTemplate == nil {
+		return name, nil
+	}
+	var title strings.Builder
+	if err := o.TitleTemplate.Execute(&title, FeedTitleData{
+		Owner:       repoResult.Owner,
+		Name:        repoResult.RepoName.String(),
+		FullName:    repoResult.FullName,
+		Description: repoResult.Description,
+	}); err != nil {
+		return name, fmt.Errorf("error %w rendering the title template", err)
+	}
+	if trimmed := strings.TrimSpace(title.String()); trimmed != "" {
+		return rss.FeedName(trimmed), nil
+	}
+	return name, nil
+}
@@ -0,0 +1,101 @@
+package runners
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	"github.com/atomicmeganerd/starfeed/common"
+	"github.com/atomicmeganerd/starfeed/gitforge"
+)
+
+// RepoIndex remembers which feed we are subscribed to for each repo keyed by the id the forge
+// gave it. Names change when a repo is renamed or transferred but the id does not, so this is how
+// we spot that a feed URL we have never seen is really a repo we already follow.
+type RepoIndex map[int64]RepoIndexEntry
+
+type RepoIndexEntry struct {
+	// The feed URL the forge gave us for this repo on the last run
+	FeedURL common.FeedURL `json:"feed_url"`
+	// The feed URL we are actually subscribed to in the RSS server. This is the old URL when the
+	// RSS server could not retarget the subscription after a rename.
+	SubscribedURL common.FeedURL `json:"subscribed_url"`
+	// Whether FeedURL is the releases or tags feed of the repo. Switching between them is not a
+	// rename.
+	FeedKind gitforge.FeedKind `json:"feed_kind,omitempty"`
+	// The GitHub star list the repo was in. We own its category until no repo is in it.
+	StarList string `json:"star_list,omitempty"`
+	// Whether we added the subscription ourselves or found it in a category that is ours alone.
+	// A list category without a prefix can hold feeds added by hand which we must not remove.
+	SubscribedByUs bool `json:"subscribed_by_us,omitempty"`
+}
+
+// Entries written before we had tags feeds have no kind and are for releases feeds
+func (e RepoIndexEntry) sameFeedKind(kind gitforge.FeedKind) bool {
+	return normaliseFeedKind(e.FeedKind) == normaliseFeedKind(kind)
+}
+
+func normaliseFeedKind(kind gitforge.FeedKind) gitforge.FeedKind {
+	if kind == "" {
+		return gitforge.FeedKindReleases
+	}
+	return kind
+}
+
+// The feed URLs of the repos in the index that we subscribed to ourselves
+func (i RepoIndex) subscribedByUs() *common.Set[common.FeedURL] {
+	feedURLs := common.NewSet[common.FeedURL]()
+	for _, entry := range i {
+		if entry.SubscribedByUs {
+			feedURLs.Add(entry.SubscribedURL)
+		}
+	}
+	return feedURLs
+}
+
+// FileRepoIndexStore persists a RepoIndex as a JSON file. All of its methods are safe to call on
+// a nil store which is what we have when no state dir is configured.
+type FileRepoIndexStore struct {
+	path string
+}
+
+// Creates the directory of the index file if required
+func NewFileRepoIndexStore(path string) (*FileRepoIndexStore, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return nil, fmt.Errorf("could not create state dir for %s: %w", path, err)
+	}
+	return &FileRepoIndexStore{path: path}, nil
+}
+
+// An index that was never saved is just empty
+func (s *FileRepoIndexStore) Load() (RepoIndex, error) {
+	index := RepoIndex{}
+	if s == nil {
+		return index, nil
+	}
+	data, err := os.ReadFile(s.path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return index, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("could not read repo index %s: %w", s.path, err)
+	}
+	if err := json.Unmarshal(data, &index); err != nil {
+		return nil, fmt.Errorf("could not parse repo index %s: %w", s.path, err)
+	}
+	return index, nil
+}
+
+func (s *FileRepoIndexStore) Save(index RepoIndex) error {
+	if s == nil {
+		return nil
+	}
+	data, err := json.MarshalIndent(index, "", "  ")
+	if err != nil {
+		return err
+	}
+	return common.WriteFileAtomic(s.path, data, 0o600)
+}
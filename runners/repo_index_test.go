@@ -0,0 +1,92 @@
+package runners
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestFileRepoIndexStore(t *testing.T) {
+	testCases := []struct {
+		name        string
+		contents    string
+		expectIndex RepoIndex
+		expectError bool
+	}{
+		{
+			name:        "Missing file is an empty index",
+			expectIndex: RepoIndex{},
+		},
+		{
+			name: "Existing index is loaded",
+			contents: `{"42": {
+				"feed_url": "https://github.com/user/new/releases.atom",
+				"subscribed_url": "https://github.com/user/old/releases.atom"
+			}}`,
+			expectIndex: RepoIndex{42: {
+				FeedURL:       "https://github.com/user/new/releases.atom",
+				SubscribedURL: "https://github.com/user/old/releases.atom",
+			}},
+		},
+		{
+			name:        "Corrupt index is an error",
+			contents:    `{not json`,
+			expectError: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			path := filepath.Join(t.TempDir(), "state", "repos.json")
+			store, err := NewFileRepoIndexStore(path)
+			if err != nil {
+				t.Fatalf("Unexpected error %q", err)
+			}
+			if tc.contents != "" {
+				if err := os.WriteFile(path, []byte(tc.contents), 0o600); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			index, err := store.Load()
+			if tc.expectError {
+				if err == nil {
+					t.Fatalf("Expected error but got none")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error %q", err)
+			}
+			if len(index) != len(tc.expectIndex) {
+				t.Fatalf("Expected %d entries but got %d", len(tc.expectIndex), len(index))
+			}
+			for id, entry := range tc.expectIndex {
+				if index[id] != entry {
+					t.Errorf("Expected entry %+v for repo %d but got %+v", entry, id, index[id])
+				}
+			}
+
+			// Whatever we save we must be able to load again
+			if err := store.Save(index); err != nil {
+				t.Fatalf("Unexpected error saving %q", err)
+			}
+			reloaded, err := store.Load()
+			if err != nil || len(reloaded) != len(index) {
+				t.Fatalf("Reloading the saved index failed: %v %v", reloaded, err)
+			}
+		})
+	}
+}
+
+func TestNilFileRepoIndexStore(t *testing.T) {
+	var store *FileRepoIndexStore
+	index, err := store.Load()
+	if err != nil || len(index) != 0 {
+		t.Fatalf("Expected an empty index from a nil store but got %v %v", index, err)
+	}
+	if err := store.Save(RepoIndex{1: {}}); err != nil {
+		t.Fatalf("Expected saving to a nil store to do nothing but got %q", err)
+	}
+}
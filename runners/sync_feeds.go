@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"log/slog"
+	"sync"
 	"sync/atomic"
 	"time"
 
@@ -20,7 +21,7 @@ type gitForge interface {
 }
 
 type rssServer interface {
-	LoadFeeds(ctx context.Context, category rss.FeedCategory) (*common.Set[common.FeedURL], error)
+	LoadFeeds(ctx context.Context, category rss.FeedCategory) (rss.FeedMap, error)
 	AddFeed(
 		ctx context.Context,
 		feedURL common.FeedURL,
@@ -28,34 +29,72 @@ type rssServer interface {
 		category rss.FeedCategory,
 	) error
 	RemoveFeed(ctx context.Context, feedURL common.FeedURL) error
+	MoveFeed(ctx context.Context, feedURL common.FeedURL, from, to rss.FeedCategory) error
+	RenameFeed(ctx context.Context, feedURL common.FeedURL, name rss.FeedName) error
+	// Points an existing subscription at a new feed URL and returns the URL we are subscribed to
+	// afterwards. That is the old URL if the server can't retarget subscriptions.
+	MigrateFeed(
+		ctx context.Context,
+		from common.FeedURL,
+		to common.FeedURL,
+		name rss.FeedName,
+		fromCategory rss.FeedCategory,
+		toCategory rss.FeedCategory,
+	) (common.FeedURL, error)
+}
+
+// RSS servers that can't rename feeds say so with this and we leave the titles of their feeds
+// alone. Servers that don't implement it can.
+type feedRenameChecker interface {
+	CanRenameFeeds() bool
+}
+
+func canRenameFeeds(server rssServer) bool {
+	checker, ok := server.(feedRenameChecker)
+	return !ok || checker.CanRenameFeeds()
+}
+
+type repoIndexStore interface {
+	Load() (RepoIndex, error)
+	Save(index RepoIndex) error
 }
 
 // SyncFeedsRunner is our primary runner orchestration object that does all of the co-ordination
 // between the GitForge and the RSS reader to make syncing happen for valid starred repo feeds.
 type SyncFeedsRunner struct {
 	gitForge  gitForge
-	category  rss.FeedCategory
+	opts      SyncFeedsOptions
 	rssServer rssServer
+	cache     *common.HTTPCache
+	repoIndex repoIndexStore
 	logger    *slog.Logger
 }
 
+// The cache is the HTTP cache used by the gitForge. It is only used to report hit/miss counts and
+// to prune the entries the run did not use. It can be nil if caching is disabled. The repoIndex
+// is where we remember which repo each feed belongs to so that we can follow renames.
 func NewSyncFeedsRunner(
 	gitForge gitForge,
 	rssServer rssServer,
-	category rss.FeedCategory,
+	opts SyncFeedsOptions,
+	cache *common.HTTPCache,
+	repoIndex repoIndexStore,
 	logger *slog.Logger,
 ) SyncFeedsRunner {
 	return SyncFeedsRunner{
 		gitForge:  gitForge,
 		rssServer: rssServer,
-		category:  category,
+		opts:      opts,
+		cache:     cache,
+		repoIndex: repoIndex,
 		logger:    logger,
 	}
 }
 
 // This queries release feeds for all starred repos in the specified Git host and publishes them
 // to FreshRSS. It also removes any stale release feeds from FreshRSS if they are no longer
-// starred.
+// starred, moves feeds between the categories the runner owns when a repo is archived or changes
+// star list and keeps feed titles in line with the title template.
 //
 // Loading should always work and we will return an error if that fails. Syncing on the other
 // hand is best effort. We will log failures but not error out on them. However, it will be
@@ -64,40 +103,29 @@ func NewSyncFeedsRunner(
 //
 // But if loading works in 99% of cases adding/deleting will work as well.
 func (r SyncFeedsRunner) Run(ctx context.Context) error {
-	var gitForgeFeedResults gitforge.FeedResultMap
-	var rssFeeds *common.Set[common.FeedURL]
-
 	start := time.Now()
 	r.logger.Info("Starting workflow to sync GiForge release feeds with RSS Server")
 
-	// We fire up separate goroutines in the same errGroup to ensure that all of these loads
-	// can happen concurrently
-	loadEg, loadCtx := errgroup.WithContext(ctx)
-	loadEg.SetLimit(10)
-	loadEg.Go(func() error {
-		var err error
-		gitForgeFeedResults, err = r.gitForge.LoadFeeds(loadCtx)
-		if err != nil {
-			return fmt.Errorf("error loading feeds from gitforge %s: %w", r.category, err)
-		}
-		return nil
-	})
-	loadEg.Go(func() error {
-		var err error
-		rssFeeds, err = r.rssServer.LoadFeeds(loadCtx, r.category)
-		if err != nil {
-			return fmt.Errorf(
-				"error loading feeds from rss server from category %s: %w",
-				r.category,
-				err,
-			)
-		}
-		return nil
-	})
-	// We block here waiting for all loads to finish
-	if err := loadEg.Wait(); err != nil {
+	// We load the starred repo feeds first as the star lists they are in decide which categories
+	// the runner owns
+	gitForgeFeedResults, err := r.gitForge.LoadFeeds(ctx)
+	if err != nil {
+		return fmt.Errorf("error loading feeds from gitforge %s: %w", r.opts.Category, err)
+	}
+
+	// A broken index should not stop the sync. At worst a renamed repo is removed and re-added.
+	index, err := r.repoIndex.Load()
+	if err != nil {
+		r.logger.Warn("Could not load the repo index", "error", err)
+		index = RepoIndex{}
+	}
+
+	rssFeeds, err := r.loadSubscriptions(ctx, r.opts.categories(gitForgeFeedResults, index))
+	if err != nil {
 		return err
 	}
+	renamed := findRenamedFeeds(gitForgeFeedResults, rssFeeds, index)
+	ours := r.ourSubscriptions(rssFeeds, index)
 
 	// Next perform the sync to RSS server adding new release feeds and removing
 	// old stale feeds. Here we return the slices of func() error that we can then add to our
@@ -105,12 +133,24 @@ func (r SyncFeedsRunner) Run(ctx context.Context) error {
 	syncEg := errgroup.Group{}
 	syncEg.SetLimit(10)
 
-	// We want to keep track of how many feeds we add and delete
-	numAdded := &atomic.Int32{}
+	// We want to keep track of which feeds we add and migrate and how many we delete, move and
+	// retitle
+	added := &addedFeeds{feedURLs: common.NewSet[common.FeedURL]()}
 	numRemoved := &atomic.Int32{}
+	numMoved := &atomic.Int32{}
+	numRetitled := &atomic.Int32{}
+	migrated := &migratedFeeds{entries: RepoIndex{}}
+	migrations := pendingMigrations(gitForgeFeedResults, renamed, index)
 
-	addTasks := r.addNewReleaseFeeds(ctx, gitForgeFeedResults, rssFeeds, numAdded)
-	rmTasks := r.removeStaleFeeds(ctx, gitForgeFeedResults, rssFeeds, numRemoved)
+	addTasks := r.addNewReleaseFeeds(ctx, gitForgeFeedResults, rssFeeds, renamed, added)
+	rmTasks := r.removeStaleFeeds(ctx, gitForgeFeedResults, rssFeeds, renamed, ours, numRemoved)
+	moveTasks := r.moveFeeds(ctx, gitForgeFeedResults, rssFeeds, renamed, numMoved)
+	retitleTasks := r.retitleFeeds(
+		ctx, gitForgeFeedResults, rssFeeds, renamed, migrations, numRetitled,
+	)
+	migrateTasks := r.migrateRenamedFeeds(
+		ctx, gitForgeFeedResults, rssFeeds, migrations, ours, migrated,
+	)
 
 	// Fire up our task goroutines
 	for _, task := range addTasks {
@@ -119,33 +159,112 @@ func (r SyncFeedsRunner) Run(ctx context.Context) error {
 	for _, task := range rmTasks {
 		syncEg.Go(task)
 	}
+	for _, task := range moveTasks {
+		syncEg.Go(task)
+	}
+	for _, task := range retitleTasks {
+		syncEg.Go(task)
+	}
+	for _, task := range migrateTasks {
+		syncEg.Go(task)
+	}
 
 	// We block here waiting for them all to finish
 	_ = syncEg.Wait()
 
+	r.publishProxiedFeeds(renamed)
+	for feedURL := range added.feedURLs.All() {
+		ours.Add(feedURL)
+	}
+	nextIndex := buildRepoIndex(gitForgeFeedResults, renamed, index, migrated.entries, ours)
+	if err := r.repoIndex.Save(nextIndex); err != nil {
+		r.logger.Warn("Could not save the repo index", "error", err)
+	}
+
+	// Every URL we still need was requested by this run so the rest are for repos we no longer
+	// follow
+	if err := r.cache.Prune(); err != nil {
+		r.logger.Warn("Could not prune the HTTP cache", "error", err)
+	}
+	cacheStats := r.cache.TakeStats()
 	r.logger.Info(
 		"Syncing GitForge feeds to RSS completed",
 		"duration", time.Since(start),
-		"numAdded", int(numAdded.Load()),
+		"numAdded", added.feedURLs.Len(),
 		"numRemoved", int(numRemoved.Load()),
+		"numMoved", int(numMoved.Load()),
+		"numRetitled", int(numRetitled.Load()),
+		"numMigrated", len(migrated.entries),
+		"cacheHits", cacheStats.Hits,
+		"cacheMisses", cacheStats.Misses,
 	)
 	return nil
 }
 
+// Loads the feeds we are subscribed to in each of the categories the runner owns. We fire up
+// separate goroutines in the same errGroup to ensure that all of these loads can happen
+// concurrently.
+func (r SyncFeedsRunner) loadSubscriptions(
+	ctx context.Context,
+	categories []rss.FeedCategory,
+) (subscriptions, error) {
+	categoryFeeds := make([]rss.FeedMap, len(categories))
+
+	loadEg, loadCtx := errgroup.WithContext(ctx)
+	loadEg.SetLimit(10)
+	for ix, category := range categories {
+		loadEg.Go(func() error {
+			var err error
+			categoryFeeds[ix], err = r.rssServer.LoadFeeds(loadCtx, category)
+			if err != nil {
+				return fmt.Errorf(
+					"error loading feeds from rss server from category %s: %w",
+					category,
+					err,
+				)
+			}
+			return nil
+		})
+	}
+	// We block here waiting for all loads to finish
+	if err := loadEg.Wait(); err != nil {
+		return nil, err
+	}
+
+	rssFeeds := subscriptions{}
+	for ix, category := range categories {
+		for feedURL, title := range categoryFeeds[ix] {
+			rssFeeds[feedURL] = subscription{category: category, title: title}
+		}
+	}
+	return rssFeeds, nil
+}
+
 // This method returns a slice of functions that can be ranged over and passed to an
 // errgroup.Group for concurrent execution. In this case it will add new feeds when the feed
 // does not yet exist in RSS has been validated with IsOK
 func (r SyncFeedsRunner) addNewReleaseFeeds(
 	ctx context.Context,
 	gitForgeFeedResults gitforge.FeedResultMap,
-	rssServerFeeds *common.Set[common.FeedURL],
-	numAdded *atomic.Int32,
+	rssServerFeeds subscriptions,
+	renamed map[common.FeedURL]common.FeedURL,
+	added *addedFeeds,
 ) []func() error {
+	// Renamed repos are already subscribed to under their old feed URL
+	subscribedUnderOldURL := renamedFeedURLs(renamed)
+
 	tasks := make([]func() error, 0, len(gitForgeFeedResults))
 	for feedURL, repoResult := range gitForgeFeedResults {
 		// Don't add feeds that are already in FreshRSS a second time or do not have entries or
 		// querying them failed.
-		if rssServerFeeds.Contains(feedURL) || !repoResult.IsOK() {
+		if rssServerFeeds.contains(feedURL) || subscribedUnderOldURL.Contains(feedURL) ||
+			!repoResult.IsOK() {
+			continue
+		}
+		// The archived policy may say we don't want this feed at all and we never add dormant
+		// feeds that have not released for longer than the max release age
+		category, wanted := r.opts.categoryFor(repoResult)
+		if !wanted || r.opts.isDormant(repoResult) {
 			continue
 		}
 		logger := r.logger.With("feedURL", feedURL)
@@ -156,13 +275,13 @@ func (r SyncFeedsRunner) addNewReleaseFeeds(
 			if err := r.rssServer.AddFeed(
 				ctx,
 				feedURL,
-				rss.FeedName(repoResult.RepoName.String()),
-				r.category,
+				r.titleFor(repoResult),
+				category,
 			); err != nil {
 				logger.Warn("Adding new feed failed", "error", err)
 				return nil
 			}
-			numAdded.Add(1)
+			added.add(feedURL)
 			return nil
 		}
 		tasks = append(tasks, task)
@@ -176,28 +295,36 @@ func (r SyncFeedsRunner) addNewReleaseFeeds(
 func (r SyncFeedsRunner) removeStaleFeeds(
 	ctx context.Context,
 	gitForgeFeedResults gitforge.FeedResultMap,
-	rssServerFeeds *common.Set[common.FeedURL],
+	rssServerFeeds subscriptions,
+	renamed map[common.FeedURL]common.FeedURL,
+	ours *common.Set[common.FeedURL],
 	numRemoved *atomic.Int32,
 ) []func() error {
-	tasks := make([]func() error, 0, rssServerFeeds.Len())
-	// This will only contain the list of feeds that are in the category associated
+	tasks := make([]func() error, 0, len(rssServerFeeds))
+	// This will only contain the list of feeds that are in the categories associated
 	// with our GitForge by design. This means we will not delete feeds that have nothing
 	// to do with this GitForge.
-	for feedURL := range rssServerFeeds.All() {
-		// Get the result for this query if there is one
-		repoResult, exists := gitForgeFeedResults[feedURL]
+	for feedURL := range rssServerFeeds {
+		// Get the result for this query if there is one. If the repo was renamed the result is
+		// under the new feed URL.
+		repoResult, exists := gitForgeFeedResults[currentFeedURL(renamed, feedURL)]
 		// If the entry is in the map but we could not query the release feed let us not remove it
 		// from FreshRSS. If it is stale we could query the release feed but did not find one.
 		// If the result is not Stale it means the feed is still valid or the query failed for some
-		// other reason.
-		if exists && !repoResult.IsStale() {
+		// other reason. Archived and dormant repos may also be unwanted by their policies.
+		if _, wanted := r.opts.categoryFor(repoResult); exists && !repoResult.IsStale() && wanted {
+			continue
+		}
+		// A list category without a prefix is not ours alone so we only remove the feeds we
+		// subscribed to
+		if !ours.Contains(feedURL) {
 			continue
 		}
 		logger := r.logger.With("feedURL", feedURL)
 		// If the feed needs to be removed append the task to the tasks slice
 		task := func() error {
 			logger.Info(
-				"Removing feed from RSS Server as it is no longer starred",
+				"Removing feed from RSS Server as it is no longer starred or wanted",
 			)
 			// Just log on failure for these
 			if err := r.rssServer.RemoveFeed(ctx, feedURL); err != nil {
@@ -211,3 +338,312 @@ func (r SyncFeedsRunner) removeStaleFeeds(
 	}
 	return tasks
 }
+
+// The subscriptions that are ours to remove. That is every feed in a category that is ours alone
+// but only the feeds the index says we subscribed to in a list category that is shared.
+func (r SyncFeedsRunner) ourSubscriptions(
+	rssServerFeeds subscriptions,
+	index RepoIndex,
+) *common.Set[common.FeedURL] {
+	subscribedByUs := index.subscribedByUs()
+	ours := common.NewSet[common.FeedURL]()
+	for feedURL, sub := range rssServerFeeds {
+		if !r.opts.isSharedCategory(sub.category) || subscribedByUs.Contains(feedURL) {
+			ours.Add(feedURL)
+		}
+	}
+	return ours
+}
+
+// The feeds we added are recorded from many goroutines
+type addedFeeds struct {
+	mu       sync.Mutex
+	feedURLs *common.Set[common.FeedURL]
+}
+
+func (a *addedFeeds) add(feedURL common.FeedURL) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.feedURLs.Add(feedURL)
+}
+
+// The feeds we are subscribed to in the categories the runner owns
+type subscriptions map[common.FeedURL]subscription
+
+type subscription struct {
+	category rss.FeedCategory
+	title    rss.FeedName
+}
+
+func (s subscriptions) contains(feedURL common.FeedURL) bool {
+	_, ok := s[feedURL]
+	return ok
+}
+
+// This method returns a slice of functions that can be ranged over and passed to an
+// errgroup.Group for concurrent execution. In this case it will move feeds that are in the
+// wrong category, which happens when a repo is archived or unarchived.
+func (r SyncFeedsRunner) moveFeeds(
+	ctx context.Context,
+	gitForgeFeedResults gitforge.FeedResultMap,
+	rssServerFeeds subscriptions,
+	renamed map[common.FeedURL]common.FeedURL,
+	numMoved *atomic.Int32,
+) []func() error {
+	tasks := make([]func() error, 0)
+	for feedURL, sub := range rssServerFeeds {
+		category := sub.category
+		// Feeds we don't know about or don't want are handled by removeStaleFeeds
+		repoResult, exists := gitForgeFeedResults[currentFeedURL(renamed, feedURL)]
+		if !exists || repoResult.IsStale() {
+			continue
+		}
+		wantCategory, wanted := r.opts.categoryFor(repoResult)
+		if !wanted || wantCategory == category {
+			continue
+		}
+		logger := r.logger.With("feedURL", feedURL, "from", category, "to", wantCategory)
+		task := func() error {
+			logger.Info("Moving feed to another category")
+			// Just log on failure for these
+			if err := r.rssServer.MoveFeed(ctx, feedURL, category, wantCategory); err != nil {
+				logger.Warn("Moving the feed failed", "error", err)
+				return nil
+			}
+			numMoved.Add(1)
+			return nil
+		}
+		tasks = append(tasks, task)
+	}
+	return tasks
+}
+
+// The result of migrating a feed is written from many goroutines
+type migratedFeeds struct {
+	mu      sync.Mutex
+	entries RepoIndex
+}
+
+func (m *migratedFeeds) add(repoID int64, entry RepoIndexEntry) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.entries[repoID] = entry
+}
+
+// Works out which of the feeds we are subscribed to belong to a repo that has since been renamed
+// or transferred. The returned map points from the feed URL we are subscribed to, to the feed URL
+// the forge gives the repo now. Repos that we already migrated on an earlier run but where the
+// RSS server kept the old URL are included as well.
+func findRenamedFeeds(
+	gitForgeFeedResults gitforge.FeedResultMap,
+	rssServerFeeds subscriptions,
+	index RepoIndex,
+) map[common.FeedURL]common.FeedURL {
+	renamed := make(map[common.FeedURL]common.FeedURL)
+	for feedURL, repoResult := range gitForgeFeedResults {
+		entry, ok := index[repoResult.RepoID]
+		if repoResult.RepoID == 0 || !ok || entry.SubscribedURL == feedURL {
+			continue
+		}
+		// Switching between the releases and tags feed changes the URL but is not a rename
+		if !entry.sameFeedKind(repoResult.FeedKind) {
+			continue
+		}
+		// If someone already subscribed to the new URL there is nothing to migrate and the old
+		// feed is removed as usual
+		if rssServerFeeds.contains(entry.SubscribedURL) && !rssServerFeeds.contains(feedURL) {
+			renamed[entry.SubscribedURL] = feedURL
+		}
+	}
+	return renamed
+}
+
+// If the repo was renamed the forge knows the feed by its new URL
+func currentFeedURL(
+	renamed map[common.FeedURL]common.FeedURL,
+	feedURL common.FeedURL,
+) common.FeedURL {
+	if newURL, ok := renamed[feedURL]; ok {
+		return newURL
+	}
+	return feedURL
+}
+
+// The feed URLs the forge gives renamed repos now
+func renamedFeedURLs(renamed map[common.FeedURL]common.FeedURL) *common.Set[common.FeedURL] {
+	feedURLs := common.NewSet[common.FeedURL]()
+	for _, feedURL := range renamed {
+		feedURLs.Add(feedURL)
+	}
+	return feedURLs
+}
+
+// The subscriptions of renamed repos that we have not migrated yet. This points from the feed URL
+// we are subscribed to, to the feed URL the forge gives the repo now.
+func pendingMigrations(
+	gitForgeFeedResults gitforge.FeedResultMap,
+	renamed map[common.FeedURL]common.FeedURL,
+	index RepoIndex,
+) map[common.FeedURL]common.FeedURL {
+	pending := make(map[common.FeedURL]common.FeedURL)
+	for from, to := range renamed {
+		repoResult := gitForgeFeedResults[to]
+		// If the index already knows the new URL we migrated on an earlier run
+		if index[repoResult.RepoID].FeedURL == to || !repoResult.IsOK() {
+			continue
+		}
+		pending[from] = to
+	}
+	return pending
+}
+
+// This method returns a slice of functions that can be ranged over and passed to an
+// errgroup.Group for concurrent execution. In this case it will migrate the subscriptions of
+// repos that have been renamed since the last run so that we keep their read state and history.
+func (r SyncFeedsRunner) migrateRenamedFeeds(
+	ctx context.Context,
+	gitForgeFeedResults gitforge.FeedResultMap,
+	rssServerFeeds subscriptions,
+	migrations map[common.FeedURL]common.FeedURL,
+	ours *common.Set[common.FeedURL],
+	migrated *migratedFeeds,
+) []func() error {
+	tasks := make([]func() error, 0, len(migrations))
+	for from, to := range migrations {
+		repoResult := gitForgeFeedResults[to]
+		fromCategory := rssServerFeeds[from].category
+		logger := r.logger.With("from", from, "to", to)
+		task := func() error {
+			logger.Info("Migrating feed of renamed repo in RSS")
+			// Just log on failure for these. We will try again on the next run.
+			toCategory, _ := r.opts.categoryFor(repoResult)
+			subscribedURL, err := r.rssServer.MigrateFeed(
+				ctx, from, to, r.titleFor(repoResult), fromCategory, toCategory,
+			)
+			if err != nil {
+				logger.Warn("Migrating the feed failed", "error", err)
+				return nil
+			}
+			migrated.add(repoResult.RepoID, RepoIndexEntry{
+				FeedURL:        to,
+				SubscribedURL:  subscribedURL,
+				FeedKind:       repoResult.FeedKind,
+				SubscribedByUs: ours.Contains(from),
+			})
+			return nil
+		}
+		tasks = append(tasks, task)
+	}
+	return tasks
+}
+
+// This method returns a slice of functions that can be ranged over and passed to an
+// errgroup.Group for concurrent execution. In this case it will retitle feeds whose title no
+// longer matches the title template. Without a template we leave titles alone so that we never
+// overwrite titles that were edited by hand. The same goes for servers that can't rename feeds as
+// their titles would never match.
+func (r SyncFeedsRunner) retitleFeeds(
+	ctx context.Context,
+	gitForgeFeedResults gitforge.FeedResultMap,
+	rssServerFeeds subscriptions,
+	renamed map[common.FeedURL]common.FeedURL,
+	migrations map[common.FeedURL]common.FeedURL,
+	numRetitled *atomic.Int32,
+) []func() error {
+	tasks := make([]func() error, 0)
+	if r.opts.TitleTemplate == nil || !canRenameFeeds(r.rssServer) {
+		return tasks
+	}
+	for feedURL, sub := range rssServerFeeds {
+		// Migrating a feed sets its title as well
+		if _, migrating := migrations[feedURL]; migrating {
+			continue
+		}
+		repoResult, exists := gitForgeFeedResults[currentFeedURL(renamed, feedURL)]
+		if !exists || repoResult.IsStale() {
+			continue
+		}
+		title := r.titleFor(repoResult)
+		if _, wanted := r.opts.categoryFor(repoResult); !wanted || title == sub.title {
+			continue
+		}
+		logger := r.logger.With("feedURL", feedURL, "from", sub.title, "to", title)
+		task := func() error {
+			logger.Info("Retitling feed to match the title template")
+			// Just log on failure for these
+			if err := r.rssServer.RenameFeed(ctx, feedURL, title); err != nil {
+				logger.Warn("Retitling the feed failed", "error", err)
+				return nil
+			}
+			numRetitled.Add(1)
+			return nil
+		}
+		tasks = append(tasks, task)
+	}
+	return tasks
+}
+
+// The title for the feed of the repo. A broken template falls back to the repo name but we log it
+// so that it does not go unnoticed.
+func (r SyncFeedsRunner) titleFor(repoResult gitforge.GitRepoResult) rss.FeedName {
+	title, err := r.opts.titleFor(repoResult)
+	if err != nil {
+		r.logger.Warn(
+			"Could not title the feed with the template, using the repo name instead",
+			"repo", repoResult.RepoName,
+			"error", err,
+		)
+	}
+	return title
+}
+
+// The renamed feeds come from the repo index, so a subscription to the proxy URL of the old name
+// of a private repo keeps working across restarts even though the forge only knows the new name
+func (r SyncFeedsRunner) publishProxiedFeeds(renamed map[common.FeedURL]common.FeedURL) {
+	if r.opts.FeedProxy == nil {
+		return
+	}
+	for from, to := range renamed {
+		r.opts.FeedProxy.Alias(from, to)
+	}
+	r.opts.FeedProxy.Publish()
+}
+
+// Builds the index we save for the next run. Repos that are no longer starred drop out of it.
+// Only the subscriptions in ours are recorded as subscribed by us, so a repo that was dormant or
+// failed to add does not make a feed added by hand ours to remove.
+func buildRepoIndex(
+	gitForgeFeedResults gitforge.FeedResultMap,
+	renamed map[common.FeedURL]common.FeedURL,
+	previous RepoIndex,
+	migrated RepoIndex,
+	ours *common.Set[common.FeedURL],
+) RepoIndex {
+	subscribedUnderOldURL := renamedFeedURLs(renamed)
+	next := RepoIndex{}
+	for feedURL, repoResult := range gitForgeFeedResults {
+		if repoResult.RepoID == 0 {
+			continue
+		}
+		entry, ok := migrated[repoResult.RepoID]
+		if !ok && subscribedUnderOldURL.Contains(feedURL) {
+			// Either we migrated on an earlier run or the migration has not worked yet. In both
+			// cases what we knew before is still right.
+			entry = previous[repoResult.RepoID]
+		} else if !ok {
+			entry = RepoIndexEntry{
+				FeedURL:       feedURL,
+				SubscribedURL: feedURL,
+				FeedKind:      repoResult.FeedKind,
+			}
+		}
+		// A migrated feed already knows whether we owned it under its old URL
+		if !ok {
+			entry.SubscribedByUs = ours.Contains(entry.SubscribedURL)
+		}
+		// Remembering the star list lets us clean up its category after the list is deleted
+		entry.StarList = repoResult.StarList
+		next[repoResult.RepoID] = entry
+	}
+	return next
+}
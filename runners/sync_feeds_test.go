@@ -4,6 +4,8 @@ import (
 	"context"
 	"errors"
 	"testing"
+	"text/template"
+	"time"
 
 	"github.com/atomicmeganerd/starfeed/common"
 	"github.com/atomicmeganerd/starfeed/gitforge"
@@ -269,6 +271,25 @@ func TestSyncFeeds(t *testing.T) {
 			expectRemoved: 0,
 			expectError:   false,
 		},
+		{
+			name: "Rate limited release feed must not remove existing feed",
+			gitForge: &MockGitForge{
+				ExpectedFeeedResultMap: gitforge.FeedResultMap{
+					"https://github.com/user/repo/releases.atom": gitforge.GitRepoResult{
+						RepoName: "repo",
+						Err:      gitforge.RateLimitError{},
+					},
+				},
+			},
+			rssServer: &MockRssServer{
+				ExpectedFeeds: common.NewSet[common.FeedURL](
+					"https://github.com/user/repo/releases.atom",
+				),
+			},
+			expectAdded:   0,
+			expectRemoved: 0,
+			expectError:   false,
+		},
 		{
 			name: "Network error on release feed must not remove existing feed",
 			gitForge: &MockGitForge{
@@ -392,8 +413,9 @@ func TestSyncFeeds(t *testing.T) {
 			runner := NewSyncFeedsRunner(
 				tc.gitForge,
 				tc.rssServer,
-
-				category,
+				SyncFeedsOptions{Category: category},
+				nil,
+				&MockRepoIndex{},
 				logger,
 			)
 
@@ -419,3 +441,745 @@ func TestSyncFeeds(t *testing.T) {
 		})
 	}
 }
+
+func TestSyncFeedsRenamedRepos(t *testing.T) {
+	logger := testutils.TestLogger(t)
+
+	const (
+		oldURL common.FeedURL = "https://github.com/user/old-name/releases.atom"
+		newURL common.FeedURL = "https://github.com/user/new-name/releases.atom"
+	)
+	renamedRepo := gitforge.GitRepoResult{
+		RepoID:            42,
+		RepoName:          "new-name",
+		RelFeedHasEntries: true,
+	}
+
+	// The entries we expect to save for the repo
+	oldEntry := RepoIndexEntry{FeedURL: oldURL, SubscribedURL: oldURL, SubscribedByUs: true}
+	migratedEntry := RepoIndexEntry{FeedURL: newURL, SubscribedURL: oldURL, SubscribedByUs: true}
+	newEntry := RepoIndexEntry{FeedURL: newURL, SubscribedURL: newURL, SubscribedByUs: true}
+
+	testCases := []struct {
+		name            string
+		feedResults     gitforge.FeedResultMap
+		rssServer       *MockRssServer
+		index           RepoIndex
+		expectAdded     int32
+		expectRemoved   int32
+		expectMigrated  int32
+		expectSavedFeed RepoIndexEntry
+	}{
+		{
+			name:        "Renamed repo is migrated instead of removed and added",
+			feedResults: gitforge.FeedResultMap{newURL: renamedRepo},
+			rssServer: &MockRssServer{
+				ExpectedFeeds: common.NewSet(oldURL),
+			},
+			index:           RepoIndex{42: {FeedURL: oldURL, SubscribedURL: oldURL}},
+			expectMigrated:  1,
+			expectSavedFeed: migratedEntry,
+		},
+		{
+			name:        "Repo migrated on an earlier run is left alone",
+			feedResults: gitforge.FeedResultMap{newURL: renamedRepo},
+			rssServer: &MockRssServer{
+				ExpectedFeeds: common.NewSet(oldURL),
+			},
+			index:           RepoIndex{42: {FeedURL: newURL, SubscribedURL: oldURL}},
+			expectSavedFeed: migratedEntry,
+		},
+		{
+			name:        "Failed migration is retried on the next run",
+			feedResults: gitforge.FeedResultMap{newURL: renamedRepo},
+			rssServer: &MockRssServer{
+				ExpectedFeeds:        common.NewSet(oldURL),
+				ExpectedMigrateError: errors.New("failed to migrate feed"),
+			},
+			index:           RepoIndex{42: {FeedURL: oldURL, SubscribedURL: oldURL}},
+			expectSavedFeed: oldEntry,
+		},
+		{
+			name: "Renamed repo with a failing feed is not removed",
+			feedResults: gitforge.FeedResultMap{
+				newURL: {RepoID: 42, RepoName: "new-name", Err: common.HTTPError{StatusCode: 500}},
+			},
+			rssServer: &MockRssServer{
+				ExpectedFeeds: common.NewSet(oldURL),
+			},
+			index:           RepoIndex{42: {FeedURL: oldURL, SubscribedURL: oldURL}},
+			expectSavedFeed: oldEntry,
+		},
+		{
+			name:        "Repo we have never seen before is removed and added",
+			feedResults: gitforge.FeedResultMap{newURL: renamedRepo},
+			rssServer: &MockRssServer{
+				ExpectedFeeds: common.NewSet(oldURL),
+			},
+			index:           RepoIndex{},
+			expectAdded:     1,
+			expectRemoved:   1,
+			expectSavedFeed: newEntry,
+		},
+		{
+			name: "Switching from the releases to the tags feed is not a rename",
+			feedResults: gitforge.FeedResultMap{
+				"https://github.com/user/old-name/tags.atom": {
+					RepoID:            42,
+					RepoName:          "old-name",
+					RelFeedHasEntries: true,
+					FeedKind:          gitforge.FeedKindTags,
+				},
+			},
+			rssServer: &MockRssServer{
+				ExpectedFeeds: common.NewSet(oldURL),
+			},
+			index:         RepoIndex{42: {FeedURL: oldURL, SubscribedURL: oldURL}},
+			expectAdded:   1,
+			expectRemoved: 1,
+			expectSavedFeed: RepoIndexEntry{
+				FeedURL:        "https://github.com/user/old-name/tags.atom",
+				SubscribedURL:  "https://github.com/user/old-name/tags.atom",
+				FeedKind:       gitforge.FeedKindTags,
+				SubscribedByUs: true,
+			},
+		},
+		{
+			name:        "Renamed repo that is no longer starred is removed",
+			feedResults: gitforge.FeedResultMap{},
+			rssServer: &MockRssServer{
+				ExpectedFeeds: common.NewSet(oldURL),
+			},
+			index:         RepoIndex{42: {FeedURL: newURL, SubscribedURL: oldURL}},
+			expectRemoved: 1,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			ctx := context.Background()
+
+			repoIndex := &MockRepoIndex{Index: tc.index}
+			runner := NewSyncFeedsRunner(
+				&MockGitForge{ExpectedFeeedResultMap: tc.feedResults},
+				tc.rssServer,
+				SyncFeedsOptions{Category: rss.FeedCategory(testutils.GitHubName)},
+				nil,
+				repoIndex,
+				logger,
+			)
+
+			if err := runner.Run(ctx); err != nil {
+				t.Fatalf("Unexpected error %q", err)
+			}
+
+			if numAdded := tc.rssServer.NumAdded.Load(); tc.expectAdded != numAdded {
+				t.Errorf("Expected %d feeds added but added %d", tc.expectAdded, numAdded)
+			}
+			if numRemoved := tc.rssServer.NumRemoved.Load(); tc.expectRemoved != numRemoved {
+				t.Errorf("Expected %d feeds removed but removed %d", tc.expectRemoved, numRemoved)
+			}
+			if numMigrated := tc.rssServer.NumMigrated.Load(); tc.expectMigrated != numMigrated {
+				t.Errorf(
+					"Expected %d feeds migrated but migrated %d", tc.expectMigrated, numMigrated,
+				)
+			}
+			if saved := repoIndex.Saved[42]; saved != tc.expectSavedFeed {
+				t.Errorf("Expected saved index entry %+v but got %+v", tc.expectSavedFeed, saved)
+			}
+		})
+	}
+}
+
+func TestSyncFeedsArchivedRepos(t *testing.T) {
+	logger := testutils.TestLogger(t)
+
+	const (
+		category         rss.FeedCategory = "GitHub"
+		archivedCategory rss.FeedCategory = "GitHub Archived"
+		feedURL          common.FeedURL   = "https://github.com/user/repo/releases.atom"
+	)
+	archivedRepo := gitforge.FeedResultMap{
+		feedURL: {RepoName: "repo", RelFeedHasEntries: true, Archived: true},
+	}
+	activeRepo := gitforge.FeedResultMap{
+		feedURL: {RepoName: "repo", RelFeedHasEntries: true},
+	}
+
+	testCases := []struct {
+		name          string
+		policy        ArchivedPolicy
+		feedResults   gitforge.FeedResultMap
+		rssFeeds      map[rss.FeedCategory]*common.Set[common.FeedURL]
+		expectAdded   int32
+		expectRemoved int32
+		expectMoved   int32
+	}{
+		{
+			name:        "Keep adds archived repos like any other",
+			policy:      ArchivedKeep,
+			feedResults: archivedRepo,
+			expectAdded: 1,
+		},
+		{
+			name:        "Remove does not add archived repos",
+			policy:      ArchivedRemove,
+			feedResults: archivedRepo,
+		},
+		{
+			name:        "Remove removes archived repos that are subscribed",
+			policy:      ArchivedRemove,
+			feedResults: archivedRepo,
+			rssFeeds: map[rss.FeedCategory]*common.Set[common.FeedURL]{
+				category: common.NewSet(feedURL),
+			},
+			expectRemoved: 1,
+		},
+		{
+			name:        "Move adds archived repos to the archived category",
+			policy:      ArchivedMove,
+			feedResults: archivedRepo,
+			expectAdded: 1,
+		},
+		{
+			name:        "Move moves repos that were archived since the last run",
+			policy:      ArchivedMove,
+			feedResults: archivedRepo,
+			rssFeeds: map[rss.FeedCategory]*common.Set[common.FeedURL]{
+				category: common.NewSet(feedURL),
+			},
+			expectMoved: 1,
+		},
+		{
+			name:        "Move moves repos back when they are unarchived",
+			policy:      ArchivedMove,
+			feedResults: activeRepo,
+			rssFeeds: map[rss.FeedCategory]*common.Set[common.FeedURL]{
+				archivedCategory: common.NewSet(feedURL),
+			},
+			expectMoved: 1,
+		},
+		{
+			name:        "Move leaves archived repos in the archived category alone",
+			policy:      ArchivedMove,
+			feedResults: archivedRepo,
+			rssFeeds: map[rss.FeedCategory]*common.Set[common.FeedURL]{
+				archivedCategory: common.NewSet(feedURL),
+			},
+		},
+		{
+			name:        "Move removes archived repos that are no longer starred",
+			policy:      ArchivedMove,
+			feedResults: gitforge.FeedResultMap{},
+			rssFeeds: map[rss.FeedCategory]*common.Set[common.FeedURL]{
+				archivedCategory: common.NewSet(feedURL),
+			},
+			expectRemoved: 1,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			ctx := context.Background()
+
+			rssServer := &MockRssServer{ExpectedCategoryFeeds: tc.rssFeeds}
+			if rssServer.ExpectedCategoryFeeds == nil {
+				rssServer.ExpectedCategoryFeeds = map[rss.FeedCategory]*common.Set[common.FeedURL]{}
+			}
+			runner := NewSyncFeedsRunner(
+				&MockGitForge{ExpectedFeeedResultMap: tc.feedResults},
+				rssServer,
+				SyncFeedsOptions{
+					Category:         category,
+					Archived:         tc.policy,
+					ArchivedCategory: archivedCategory,
+				},
+				nil,
+				&MockRepoIndex{},
+				logger,
+			)
+
+			if err := runner.Run(ctx); err != nil {
+				t.Fatalf("Unexpected error %q", err)
+			}
+
+			if numAdded := rssServer.NumAdded.Load(); tc.expectAdded != numAdded {
+				t.Errorf("Expected %d feeds added but added %d", tc.expectAdded, numAdded)
+			}
+			if numRemoved := rssServer.NumRemoved.Load(); tc.expectRemoved != numRemoved {
+				t.Errorf("Expected %d feeds removed but removed %d", tc.expectRemoved, numRemoved)
+			}
+			if numMoved := rssServer.NumMoved.Load(); tc.expectMoved != numMoved {
+				t.Errorf("Expected %d feeds moved but moved %d", tc.expectMoved, numMoved)
+			}
+		})
+	}
+}
+
+func TestSyncFeedsTitleTemplate(t *testing.T) {
+	logger := testutils.TestLogger(t)
+
+	const feedURL common.FeedURL = "https://github.com/user/repo/releases.atom"
+	feedResults := gitforge.FeedResultMap{
+		feedURL: {
+			RepoName:          "repo",
+			Owner:             "user",
+			FullName:          "user/repo",
+			RelFeedHasEntries: true,
+		},
+	}
+	ownerTemplate := template.Must(template.New("title").Parse("{{.Owner}}/{{.Name}} releases"))
+
+	testCases := []struct {
+		name          string
+		titleTemplate *template.Template
+		rssServer     *MockRssServer
+		expectAdded   int32
+		expectRenamed int32
+		expectTitle   rss.FeedName
+	}{
+		{
+			name:          "New feeds are titled with the template",
+			titleTemplate: ownerTemplate,
+			rssServer:     &MockRssServer{},
+			expectAdded:   1,
+			expectTitle:   "user/repo releases",
+		},
+		{
+			name:          "Without a template new feeds are titled with the repo name",
+			titleTemplate: nil,
+			rssServer:     &MockRssServer{},
+			expectAdded:   1,
+			expectTitle:   "repo",
+		},
+		{
+			name:          "Existing feeds that do not match the template are retitled",
+			titleTemplate: ownerTemplate,
+			rssServer: &MockRssServer{
+				ExpectedFeeds:  common.NewSet(feedURL),
+				ExpectedTitles: map[common.FeedURL]rss.FeedName{feedURL: "repo"},
+			},
+			expectRenamed: 1,
+			expectTitle:   "user/repo releases",
+		},
+		{
+			name:          "Feeds on servers that can't rename feeds are left alone",
+			titleTemplate: ownerTemplate,
+			rssServer: &MockRssServer{
+				ExpectedFeeds:  common.NewSet(feedURL),
+				ExpectedTitles: map[common.FeedURL]rss.FeedName{feedURL: "repo"},
+				CannotRename:   true,
+			},
+		},
+		{
+			name:          "Existing feeds that match the template are left alone",
+			titleTemplate: ownerTemplate,
+			rssServer: &MockRssServer{
+				ExpectedFeeds: common.NewSet(feedURL),
+				ExpectedTitles: map[common.FeedURL]rss.FeedName{
+					feedURL: "user/repo releases",
+				},
+			},
+		},
+		{
+			name:          "Without a template existing titles are never changed",
+			titleTemplate: nil,
+			rssServer: &MockRssServer{
+				ExpectedFeeds:  common.NewSet(feedURL),
+				ExpectedTitles: map[common.FeedURL]rss.FeedName{feedURL: "My favourite repo"},
+			},
+		},
+		{
+			name:          "Template that fails falls back to the repo name",
+			titleTemplate: template.Must(template.New("title").Parse("{{.Stars}} stars")),
+			rssServer:     &MockRssServer{},
+			expectAdded:   1,
+			expectTitle:   "repo",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			ctx := context.Background()
+
+			runner := NewSyncFeedsRunner(
+				&MockGitForge{ExpectedFeeedResultMap: feedResults},
+				tc.rssServer,
+				SyncFeedsOptions{
+					Category:      rss.FeedCategory(testutils.GitHubName),
+					TitleTemplate: tc.titleTemplate,
+				},
+				nil,
+				&MockRepoIndex{},
+				logger,
+			)
+
+			if err := runner.Run(ctx); err != nil {
+				t.Fatalf("Unexpected error %q", err)
+			}
+
+			if numAdded := tc.rssServer.NumAdded.Load(); tc.expectAdded != numAdded {
+				t.Errorf("Expected %d feeds added but added %d", tc.expectAdded, numAdded)
+			}
+			if numRenamed := tc.rssServer.NumRenamed.Load(); tc.expectRenamed != numRenamed {
+				t.Errorf("Expected %d feeds retitled but retitled %d", tc.expectRenamed, numRenamed)
+			}
+			if title := tc.rssServer.NameOf(feedURL); title != tc.expectTitle {
+				t.Errorf("Expected feed title %q but got %q", tc.expectTitle, title)
+			}
+		})
+	}
+}
+
+func TestSyncFeedsDormantFeeds(t *testing.T) {
+	logger := testutils.TestLogger(t)
+
+	const feedURL common.FeedURL = "https://github.com/user/repo/releases.atom"
+	releasedAt := func(age time.Duration) gitforge.FeedResultMap {
+		result := gitforge.GitRepoResult{RepoName: "repo", RelFeedHasEntries: true}
+		if age > 0 {
+			result.LatestRelease = time.Now().Add(-age)
+		}
+		return gitforge.FeedResultMap{feedURL: result}
+	}
+	const year = 365 * 24 * time.Hour
+
+	testCases := []struct {
+		name          string
+		maxAge        time.Duration
+		policy        DormantPolicy
+		feedResults   gitforge.FeedResultMap
+		subscribed    bool
+		expectAdded   int32
+		expectRemoved int32
+	}{
+		{
+			name:        "Without a max release age old feeds are added",
+			feedResults: releasedAt(5 * year),
+			expectAdded: 1,
+		},
+		{
+			name:        "Recent feeds are added",
+			maxAge:      year,
+			policy:      DormantSkip,
+			feedResults: releasedAt(time.Hour),
+			expectAdded: 1,
+		},
+		{
+			name:        "Dormant feeds are not added",
+			maxAge:      year,
+			policy:      DormantSkip,
+			feedResults: releasedAt(2 * year),
+		},
+		{
+			name:        "Skip keeps dormant feeds we already have",
+			maxAge:      year,
+			policy:      DormantSkip,
+			feedResults: releasedAt(2 * year),
+			subscribed:  true,
+		},
+		{
+			name:          "Remove prunes dormant feeds we already have",
+			maxAge:        year,
+			policy:        DormantRemove,
+			feedResults:   releasedAt(2 * year),
+			subscribed:    true,
+			expectRemoved: 1,
+		},
+		{
+			name:        "Feeds without release dates are never dormant",
+			maxAge:      year,
+			policy:      DormantRemove,
+			feedResults: releasedAt(0),
+			expectAdded: 1,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			ctx := context.Background()
+
+			rssServer := &MockRssServer{ExpectedFeeds: common.NewSet[common.FeedURL]()}
+			if tc.subscribed {
+				rssServer.ExpectedFeeds.Add(feedURL)
+			}
+			runner := NewSyncFeedsRunner(
+				&MockGitForge{ExpectedFeeedResultMap: tc.feedResults},
+				rssServer,
+				SyncFeedsOptions{
+					Category:      rss.FeedCategory(testutils.GitHubName),
+					MaxReleaseAge: tc.maxAge,
+					Dormant:       tc.policy,
+				},
+				nil,
+				&MockRepoIndex{},
+				logger,
+			)
+
+			if err := runner.Run(ctx); err != nil {
+				t.Fatalf("Unexpected error %q", err)
+			}
+
+			if numAdded := rssServer.NumAdded.Load(); tc.expectAdded != numAdded {
+				t.Errorf("Expected %d feeds added but added %d", tc.expectAdded, numAdded)
+			}
+			if numRemoved := rssServer.NumRemoved.Load(); tc.expectRemoved != numRemoved {
+				t.Errorf("Expected %d feeds removed but removed %d", tc.expectRemoved, numRemoved)
+			}
+		})
+	}
+}
+
+func TestSyncFeedsStarLists(t *testing.T) {
+	logger := testutils.TestLogger(t)
+
+	const (
+		category   rss.FeedCategory = "GitHub"
+		dbCategory rss.FeedCategory = "Databases"
+		feedURL    common.FeedURL   = "https://github.com/user/repo/releases.atom"
+		// A repo that was in the list on the last run
+		unstarredURL common.FeedURL = "https://github.com/user/unstarred/releases.atom"
+	)
+	listedEntry := RepoIndexEntry{
+		FeedURL: feedURL, SubscribedURL: feedURL, StarList: "Databases", SubscribedByUs: true,
+	}
+	// A feed in a list category that was added by hand or that we failed to add
+	handAddedEntry := RepoIndexEntry{
+		FeedURL: feedURL, SubscribedURL: feedURL, StarList: "Databases",
+	}
+	inList := func(starList string) gitforge.FeedResultMap {
+		return gitforge.FeedResultMap{
+			feedURL: {RepoID: 7, RepoName: "repo", RelFeedHasEntries: true, StarList: starList},
+		}
+	}
+
+	testCases := []struct {
+		name             string
+		prefix           bool
+		feedResults      gitforge.FeedResultMap
+		rssFeeds         map[rss.FeedCategory]*common.Set[common.FeedURL]
+		index            RepoIndex
+		addErr           error
+		expectAdded      int32
+		expectRemoved    int32
+		expectMoved      int32
+		expectCategory   rss.FeedCategory
+		expectSavedEntry RepoIndexEntry
+	}{
+		{
+			name:             "Repos in a list are added to its category",
+			feedResults:      inList("Databases"),
+			expectAdded:      1,
+			expectCategory:   dbCategory,
+			expectSavedEntry: listedEntry,
+		},
+		{
+			name:             "List categories can be prefixed with the forge category",
+			prefix:           true,
+			feedResults:      inList("Databases"),
+			expectAdded:      1,
+			expectCategory:   "GitHub: Databases",
+			expectSavedEntry: listedEntry,
+		},
+		{
+			name:        "Repos added to a list are moved to its category",
+			feedResults: inList("Databases"),
+			rssFeeds: map[rss.FeedCategory]*common.Set[common.FeedURL]{
+				category: common.NewSet(feedURL),
+			},
+			expectMoved:      1,
+			expectCategory:   dbCategory,
+			expectSavedEntry: listedEntry,
+		},
+		{
+			name:        "Repos in a list that was deleted are moved back",
+			feedResults: inList(""),
+			rssFeeds: map[rss.FeedCategory]*common.Set[common.FeedURL]{
+				dbCategory: common.NewSet(feedURL),
+			},
+			index:          RepoIndex{7: listedEntry},
+			expectMoved:    1,
+			expectCategory: category,
+			expectSavedEntry: RepoIndexEntry{
+				FeedURL: feedURL, SubscribedURL: feedURL, SubscribedByUs: true,
+			},
+		},
+		{
+			name:        "Repos in a list that are no longer starred are removed",
+			feedResults: inList("Databases"),
+			rssFeeds: map[rss.FeedCategory]*common.Set[common.FeedURL]{
+				dbCategory: common.NewSet(feedURL, unstarredURL),
+			},
+			index: RepoIndex{
+				7: listedEntry,
+				8: {
+					FeedURL:        unstarredURL,
+					SubscribedURL:  unstarredURL,
+					StarList:       "Databases",
+					SubscribedByUs: true,
+				},
+			},
+			expectRemoved:    1,
+			expectSavedEntry: listedEntry,
+		},
+		{
+			name:        "Feeds in a list category that we did not subscribe to are kept",
+			feedResults: inList("Databases"),
+			rssFeeds: map[rss.FeedCategory]*common.Set[common.FeedURL]{
+				dbCategory: common.NewSet(feedURL, unstarredURL),
+			},
+			// The unstarred repo was dormant so we never added its feed
+			index: RepoIndex{
+				8: {FeedURL: unstarredURL, SubscribedURL: unstarredURL, StarList: "Databases"},
+			},
+			expectSavedEntry: handAddedEntry,
+		},
+		{
+			name:             "Feeds we failed to add are not ours",
+			feedResults:      inList("Databases"),
+			addErr:           errors.New("failed to add feed"),
+			expectSavedEntry: handAddedEntry,
+		},
+		{
+			name:        "Prefixed list categories are ours alone",
+			prefix:      true,
+			feedResults: inList("Databases"),
+			rssFeeds: map[rss.FeedCategory]*common.Set[common.FeedURL]{
+				"GitHub: Databases": common.NewSet(feedURL, unstarredURL),
+			},
+			expectRemoved:    1,
+			expectSavedEntry: listedEntry,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			ctx := context.Background()
+
+			rssServer := &MockRssServer{
+				ExpectedCategoryFeeds: tc.rssFeeds,
+				ExpectedAddError:      tc.addErr,
+			}
+			if rssServer.ExpectedCategoryFeeds == nil {
+				rssServer.ExpectedCategoryFeeds = map[rss.FeedCategory]*common.Set[common.FeedURL]{}
+			}
+			repoIndex := &MockRepoIndex{Index: tc.index}
+			runner := NewSyncFeedsRunner(
+				&MockGitForge{ExpectedFeeedResultMap: tc.feedResults},
+				rssServer,
+				SyncFeedsOptions{Category: category, PrefixStarLists: tc.prefix},
+				nil,
+				repoIndex,
+				logger,
+			)
+
+			if err := runner.Run(ctx); err != nil {
+				t.Fatalf("Unexpected error %q", err)
+			}
+
+			if numAdded := rssServer.NumAdded.Load(); tc.expectAdded != numAdded {
+				t.Errorf("Expected %d feeds added but added %d", tc.expectAdded, numAdded)
+			}
+			if numRemoved := rssServer.NumRemoved.Load(); tc.expectRemoved != numRemoved {
+				t.Errorf("Expected %d feeds removed but removed %d", tc.expectRemoved, numRemoved)
+			}
+			if numMoved := rssServer.NumMoved.Load(); tc.expectMoved != numMoved {
+				t.Errorf("Expected %d feeds moved but moved %d", tc.expectMoved, numMoved)
+			}
+			if actual := rssServer.CategoryOf(feedURL); actual != tc.expectCategory {
+				t.Errorf("Expected feed in category %q but got %q", tc.expectCategory, actual)
+			}
+			if saved := repoIndex.Saved[7]; saved != tc.expectSavedEntry {
+				t.Errorf("Expected saved index entry %+v but got %+v", tc.expectSavedEntry, saved)
+			}
+		})
+	}
+}
+
+// Two forges with a star list of the same name share its category. Neither of them may remove the
+// feeds of the other.
+func TestSyncFeedsSharedStarList(t *testing.T) {
+	ctx := context.Background()
+	logger := testutils.TestLogger(t)
+
+	const (
+		dbCategory rss.FeedCategory = "Databases"
+		publicURL  common.FeedURL   = "https://github.com/user/db/releases.atom"
+		ghesURL    common.FeedURL   = "https://ghe.example.com/team/db/releases.atom"
+	)
+	rssServer := &MockRssServer{
+		ExpectedCategoryFeeds: map[rss.FeedCategory]*common.Set[common.FeedURL]{
+			dbCategory: common.NewSet(publicURL, ghesURL),
+		},
+	}
+	forges := []struct {
+		category rss.FeedCategory
+		feedURL  common.FeedURL
+	}{
+		{category: "GitHub", feedURL: publicURL},
+		{category: "GHES", feedURL: ghesURL},
+	}
+
+	for _, forge := range forges {
+		entry := RepoIndexEntry{
+			FeedURL: forge.feedURL, SubscribedURL: forge.feedURL, StarList: "Databases",
+		}
+		runner := NewSyncFeedsRunner(
+			&MockGitForge{ExpectedFeeedResultMap: gitforge.FeedResultMap{
+				forge.feedURL: {
+					RepoID: 7, RepoName: "db", RelFeedHasEntries: true, StarList: "Databases",
+				},
+			}},
+			rssServer,
+			SyncFeedsOptions{Category: forge.category},
+			nil,
+			&MockRepoIndex{Index: RepoIndex{7: entry}},
+			logger,
+		)
+		if err := runner.Run(ctx); err != nil {
+			t.Fatalf("Unexpected error %q", err)
+		}
+	}
+
+	numAdded, numRemoved := rssServer.NumAdded.Load(), rssServer.NumRemoved.Load()
+	if numAdded != 0 || numRemoved != 0 {
+		t.Errorf("Expected the feeds of both forges to be left alone but added %d and removed %d",
+			numAdded, numRemoved)
+	}
+}
+
+// The proxy URL of the old name of a private repo is known only from the index. We keep it served
+// after the rename until the RSS server is subscribed to the new URL.
+func TestSyncFeedsProxiedRenamedRepo(t *testing.T) {
+	ctx := context.Background()
+	const (
+		oldURL common.FeedURL = "http://starfeed:8080/feeds/0123.atom"
+		newURL common.FeedURL = "http://starfeed:8080/feeds/4567.atom"
+	)
+	feedProxy := &MockFeedProxy{}
+	runner := NewSyncFeedsRunner(
+		&MockGitForge{ExpectedFeeedResultMap: gitforge.FeedResultMap{
+			newURL: {RepoID: 42, RepoName: "new-name", RelFeedHasEntries: true, Private: true},
+		}},
+		&MockRssServer{ExpectedFeeds: common.NewSet(oldURL)},
+		SyncFeedsOptions{Category: "GitHub", FeedProxy: feedProxy},
+		nil,
+		&MockRepoIndex{Index: RepoIndex{42: {FeedURL: newURL, SubscribedURL: oldURL}}},
+		testutils.TestLogger(t),
+	)
+
+	if err := runner.Run(ctx); err != nil {
+		t.Fatalf("Unexpected error %q", err)
+	}
+	if !feedProxy.Published {
+		t.Errorf("Expected the proxied feeds to be published")
+	}
+	if to := feedProxy.Aliases[oldURL]; to != newURL {
+		t.Errorf("Expected %s to be served as %s, got %q", oldURL, newURL, to)
+	}
+}